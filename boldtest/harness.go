@@ -0,0 +1,177 @@
+// Package boldtest runs end-to-end tests against a booted application.
+//
+//	func TestSignup(t *testing.T) {
+//		h := boldtest.New(t, app(), db, "postgres")
+//		resp := h.PostJSON("/users", map[string]string{"email": "a@example.com"})
+//		...
+//		h.Reset()
+//	}
+//
+// The database must be a throwaway one: New drops its tables before
+// migrating it, and Reset truncates them. New refuses to run outside the
+// testing environment or against a database whose name lacks "test".
+package boldtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-bold/bold/config"
+	"github.com/go-bold/bold/migrations"
+	"github.com/go-bold/bold/routing"
+)
+
+// Harness is a migrated database plus the application serving on an ephemeral port
+type Harness struct {
+	App      *routing.NetHTTPApp
+	DB       *sql.DB
+	Provider migrations.Provider
+	Server   *httptest.Server
+
+	tb     testing.TB
+	client *http.Client
+}
+
+// New wipes db, runs the registered migrations, boots app and serves it until
+// the test ends, when the server is closed and the app's shutdown hooks run.
+// driver is the database/sql driver name db was opened with. The test fails
+// before anything is dropped unless config.IsTesting and the name of the
+// database contains "test", such as app_test.
+func New(tb testing.TB, app *routing.NetHTTPApp, db *sql.DB, driver string) *Harness {
+	tb.Helper()
+
+	provider, err := migrations.Dialect(driver)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if !config.IsTesting() {
+		tb.Fatalf("boldtest: refusing to wipe the database outside the testing environment, set %s=%s", config.EnvVar, config.Testing)
+	}
+	name, err := databaseName(ctx, db, driver)
+	if err != nil {
+		tb.Fatalf("boldtest: reading the database name: %v", err)
+	}
+	if !strings.Contains(strings.ToLower(name), "test") {
+		tb.Fatalf("boldtest: refusing to wipe database %q, its name does not contain \"test\"", name)
+	}
+	if err := provider.DropAllTablesContext(ctx, db); err != nil {
+		tb.Fatalf("boldtest: wiping the database: %v", err)
+	}
+	if err := migrations.NewRunner(provider).UpContext(ctx, db); err != nil {
+		tb.Fatalf("boldtest: migrating: %v", err)
+	}
+	if err := app.Boot(ctx); err != nil {
+		tb.Fatalf("boldtest: booting: %v", err)
+	}
+
+	h := &Harness{App: app, DB: db, Provider: provider, Server: httptest.NewServer(app.Handler()), tb: tb}
+	h.client = h.NewClient()
+	tb.Cleanup(func() {
+		h.Server.Close()
+		if err := app.Close(context.Background()); err != nil {
+			tb.Errorf("boldtest: shutting down: %v", err)
+		}
+	})
+	return h
+}
+
+// databaseName returns the name of the database db is connected to
+func databaseName(ctx context.Context, db *sql.DB, driver string) (string, error) {
+	query := "SELECT current_database()"
+	switch driver {
+	case "mysql", "mariadb", "tidb", "vitess":
+		query = "SELECT DATABASE()"
+	case "sqlserver", "mssql", "azuresql":
+		query = "SELECT DB_NAME()"
+	}
+	var name sql.NullString
+	err := db.QueryRowContext(ctx, query).Scan(&name)
+	return name.String, err
+}
+
+// URL returns the absolute URL of path on the test server
+func (h *Harness) URL(path string) string {
+	return h.Server.URL + path
+}
+
+// NewClient returns a client with its own cookie jar, for tests acting as several users
+func (h *Harness) NewClient() *http.Client {
+	jar, _ := cookiejar.New(nil)
+	client := h.Server.Client()
+	client.Jar = jar
+	return client
+}
+
+// Client returns the client used by Do, Get and PostJSON, which keeps cookies between requests
+func (h *Harness) Client() *http.Client {
+	return h.client
+}
+
+// Do sends r with Client, failing the test on transport errors. The response
+// body is closed when the test ends.
+func (h *Harness) Do(r *http.Request) *http.Response {
+	h.tb.Helper()
+	resp, err := h.client.Do(r)
+	if err != nil {
+		h.tb.Fatalf("boldtest: %s %s: %v", r.Method, r.URL, err)
+	}
+	h.tb.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// Get requests path on the test server
+func (h *Harness) Get(path string) *http.Response {
+	h.tb.Helper()
+	r, err := http.NewRequest(http.MethodGet, h.URL(path), nil)
+	if err != nil {
+		h.tb.Fatal(err)
+	}
+	return h.Do(r)
+}
+
+// PostJSON posts body encoded as JSON to path on the test server
+func (h *Harness) PostJSON(path string, body any) *http.Response {
+	h.tb.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		h.tb.Fatal(err)
+	}
+	r, err := http.NewRequest(http.MethodPost, h.URL(path), bytes.NewReader(data))
+	if err != nil {
+		h.tb.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	return h.Do(r)
+}
+
+// DecodeJSON decodes the body of resp into v, failing the test when it is not valid JSON
+func (h *Harness) DecodeJSON(resp *http.Response, v any) {
+	h.tb.Helper()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.tb.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		h.tb.Fatalf("boldtest: decoding %q: %v", data, err)
+	}
+}
+
+// Reset truncates every table but the migrations table and starts a fresh
+// cookie jar, so the next test starts from the migrated state
+func (h *Harness) Reset() {
+	h.tb.Helper()
+	if err := h.Provider.TruncateAllContext(context.Background(), h.DB, migrations.MigrationsTable); err != nil {
+		h.tb.Fatalf("boldtest: truncating: %v", err)
+	}
+	h.client = h.NewClient()
+}
@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// ErrNoEndpoints is returned when a service resolves to no endpoint
+var ErrNoEndpoints = errors.New("client: no endpoints")
+
+// Resolver returns the endpoints, as host:port, serving a service
+type Resolver interface {
+	Resolve(ctx context.Context, service string) ([]string, error)
+}
+
+// ResolverFunc adapts a function to Resolver
+type ResolverFunc func(ctx context.Context, service string) ([]string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, service string) ([]string, error) {
+	return f(ctx, service)
+}
+
+// StaticResolver resolves services from a fixed list of endpoints per service
+type StaticResolver map[string][]string
+
+func (r StaticResolver) Resolve(_ context.Context, service string) ([]string, error) {
+	endpoints, ok := r[service]
+	if !ok || len(endpoints) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoEndpoints, service)
+	}
+	return endpoints, nil
+}
+
+// SRVResolver resolves services with DNS SRV records, looking up
+// _<Service>._<Proto>.<service>, as in _http._tcp.users.internal
+type SRVResolver struct {
+	// Service and Proto name the record, "http" and "tcp" when empty
+	Service string
+	Proto   string
+	// Resolver looks the records up, net.DefaultResolver when nil
+	Resolver *net.Resolver
+}
+
+func (r SRVResolver) Resolve(ctx context.Context, service string) ([]string, error) {
+	name, proto := r.Service, r.Proto
+	if name == "" {
+		name = "http"
+	}
+	if proto == "" {
+		proto = "tcp"
+	}
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	_, records, err := resolver.LookupSRV(ctx, name, proto, service)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]string, 0, len(records))
+	for _, record := range records {
+		endpoints = append(endpoints, net.JoinHostPort(record.Target, strconv.Itoa(int(record.Port))))
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoEndpoints, service)
+	}
+	return endpoints, nil
+}
+
+// Balancing strategies
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+)
+
+// Balancer sends requests to the endpoints Resolver returns for their host,
+// so http://users/profile goes to one of the instances of the users service.
+// Endpoints failing on the network or answering 502, 503 or 504 are left out
+// for Cooldown, unless every endpoint is.
+type Balancer struct {
+	// Transport sends the requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+	Resolver  Resolver
+	// Strategy picks the endpoint, RoundRobin when empty
+	Strategy string
+	// TTL is how long resolved endpoints are reused, 30 seconds when zero
+	TTL time.Duration
+	// Cooldown is how long a failing endpoint is left out, 10 seconds when zero
+	Cooldown time.Duration
+	// Clock times the TTL and cooldowns, clock.Default() when nil
+	Clock clock.Clock
+
+	mu       sync.Mutex
+	services map[string]*service
+}
+
+// service is the resolved endpoints of a service
+type service struct {
+	endpoints []*endpoint
+	resolved  time.Time
+	next      int
+}
+
+type endpoint struct {
+	addr      string
+	active    int
+	downUntil time.Time
+}
+
+// RoundTrip sends req to an endpoint of the service named by its host
+func (b *Balancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	ep, err := b.pick(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Host = ep.addr
+	if out.Host == "" {
+		out.Host = req.URL.Host
+	}
+	resp, err := transport(b.Transport).RoundTrip(out)
+	failed := err != nil
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			failed = true
+		}
+	}
+	if err != nil || resp.Body == nil {
+		b.done(ep, failed)
+		return resp, err
+	}
+	// the connection stays active until the body is read
+	resp.Body = &balancedBody{ReadCloser: resp.Body, done: func() { b.done(ep, failed) }}
+	return resp, nil
+}
+
+// pick chooses the endpoint of host to send a request to, counting it active
+func (b *Balancer) pick(ctx context.Context, host string) (*endpoint, error) {
+	now := clock.Or(b.Clock).Now()
+	ttl := b.TTL
+	if ttl == 0 {
+		ttl = 30 * time.Second
+	}
+
+	b.mu.Lock()
+	s := b.services[host]
+	b.mu.Unlock()
+	if s == nil || now.Sub(s.resolved) >= ttl {
+		addrs, err := b.Resolver.Resolve(ctx, host)
+		if err != nil && s == nil {
+			return nil, err
+		}
+		if err == nil {
+			s = b.refresh(host, addrs, now)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	candidates := make([]*endpoint, 0, len(s.endpoints))
+	for _, ep := range s.endpoints {
+		if !ep.downUntil.After(now) {
+			candidates = append(candidates, ep)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = s.endpoints
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoEndpoints, host)
+	}
+
+	var ep *endpoint
+	switch b.Strategy {
+	case LeastConnections:
+		// ties are broken at random so idle endpoints share the load
+		for _, i := range rand.Perm(len(candidates)) {
+			if ep == nil || candidates[i].active < ep.active {
+				ep = candidates[i]
+			}
+		}
+	default:
+		ep = candidates[s.next%len(candidates)]
+		s.next++
+	}
+	ep.active++
+	return ep, nil
+}
+
+// refresh replaces the endpoints of host, keeping the state of those that remain
+func (b *Balancer) refresh(host string, addrs []string, now time.Time) *service {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	old := map[string]*endpoint{}
+	if s := b.services[host]; s != nil {
+		for _, ep := range s.endpoints {
+			old[ep.addr] = ep
+		}
+	}
+	s := &service{resolved: now, next: rand.IntN(max(len(addrs), 1))}
+	for _, addr := range addrs {
+		ep := old[addr]
+		if ep == nil {
+			ep = &endpoint{addr: addr}
+		}
+		s.endpoints = append(s.endpoints, ep)
+	}
+	if b.services == nil {
+		b.services = map[string]*service{}
+	}
+	b.services[host] = s
+	return s
+}
+
+// done records the end of a request to ep, leaving ep out when it failed
+func (b *Balancer) done(ep *endpoint, failed bool) {
+	cooldown := b.Cooldown
+	if cooldown == 0 {
+		cooldown = 10 * time.Second
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ep.active--
+	if failed {
+		ep.downUntil = clock.Or(b.Clock).Now().Add(cooldown)
+	}
+}
+
+// balancedBody reports the end of a request once its body is closed
+type balancedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *balancedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,82 @@
+// Package client provides http.RoundTrippers for calls between services,
+// each wrapping the next so they compose into one http.Client:
+//
+//	c := &http.Client{Transport: &client.Retry{
+//		Transport: &client.Hedge{
+//			Transport: &client.Balancer{Resolver: client.SRVResolver{}},
+//		},
+//	}}
+//	resp, err := c.Get("http://users.internal/profile")
+//
+// Retry retries failed idempotent requests, waiting as long as the server
+// asks with Retry-After or rate limit headers, and within a budget so
+// retries do not multiply the load of a struggling dependency. Balancer
+// spreads requests over the endpoints a Resolver finds for their host,
+// leaving out the failing ones, so a retry lands on another instance. Hedge
+// sends a second GET when the first is slower than most, keeping the faster.
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// transport returns rt, or http.DefaultTransport when nil
+func transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		return http.DefaultTransport
+	}
+	return rt
+}
+
+// idempotent reports whether req can be sent again: its method is
+// idempotent, or it carries an Idempotency-Key, and its body can be replayed
+func idempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// replay returns a copy of req with a fresh body, to send it once more
+func replay(ctx context.Context, req *http.Request) (*http.Request, error) {
+	again := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		again.Body = body
+	}
+	return again, nil
+}
+
+// discard drains and closes the body of a response that is not returned,
+// so its connection can be reused
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	io.CopyN(io.Discard, resp.Body, 64<<10)
+	resp.Body.Close()
+}
+
+// sleep waits for d on c, reporting false when ctx is done first
+func sleep(ctx context.Context, c clock.Clock, d time.Duration) bool {
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
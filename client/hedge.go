@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// hedgeSamples is the number of recent latencies Hedge derives its delay from
+const hedgeSamples = 256
+
+// Hedge sends a second GET or HEAD when the first one takes longer than most
+// do, returning whichever answers first and cancelling the other, to cut
+// the tail latency of a dependency at the cost of a few extra requests.
+// Requests with another method or a body are sent once.
+type Hedge struct {
+	// Transport sends the requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+	// Delay is how long the first attempt runs before the second is sent.
+	// When zero it is the Percentile of the latencies of the last 256
+	// requests, and requests are not hedged until 20 were measured.
+	Delay time.Duration
+	// Percentile of the recent latencies used as the delay, 0.95 when zero
+	Percentile float64
+	// Clock times the attempts, clock.Default() when nil
+	Clock clock.Clock
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+type hedgeResult struct {
+	resp    *http.Response
+	err     error
+	attempt int
+	latency time.Duration
+}
+
+// RoundTrip sends req, hedging it once the delay passed
+func (h *Hedge) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := transport(h.Transport)
+	if (req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead) ||
+		(req.Body != nil && req.Body != http.NoBody) {
+		return rt.RoundTrip(req)
+	}
+	c := clock.Or(h.Clock)
+	delay, hedge := h.delay()
+
+	ctx := req.Context()
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	launch := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		attempt := len(cancels) - 1
+		go func() {
+			started := c.Now()
+			resp, err := rt.RoundTrip(req.Clone(attemptCtx))
+			results <- hedgeResult{resp: resp, err: err, attempt: attempt, latency: c.Now().Sub(started)}
+		}()
+	}
+	// abandon cancels the other attempts, closing the responses they still return
+	abandon := func(pending, winner int) {
+		for i, cancel := range cancels {
+			if i != winner {
+				cancel()
+			}
+		}
+		go func() {
+			for range pending {
+				if r := <-results; r.resp != nil {
+					r.resp.Body.Close()
+				}
+			}
+		}()
+	}
+
+	launch()
+	var hedged <-chan time.Time
+	if hedge {
+		timer := c.NewTimer(delay)
+		defer timer.Stop()
+		hedged = timer.C()
+	}
+
+	pending := 1
+	for {
+		select {
+		case <-hedged:
+			hedged = nil
+			pending++
+			launch()
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				h.record(r.latency)
+				abandon(pending, r.attempt)
+				r.resp.Body = &cancelBody{ReadCloser: r.resp.Body, cancel: cancels[r.attempt]}
+				return r.resp, nil
+			}
+			cancels[r.attempt]()
+			// a failure is returned at once unless the other attempt may still succeed
+			if pending == 0 {
+				abandon(pending, -1)
+				return nil, r.err
+			}
+		case <-ctx.Done():
+			abandon(pending, -1)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// delay returns how long to wait before hedging, reporting false while too
+// few latencies were measured
+func (h *Hedge) delay() (time.Duration, bool) {
+	if h.Delay > 0 {
+		return h.Delay, true
+	}
+	percentile := h.Percentile
+	if percentile == 0 {
+		percentile = 0.95
+	}
+
+	h.mu.Lock()
+	latencies := slices.Clone(h.latencies)
+	h.mu.Unlock()
+	if len(latencies) < 20 {
+		return 0, false
+	}
+	slices.Sort(latencies)
+	return latencies[min(int(percentile*float64(len(latencies))), len(latencies)-1)], true
+}
+
+// record adds the latency of a successful attempt to the recent ones
+func (h *Hedge) record(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeSamples {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeSamples
+}
+
+// cancelBody cancels the context of the winning attempt once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// Retry retries requests that failed on the network or were answered with
+// 429, 502, 503 or 504, as long as they are idempotent and their body can be
+// replayed. It waits as long as the server asks with Retry-After, or with a
+// RateLimit-Reset or X-RateLimit-Reset header once no requests remain, and
+// otherwise backs off exponentially with full jitter.
+type Retry struct {
+	// Transport sends the requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+	// MaxAttempts bounds the attempts per request, the first included, 3 when zero
+	MaxAttempts int
+	// BaseDelay is the backoff ceiling of the first retry, doubled on every
+	// further one, 100 milliseconds when zero
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, 10 seconds when zero. A server asking to
+	// wait longer gets its response returned rather than retried.
+	MaxDelay time.Duration
+	// Budget limits the retries of every request sent through Retry, a
+	// Budget with its defaults when nil
+	Budget *Budget
+	// Clock times the waits, clock.Default() when nil
+	Clock clock.Clock
+
+	once sync.Once
+}
+
+// RoundTrip sends req, retrying it as configured
+func (r *Retry) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.once.Do(func() {
+		if r.Budget == nil {
+			r.Budget = &Budget{Clock: r.Clock}
+		}
+	})
+	attempts := r.MaxAttempts
+	if attempts == 0 {
+		attempts = 3
+	}
+	ctx := req.Context()
+	r.Budget.request()
+
+	attempt := req
+	for n := 1; ; n++ {
+		resp, err := transport(r.Transport).RoundTrip(attempt)
+		if n >= attempts || ctx.Err() != nil || !idempotent(req) || !retryable(resp, err) {
+			return resp, err
+		}
+		delay, ok := r.delay(resp, n)
+		if !ok || !r.Budget.retry() {
+			return resp, err
+		}
+		discard(resp)
+
+		if !sleep(ctx, clock.Or(r.Clock), delay) {
+			return nil, ctx.Err()
+		}
+		if attempt, err = replay(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether the outcome of an attempt is worth retrying
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay returns how long to wait before attempt n+1, reporting false when
+// the server asks for longer than MaxDelay
+func (r *Retry) delay(resp *http.Response, n int) (time.Duration, bool) {
+	maxDelay := r.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = 10 * time.Second
+	}
+	if wait, ok := r.serverDelay(resp); ok {
+		if wait > maxDelay {
+			return 0, false
+		}
+		// a tenth of jitter keeps the clients told the same time apart
+		return wait + rand.N(wait/10+1), true
+	}
+
+	base := r.BaseDelay
+	if base == 0 {
+		base = 100 * time.Millisecond
+	}
+	ceiling := maxDelay
+	if shift := n - 1; shift < 30 && base<<shift > 0 && base<<shift < maxDelay {
+		ceiling = base << shift
+	}
+	return rand.N(ceiling) + 1, true
+}
+
+// serverDelay reads how long the server asks to wait from Retry-After or,
+// when no requests remain, from the rate limit headers
+func (r *Retry) serverDelay(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	now := clock.Or(r.Clock).Now()
+
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		if resp.Header.Get(prefix+"Remaining") != "0" {
+			continue
+		}
+		reset, err := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64)
+		if err != nil {
+			continue
+		}
+		// X-RateLimit-Reset is a Unix time for some APIs, a number of seconds for others
+		if reset > 1_000_000_000 {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+		return max(time.Duration(reset)*time.Second, 0), true
+	}
+	return 0, false
+}
+
+// Budget caps retries at a share of the requests, so that when a dependency
+// fails every request, retrying adds Ratio to its load instead of
+// multiplying it by the number of attempts. Every request deposits Ratio of
+// a retry and every retry withdraws one, with MinPerSecond retries allowed
+// whatever the traffic. A Budget can be shared by several Retry transports.
+type Budget struct {
+	// Ratio is the share of requests that may be retried, 0.1 when zero
+	Ratio float64
+	// MinPerSecond is the retries allowed per second regardless of the
+	// requests, 10 when zero
+	MinPerSecond int
+	// Clock times the refill of MinPerSecond, clock.Default() when nil
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	tokens  float64
+	refill  float64
+	updated time.Time
+}
+
+func (b *Budget) limits() (ratio, perSecond, capacity float64) {
+	ratio = b.Ratio
+	if ratio == 0 {
+		ratio = 0.1
+	}
+	perSecond = float64(b.MinPerSecond)
+	if perSecond == 0 {
+		perSecond = 10
+	}
+	// deposits add up to at most ten seconds worth of MinPerSecond
+	return ratio, perSecond, 10 * perSecond
+}
+
+// request deposits the share of a retry a request earns
+func (b *Budget) request() {
+	ratio, _, capacity := b.limits()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+ratio, capacity)
+}
+
+// retry withdraws a retry, reporting false when the budget is spent
+func (b *Budget) retry() bool {
+	_, perSecond, _ := b.limits()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := clock.Or(b.Clock).Now()
+	if b.updated.IsZero() {
+		b.refill = perSecond
+	} else if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.refill = min(b.refill+elapsed*perSecond, perSecond)
+	}
+	b.updated = now
+
+	switch {
+	case b.refill >= 1:
+		b.refill--
+	case b.tokens >= 1:
+		b.tokens--
+	default:
+		return false
+	}
+	return true
+}
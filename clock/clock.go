@@ -0,0 +1,181 @@
+// Package clock abstracts the passing of time so tests can control it.
+// Subsystems that expire, throttle or schedule things read the time through a
+// Clock, the system clock unless another one is injected:
+//
+//	c := clock.NewMock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	clock.Use(c)
+//	defer clock.Use(nil)
+//	c.Advance(time.Hour)
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and creates timers
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event, like time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Real returns the system clock
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+var (
+	mu           sync.RWMutex
+	defaultClock Clock = realClock{}
+)
+
+// Use sets the Clock returned by Default, nil restores the system clock
+func Use(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	mu.Lock()
+	defaultClock = c
+	mu.Unlock()
+}
+
+// Default returns the Clock set with Use, the system clock by default
+func Default() Clock {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultClock
+}
+
+// Or returns c, or Default when c is nil, for components with an optional Clock field
+func Or(c Clock) Clock {
+	if c != nil {
+		return c
+	}
+	return Default()
+}
+
+// Now returns the time of the default Clock
+func Now() time.Time {
+	return Default().Now()
+}
+
+// Mock is a Clock that only moves when told to. Its timers fire once Advance
+// or Set moves the time past their deadline.
+type Mock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*mockTimer
+}
+
+// NewMock returns a Mock set to now
+func NewMock(now time.Time) *Mock {
+	m := &Mock{now: now}
+	m.changed = sync.NewCond(&m.mu)
+	return m
+}
+
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &mockTimer{mock: m, deadline: m.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- m.now
+		return t
+	}
+	m.timers = append(m.timers, t)
+	m.changed.Broadcast()
+	return t
+}
+
+// Advance moves the time forward by d, firing the timers due by then in deadline order
+func (m *Mock) Advance(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// Set moves the time to now, firing the timers due by then in deadline order
+func (m *Mock) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = now
+	sort.SliceStable(m.timers, func(i, j int) bool {
+		return m.timers[i].deadline.Before(m.timers[j].deadline)
+	})
+	pending := m.timers[:0]
+	for _, t := range m.timers {
+		if t.deadline.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- t.deadline
+	}
+	m.timers = pending
+	m.changed.Broadcast()
+}
+
+// BlockUntil waits until n timers are pending, so a test can advance the time
+// once the code under test is waiting on it
+func (m *Mock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.timers) < n {
+		m.changed.Wait()
+	}
+}
+
+type mockTimer struct {
+	mock     *Mock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *mockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	m := t.mock
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, pending := range m.timers {
+		if pending == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			m.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
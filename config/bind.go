@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Bind fills the struct pointed to by dst from the configuration and
+// validates it, returning every problem at once so an application can refuse
+// to boot with a half complete configuration.
+//
+// Fields are read from the key in their config tag, or their lowercased name,
+// and nested structs prefix the keys of their fields with their own key. A
+// default tag provides the value of unset keys, and the comma separated rules
+// of a validate tag are checked: required, url, oneof=a|b, min=n and max=n.
+// min and max compare numbers by value and strings and slices by length.
+//
+//	type AppConfig struct {
+//		URL      string        `config:"app.url" validate:"required,url"`
+//		Timeout  time.Duration `config:"http.timeout" default:"30s"`
+//		Database struct {
+//			Host string `validate:"required"`
+//			Port int    `default:"5432" validate:"min=1,max=65535"`
+//		} `config:"database"`
+//	}
+func Bind(dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Bind requires a pointer to a struct, got %T", dst)
+	}
+	return errors.Join(bindStruct(v.Elem(), "")...)
+}
+
+func bindStruct(v reflect.Value, prefix string) []error {
+	var errs []error
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Tag.Get("config")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		fv := v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, bindStruct(fv, key)...)
+			continue
+		}
+
+		if err := bindField(fv, key, field.Tag.Get("default")); err != nil {
+			errs = append(errs, fmt.Errorf("config: %s: %w", key, err))
+			continue
+		}
+		for _, err := range validate(fv, field.Tag.Get("validate")) {
+			errs = append(errs, fmt.Errorf("config: %s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+// bindField sets v from key, or from def when key is not set
+func bindField(v reflect.Value, key, def string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String {
+		items := Strings(key)
+		if len(items) == 0 && def != "" {
+			items = strings.Split(def, ",")
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+		return nil
+	}
+
+	value, ok := Lookup(key)
+	if !ok {
+		if def == "" {
+			return nil
+		}
+		value = def
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case v.CanInt():
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case v.CanUint():
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		v.SetUint(n)
+	case v.CanFloat():
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// validate checks v against the comma separated rules
+func validate(v reflect.Value, rules string) []error {
+	var errs []error
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "":
+		case "required":
+			if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+				errs = append(errs, errors.New("is required"))
+			}
+		case "url":
+			if s := v.String(); s != "" {
+				if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+					errs = append(errs, fmt.Errorf("%q is not an absolute URL", s))
+				}
+			}
+		case "oneof":
+			if s := fmt.Sprint(v.Interface()); s != "" && !slices.Contains(strings.Split(arg, "|"), s) {
+				errs = append(errs, fmt.Errorf("%q is not one of %s", s, strings.ReplaceAll(arg, "|", ", ")))
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s rule %q", name, arg))
+				continue
+			}
+			n, ok := measure(v)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s does not apply to %s", name, v.Type()))
+				continue
+			}
+			if name == "min" && n < limit {
+				errs = append(errs, fmt.Errorf("must be at least %s", arg))
+			}
+			if name == "max" && n > limit {
+				errs = append(errs, fmt.Errorf("must be at most %s", arg))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("unknown validation rule %q", name))
+		}
+	}
+	return errs
+}
+
+// measure returns the value compared by min and max rules
+func measure(v reflect.Value) (float64, bool) {
+	switch {
+	case v.Kind() == reflect.String || v.Kind() == reflect.Slice:
+		return float64(v.Len()), true
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	}
+	return 0, false
+}
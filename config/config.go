@@ -0,0 +1,265 @@
+// Package config loads application configuration from .env files, layered
+// YAML files and the process environment.
+//
+// Load reads, from lowest to highest precedence, config.yaml,
+// config.<env>.yaml and environment variables, where the key database.host
+// is overridden by the variable BOLD_DATABASE_HOST. Before that, .env and
+// .env.<env> are loaded into the process environment without replacing
+// variables that are already set.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/secrets"
+)
+
+// EnvVar names the environment variable selecting the environment, APP_ENV is read as a fallback
+const EnvVar = "BOLD_ENV"
+
+// Environment names
+const (
+	Production  = "production"
+	Staging     = "staging"
+	Development = "development"
+	Testing     = "testing"
+)
+
+var (
+	mu        sync.RWMutex
+	values    = map[string]string{}
+	loadedDir string
+)
+
+// Env returns the current environment, development when none is set
+func Env() string {
+	if env := os.Getenv(EnvVar); env != "" {
+		return env
+	}
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	return Development
+}
+
+// IsProduction reports whether the application runs in production
+func IsProduction() bool {
+	return Env() == Production
+}
+
+// IsEnvSet reports whether BOLD_ENV or APP_ENV names the environment
+func IsEnvSet() bool {
+	return os.Getenv(EnvVar) != "" || os.Getenv("APP_ENV") != ""
+}
+
+// IsProtected reports whether destructive commands must be confirmed, in
+// production and whenever no environment is set, so a server missing its
+// BOLD_ENV fails closed rather than passing for development
+func IsProtected() bool {
+	return IsProduction() || !IsEnvSet()
+}
+
+// IsDevelopment reports whether the application runs in development
+func IsDevelopment() bool {
+	return Env() == Development
+}
+
+// IsTesting reports whether the application runs under tests
+func IsTesting() bool {
+	return Env() == Testing
+}
+
+// Load loads the .env and YAML files of dir, replacing any previously loaded values
+func Load(dir string) error {
+	if err := LoadEnvFiles(dir); err != nil {
+		return err
+	}
+
+	loaded := map[string]string{}
+	for _, name := range []string{"config.yaml", "config." + Env() + ".yaml"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+
+		layer, err := parseYAML(data)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+		for key, value := range layer {
+			loaded[key] = value
+		}
+	}
+
+	mu.Lock()
+	values = loaded
+	loadedDir = dir
+	mu.Unlock()
+
+	logLevel.Set(parseLevel(String("log.level", "info")))
+	return nil
+}
+
+// LoadEnvFiles loads .env and .env.<env> from dir into the process
+// environment. Variables already set take precedence over both files, and
+// .env.<env> over .env, where env may itself be set in .env.
+func LoadEnvFiles(dir string) error {
+	base, err := readEnvFile(filepath.Join(dir, ".env"))
+	if err != nil {
+		return err
+	}
+
+	env := Env()
+	if _, set := os.LookupEnv(EnvVar); !set && base[EnvVar] != "" {
+		env = base[EnvVar]
+	}
+
+	override, err := readEnvFile(filepath.Join(dir, ".env."+env))
+	if err != nil {
+		return err
+	}
+	for key, value := range override {
+		base[key] = value
+	}
+
+	for key, value := range base {
+		if _, set := os.LookupEnv(key); !set {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// Set overrides a configuration value, mostly useful in tests
+func Set(key, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	values[key] = value
+}
+
+// Lookup returns the value of a dotted key and whether it is set
+func Lookup(key string) (string, bool) {
+	if value, ok := os.LookupEnv(envName(key)); ok {
+		return value, true
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	value, ok := values[key]
+	return value, ok
+}
+
+// Get returns the value of a dotted key, or an empty string
+func Get(key string) string {
+	value, _ := Lookup(key)
+	return value
+}
+
+// String returns the value of key, or def when it is not set
+func String(key, def string) string {
+	if value, ok := Lookup(key); ok {
+		return value
+	}
+	return def
+}
+
+// Int returns the value of key as an int, or def when it is not set or invalid
+func Int(key string, def int) int {
+	if value, ok := Lookup(key); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// Bool returns the value of key as a bool, or def when it is not set or invalid
+func Bool(key string, def bool) bool {
+	if value, ok := Lookup(key); ok {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// Duration returns the value of key as a time.Duration, or def when it is not set or invalid
+func Duration(key string, def time.Duration) time.Duration {
+	if value, ok := Lookup(key); ok {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// Secret returns the value of key, resolving values of the form
+// "secret:name" through the default secrets Store, so a database password
+// can be configured as "secret:db_password"
+func Secret(ctx context.Context, key string) (string, error) {
+	value, ok := Lookup(key)
+	if !ok {
+		return "", fmt.Errorf("config: %s is not set", key)
+	}
+	if name, ok := strings.CutPrefix(value, "secret:"); ok {
+		return secrets.Get(ctx, name)
+	}
+	return value, nil
+}
+
+// Strings returns the items of a YAML list, or the comma separated items of
+// an environment variable overriding it
+func Strings(key string) []string {
+	if value, ok := os.LookupEnv(envName(key)); ok {
+		if value == "" {
+			return nil
+		}
+		items := strings.Split(value, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		return items
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	prefix := key + "."
+	indexed := map[int]string{}
+	for k, v := range values {
+		if i, err := strconv.Atoi(strings.TrimPrefix(k, prefix)); err == nil && strings.HasPrefix(k, prefix) {
+			indexed[i] = v
+		}
+	}
+
+	indexes := make([]int, 0, len(indexed))
+	for i := range indexed {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	items := make([]string, len(indexes))
+	for n, i := range indexes {
+		items[n] = indexed[i]
+	}
+	return items
+}
+
+// envName returns the environment variable overriding a dotted key, prefixed
+// with BOLD_ so top level keys never pick up variables such as USER or PATH
+func envName(key string) string {
+	return "BOLD_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readEnvFile parses a .env file of KEY=value lines, returning no values when it does not exist
+func readEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("config: %s:%d: expected KEY=value", path, n)
+		}
+		values[strings.TrimSpace(key)] = unquote(strings.TrimSpace(stripComment(value)))
+	}
+	return values, scanner.Err()
+}
+
+// parseYAML parses the subset of YAML used by configuration files: nested
+// mappings of scalars and lists of scalars, flattened to dotted keys with
+// list items stored under key.0, key.1 and so on
+func parseYAML(data []byte) (map[string]string, error) {
+	type mapping struct {
+		indent int
+		prefix string
+	}
+
+	values := map[string]string{}
+	items := map[string]int{}
+	var open []mapping
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(stripComment(scanner.Text()), " \t")
+		text := strings.TrimSpace(line)
+		if text == "" || text == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		item := text == "-" || strings.HasPrefix(text, "- ")
+
+		// a list may sit at the same indentation as its key
+		for len(open) > 0 {
+			top := open[len(open)-1]
+			if indent > top.indent || (item && indent == top.indent) {
+				break
+			}
+			open = open[:len(open)-1]
+		}
+
+		prefix := ""
+		if len(open) > 0 {
+			prefix = open[len(open)-1].prefix
+		}
+
+		if item {
+			if prefix == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a key", n)
+			}
+			values[prefix+"."+strconv.Itoa(items[prefix])] = unquote(strings.TrimSpace(text[1:]))
+			items[prefix]++
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		key = unquote(strings.TrimSpace(key))
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			open = append(open, mapping{indent: indent, prefix: key})
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			inner := strings.TrimSpace(value[1 : len(value)-1])
+			if inner == "" {
+				continue
+			}
+			for i, part := range strings.Split(inner, ",") {
+				values[key+"."+strconv.Itoa(i)] = unquote(strings.TrimSpace(part))
+			}
+		default:
+			values[key] = unquote(value)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// stripComment removes a trailing # comment that is not inside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes matching single or double quotes, interpreting escapes in double quoted values
+func unquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+		return value[1 : len(value)-1]
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	reloadMu  sync.Mutex
+	onReload  []func()
+	logLevel  = new(slog.LevelVar)
+	errNoLoad = errors.New("config: Reload called before Load")
+)
+
+// Reload loads the directory of the last Load again and calls the OnReload
+// callbacks. Since values read through Get and its typed variants are looked
+// up on every call, knobs such as rate limits, maintenance mode and feature
+// flags change without a restart. Variables from .env files were exported to
+// the process environment on first load and are not reloaded.
+func Reload() error {
+	mu.RLock()
+	dir := loadedDir
+	mu.RUnlock()
+	if dir == "" {
+		return errNoLoad
+	}
+
+	if err := Load(dir); err != nil {
+		return err
+	}
+
+	reloadMu.Lock()
+	callbacks := append([]func(){}, onReload...)
+	reloadMu.Unlock()
+	for _, fn := range callbacks {
+		fn()
+	}
+	return nil
+}
+
+// OnReload registers fn to be called after every successful Reload
+func OnReload(fn func()) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	onReload = append(onReload, fn)
+}
+
+// ReloadOnSIGHUP calls Reload whenever the process receives SIGHUP until ctx
+// is done, passing failures to onError when not nil
+func ReloadOnSIGHUP(ctx context.Context, onError func(error)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// LogLevel returns a slog level following the log.level key (debug, info,
+// warn or error), updated by Load and Reload. Pass it as the Level of a
+// slog.HandlerOptions to change verbosity at runtime.
+func LogLevel() *slog.LevelVar {
+	return logLevel
+}
+
+// Feature reports whether the feature flag features.<name> is enabled
+func Feature(name string) bool {
+	return Bool("features."+name, false)
+}
+
+// Maintenance reports whether app.maintenance is enabled
+func Maintenance() bool {
+	return Bool("app.maintenance", false)
+}
+
+func parseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
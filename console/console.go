@@ -0,0 +1,136 @@
+// Package console runs the commands of an application binary, such as
+// route:list or migrate, dispatching on the first command line argument.
+//
+//	c := console.New()
+//	c.Register(routing.RouteListCommand(app))
+//	if err := c.Run(ctx, os.Args[1:]); err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//		os.Exit(1)
+//	}
+package console
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Command is a named console command
+type Command struct {
+	Name        string
+	Description string
+	// Flags declares the flags of the command, it may be nil
+	Flags func(fs *flag.FlagSet)
+	Run   func(ctx context.Context, in *Input) error
+}
+
+// Input is what a running command receives
+type Input struct {
+	// Args are the arguments left after the flags
+	Args  []string
+	Flags *flag.FlagSet
+	Out   io.Writer
+	In    io.Reader
+
+	// reader buffers In across prompts, so input read ahead by one prompt is
+	// left for the next
+	reader *bufio.Reader
+}
+
+// Bool returns the value of a boolean flag declared by the command
+func (in *Input) Bool(name string) bool {
+	f := in.Flags.Lookup(name)
+	return f != nil && f.Value.String() == "true"
+}
+
+// String returns the value of a flag declared by the command
+func (in *Input) String(name string) string {
+	if f := in.Flags.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+// Confirm asks a yes or no question on Out and reads the answer from In, defaulting to no
+func (in *Input) Confirm(question string) bool {
+	fmt.Fprintf(in.Out, "%s [y/N] ", question)
+	if in.reader == nil {
+		in.reader = bufio.NewReader(in.In)
+	}
+	answer, _ := in.reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// Console dispatches command line arguments to registered commands
+type Console struct {
+	commands map[string]*Command
+	// Out receives command output, os.Stdout by default
+	Out io.Writer
+	// In is read by prompts, os.Stdin by default
+	In io.Reader
+}
+
+func New() *Console {
+	return &Console{commands: map[string]*Command{}}
+}
+
+// Register adds commands, replacing any registered under the same name
+func (c *Console) Register(commands ...*Command) *Console {
+	for _, cmd := range commands {
+		c.commands[cmd.Name] = cmd
+	}
+	return c
+}
+
+// Run runs the command named by args[0], listing the commands when there is none
+func (c *Console) Run(ctx context.Context, args []string) error {
+	out, in := c.Out, c.In
+	if out == nil {
+		out = os.Stdout
+	}
+	if in == nil {
+		in = os.Stdin
+	}
+
+	if len(args) == 0 || args[0] == "list" || args[0] == "help" {
+		return c.list(out)
+	}
+
+	cmd, ok := c.commands[args[0]]
+	if !ok {
+		return fmt.Errorf("console: unknown command %q", args[0])
+	}
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	if cmd.Flags != nil {
+		cmd.Flags(fs)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	return cmd.Run(ctx, &Input{Args: fs.Args(), Flags: fs, Out: out, In: in})
+}
+
+func (c *Console) list(out io.Writer) error {
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Available commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", name, c.commands[name].Description)
+	}
+	return w.Flush()
+}
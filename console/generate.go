@@ -0,0 +1,46 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Generate renders tmpl with data into a new file at path, creating its
+// directory, and reports it on out. It refuses to overwrite an existing file.
+func Generate(out io.Writer, path string, tmpl *template.Template, data any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Created %s\n", path)
+	return nil
+}
+
+var (
+	wordBoundary    = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	acronymBoundary = regexp.MustCompile(`([A-Z]+)([A-Z][a-z])`)
+)
+
+// SnakeCase turns a Go identifier such as ShowUser or APIIndex into show_user
+// or api_index, for file names
+func SnakeCase(name string) string {
+	name = acronymBoundary.ReplaceAllString(name, "${1}_${2}")
+	return strings.ToLower(wordBoundary.ReplaceAllString(name, "${1}_${2}"))
+}
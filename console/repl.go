@@ -0,0 +1,81 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Evaluator runs source code for the tinker command. Bold does not ship a Go
+// interpreter, so applications adapt one such as yaegi, exposing the booted
+// database, config and services as symbols.
+type Evaluator interface {
+	Eval(ctx context.Context, src string) (any, error)
+}
+
+// EvaluatorFunc adapts a function to the Evaluator interface
+type EvaluatorFunc func(ctx context.Context, src string) (any, error)
+
+func (f EvaluatorFunc) Eval(ctx context.Context, src string) (any, error) {
+	return f(ctx, src)
+}
+
+// TinkerCommand returns the tinker command, an interactive session that runs
+// boot once, then evaluates each line read from the input with the evaluator
+// returned by boot and prints the result. A line ending in a backslash
+// continues on the next one, and exit or quit ends the session.
+func TinkerCommand(boot func(ctx context.Context) (Evaluator, error)) *Command {
+	return &Command{
+		Name:        "tinker",
+		Description: "Interact with the booted application",
+		Run: func(ctx context.Context, in *Input) error {
+			evaluator, err := boot(ctx)
+			if err != nil {
+				return err
+			}
+
+			scanner := bufio.NewScanner(in.In)
+			var src strings.Builder
+			prompt := ">>> "
+			for {
+				fmt.Fprint(in.Out, prompt)
+				if !scanner.Scan() {
+					fmt.Fprintln(in.Out)
+					return scanner.Err()
+				}
+				line := scanner.Text()
+
+				if strings.HasSuffix(line, "\\") {
+					src.WriteString(strings.TrimSuffix(line, "\\") + "\n")
+					prompt = "... "
+					continue
+				}
+				src.WriteString(line)
+				code := strings.TrimSpace(src.String())
+				src.Reset()
+				prompt = ">>> "
+
+				switch code {
+				case "":
+					continue
+				case "exit", "quit":
+					return nil
+				}
+
+				result, err := evaluator.Eval(ctx, code)
+				if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					fmt.Fprintf(in.Out, "error: %v\n", err)
+					continue
+				}
+				if result != nil {
+					fmt.Fprintf(in.Out, "%#v\n", result)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotHeld is returned when releasing a lock that expired or is held by someone else
+var ErrNotHeld = errors.New("driver: lock not held")
+
+// KV is a key-value store with expiring keys
+type KV interface {
+	// Get returns the value stored at key and whether it exists
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value at key, without expiry when ttl is zero
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	// Incr atomically increments the counter at key, starting its ttl when the key is created
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Message is a payload received on a pub/sub channel
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+// PubSub publishes messages to and subscribes to named channels
+type PubSub interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe delivers messages of the given channels until ctx is done, then closes the returned channel
+	Subscribe(ctx context.Context, channels ...string) (<-chan Message, error)
+}
+
+// Locker provides mutual exclusion across processes
+type Locker interface {
+	// Lock tries to acquire key for ttl, returning the token needed to release it
+	Lock(ctx context.Context, key string, ttl time.Duration) (token string, ok bool, err error)
+	// Unlock releases key if it is still held with token
+	Unlock(ctx context.Context, key, token string) error
+}
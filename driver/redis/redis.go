@@ -0,0 +1,239 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/go-bold/bold/driver"
+)
+
+// Options configures a Client
+type Options struct {
+	Addr     string
+	Password string
+	DB       int
+	// PoolSize is the maximum number of idle connections kept, 10 when zero
+	PoolSize    int
+	DialTimeout time.Duration
+}
+
+// Client is a minimal Redis client implementing the driver KV, PubSub and Locker interfaces
+type Client struct {
+	opts Options
+	idle chan *conn
+}
+
+var (
+	_ driver.KV     = (*Client)(nil)
+	_ driver.PubSub = (*Client)(nil)
+	_ driver.Locker = (*Client)(nil)
+)
+
+func New(opts Options) *Client {
+	if opts.PoolSize == 0 {
+		opts.PoolSize = 10
+	}
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	return &Client{opts: opts, idle: make(chan *conn, opts.PoolSize)}
+}
+
+// Do runs a raw command, e.g. Do(ctx, "HSET", "key", "field", "value")
+func (c *Client) Do(ctx context.Context, args ...any) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, _ := ctx.Deadline()
+	reply, err := cn.do(deadline, args...)
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes the idle connections
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	return reply.([]byte), true, nil
+}
+
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl > 0 {
+		_, err := c.Do(ctx, "SET", key, value, "PX", ttl.Milliseconds())
+		return err
+	}
+	_, err := c.Do(ctx, "SET", key, value)
+	return err
+}
+
+func (c *Client) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []any{"DEL"}
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// incrScript increments the key and sets its expiry when it creates it, in a
+// single step so the key never outlives a lost PEXPIRE
+const incrScript = `local n = redis.call("INCR", KEYS[1]) if n == 1 and tonumber(ARGV[1]) > 0 then redis.call("PEXPIRE", KEYS[1], ARGV[1]) end return n`
+
+func (c *Client) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	reply, err := c.Do(ctx, "EVAL", incrScript, 1, key, ttl.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	return reply.(int64), nil
+}
+
+func (c *Client) Publish(ctx context.Context, channel string, payload []byte) error {
+	_, err := c.Do(ctx, "PUBLISH", channel, payload)
+	return err
+}
+
+// Subscribe opens a dedicated connection for the subscription, closed once ctx is done
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (<-chan driver.Message, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{"SUBSCRIBE"}
+	for _, ch := range channels {
+		args = append(args, ch)
+	}
+	if err := cn.write(args...); err != nil {
+		cn.Close()
+		return nil, err
+	}
+
+	messages := make(chan driver.Message)
+	go func() {
+		<-ctx.Done()
+		cn.Close()
+	}()
+	go func() {
+		defer close(messages)
+		for {
+			reply, err := cn.read()
+			if err != nil {
+				return
+			}
+			parts, ok := reply.([]any)
+			if !ok || len(parts) != 3 || string(asBytes(parts[0])) != "message" {
+				continue
+			}
+			msg := driver.Message{Channel: string(asBytes(parts[1])), Payload: asBytes(parts[2])}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// unlockScript deletes the lock key only if it still holds the caller's token
+const unlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+
+func (c *Client) Lock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	token := hex.EncodeToString(b)
+
+	reply, err := c.Do(ctx, "SET", key, token, "NX", "PX", ttl.Milliseconds())
+	if err != nil || reply == nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+func (c *Client) Unlock(ctx context.Context, key, token string) error {
+	reply, err := c.Do(ctx, "EVAL", unlockScript, 1, key, token)
+	if err != nil {
+		return err
+	}
+	if n, _ := reply.(int64); n == 0 {
+		return driver.ErrNotHeld
+	}
+	return nil
+}
+
+// get takes an idle connection from the pool or dials a new one
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+		return c.dial(ctx)
+	}
+}
+
+// put returns a healthy connection to the pool, closing it when the pool is full
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: c.opts.DialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := newConn(nc)
+
+	deadline, _ := ctx.Deadline()
+	if c.opts.Password != "" {
+		if _, err := cn.do(deadline, "AUTH", c.opts.Password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := cn.do(deadline, "SELECT", c.opts.DB); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func asBytes(v any) []byte {
+	b, _ := v.([]byte)
+	return b
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Error is an error reply sent by the server
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// conn is a single connection speaking RESP2
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func newConn(c net.Conn) *conn {
+	return &conn{Conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}
+}
+
+// do sends a command and reads its reply
+func (c *conn) do(deadline time.Time, args ...any) (any, error) {
+	c.SetDeadline(deadline)
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+func (c *conn) write(args ...any) error {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		case int:
+			b = strconv.AppendInt(nil, int64(v), 10)
+		case int64:
+			b = strconv.AppendInt(nil, v, 10)
+		default:
+			b = fmt.Append(nil, v)
+		}
+		fmt.Fprintf(c.w, "$%d\r\n", len(b))
+		c.w.Write(b)
+		c.w.WriteString("\r\n")
+	}
+	return c.w.Flush()
+}
+
+// read parses one reply: strings and bulk strings as []byte (nil for null),
+// integers as int64, arrays as []any and error replies as Error. Arrays
+// holding error replies are returned along with the errors joined.
+func (c *conn) read() (any, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	kind, body := line[0], string(line[1:len(line)-2])
+
+	switch kind {
+	case '+':
+		return []byte(body), nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		// error replies among the items, as EXEC returns them, are kept in
+		// place and joined once the whole array is read, so the connection
+		// is left at a reply boundary and can go back to the pool
+		items := make([]any, n)
+		var replyErrs []error
+		for i := range items {
+			item, err := c.read()
+			var replyErr Error
+			switch {
+			case errors.As(err, &replyErr):
+				if item == nil {
+					item = replyErr
+				}
+				replyErrs = append(replyErrs, err)
+			case err != nil:
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, errors.Join(replyErrs...)
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
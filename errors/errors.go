@@ -0,0 +1,155 @@
+// Package errors reports unhandled errors to pluggable reporters such as
+// Sentry, Honeybadger or an APM agent. The recovery middleware, queue
+// workers and scheduler report through it, so a vendor integration only
+// needs to implement Reporter and register it with Use.
+//
+// The package also forwards the standard library's New, Is, As, Join and
+// Unwrap, so it can replace the errors import where both are needed.
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event is an error report with the context it happened in
+type Event struct {
+	Err  error
+	Time time.Time
+	// Request is the HTTP request being served, if any
+	Request *http.Request
+	// Stack is the goroutine stack when the report comes from a recovered panic
+	Stack []byte
+	// Tags are short indexed values such as the job type or task name
+	Tags map[string]string
+	// Extra holds arbitrary context attached with WithContext or WithExtra
+	Extra map[string]any
+}
+
+// Reporter sends events to an error tracking service
+type Reporter interface {
+	Report(ctx context.Context, e Event)
+}
+
+// ReporterFunc adapts a function to a Reporter
+type ReporterFunc func(ctx context.Context, e Event)
+
+func (f ReporterFunc) Report(ctx context.Context, e Event) {
+	f(ctx, e)
+}
+
+// Sampled returns a Reporter forwarding a fraction rate, between 0 and 1, of the events to r
+func Sampled(r Reporter, rate float64) Reporter {
+	return ReporterFunc(func(ctx context.Context, e Event) {
+		if rate >= 1 || rand.Float64() < rate {
+			r.Report(ctx, e)
+		}
+	})
+}
+
+var (
+	mu        sync.RWMutex
+	reporters []Reporter
+)
+
+// Use registers reporters receiving every reported error
+func Use(r ...Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	reporters = append(reporters, r...)
+}
+
+// Option adds context to a reported Event
+type Option func(e *Event)
+
+// WithRequest attaches the HTTP request being served
+func WithRequest(r *http.Request) Option {
+	return func(e *Event) { e.Request = r }
+}
+
+// WithStack attaches the stack of a recovered panic
+func WithStack(stack []byte) Option {
+	return func(e *Event) { e.Stack = stack }
+}
+
+// WithTag attaches an indexed tag
+func WithTag(key, value string) Option {
+	return func(e *Event) { e.Tags[key] = value }
+}
+
+// WithExtra attaches an arbitrary value
+func WithExtra(key string, value any) Option {
+	return func(e *Event) { e.Extra[key] = value }
+}
+
+type contextKey struct{}
+
+// WithContext returns a context whose reported errors carry key and value in
+// their Extra, for instance the authenticated user id set by a middleware
+func WithContext(ctx context.Context, key string, value any) context.Context {
+	extra := map[string]any{}
+	if parent, ok := ctx.Value(contextKey{}).(map[string]any); ok {
+		for k, v := range parent {
+			extra[k] = v
+		}
+	}
+	extra[key] = value
+	return context.WithValue(ctx, contextKey{}, extra)
+}
+
+// Report sends err to the registered reporters. Nil errors are ignored.
+func Report(ctx context.Context, err error, opts ...Option) {
+	if err == nil {
+		return
+	}
+
+	mu.RLock()
+	rs := reporters
+	mu.RUnlock()
+	if len(rs) == 0 {
+		return
+	}
+
+	e := Event{Err: err, Time: time.Now(), Tags: map[string]string{}, Extra: map[string]any{}}
+	if extra, ok := ctx.Value(contextKey{}).(map[string]any); ok {
+		for k, v := range extra {
+			e.Extra[k] = v
+		}
+	}
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	for _, r := range rs {
+		r.Report(ctx, e)
+	}
+}
+
+// New is errors.New from the standard library
+func New(text string) error {
+	return stderrors.New(text)
+}
+
+// Is is errors.Is from the standard library
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As is errors.As from the standard library
+func As(err error, target any) bool {
+	return stderrors.As(err, target)
+}
+
+// Join is errors.Join from the standard library
+func Join(errs ...error) error {
+	return stderrors.Join(errs...)
+}
+
+// Unwrap is errors.Unwrap from the standard library
+func Unwrap(err error) error {
+	return stderrors.Unwrap(err)
+}
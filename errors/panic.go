@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PanicError is a recovered panic value as an error. A panic with an error
+// unwraps to it, so Is and As see through the panic.
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	if err, ok := e.Value.(error); ok {
+		return "panic: " + err.Error()
+	}
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Kind returns "error" or "string" for panics with an error or a string,
+// the type of the value otherwise
+func (e *PanicError) Kind() string {
+	switch e.Value.(type) {
+	case error:
+		return "error"
+	case string:
+		return "string"
+	}
+	return fmt.Sprintf("%T", e.Value)
+}
+
+// WithPanic attaches a recovered panic: its kind as the panic tag, the value
+// itself when not an error, and stack filtered by FilterStack
+func WithPanic(p *PanicError, stack []byte) Option {
+	return func(e *Event) {
+		e.Tags["panic"] = p.Kind()
+		if _, ok := p.Value.(error); !ok {
+			e.Extra["panic_value"] = p.Value
+		}
+		e.Stack = FilterStack(stack)
+	}
+}
+
+// FilterStack drops the frames of a goroutine stack, as debug.Stack returns
+// it, that come before the panic, which belong to the recovery code, and the
+// runtime frames, such as the signal handling of a nil dereference. Stacks
+// without a panic frame only lose their runtime frames.
+func FilterStack(stack []byte) []byte {
+	lines := bytes.Split(bytes.TrimRight(stack, "\n"), []byte("\n"))
+	if len(lines) == 0 {
+		return stack
+	}
+
+	// frames are a function line followed by an indented file line
+	type frame struct{ function, file []byte }
+	var frames []frame
+	for i := 1; i < len(lines); i++ {
+		f := frame{function: lines[i]}
+		if i+1 < len(lines) && bytes.HasPrefix(lines[i+1], []byte("\t")) {
+			f.file = lines[i+1]
+			i++
+		}
+		frames = append(frames, f)
+	}
+	for i := len(frames) - 1; i >= 0; i-- {
+		if bytes.HasPrefix(frames[i].function, []byte("panic(")) {
+			frames = frames[i+1:]
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(lines[0])
+	buf.WriteByte('\n')
+	for _, f := range frames {
+		if bytes.HasPrefix(f.function, []byte("runtime.")) || bytes.HasPrefix(f.function, []byte("runtime/")) {
+			continue
+		}
+		buf.Write(f.function)
+		buf.WriteByte('\n')
+		if f.file != nil {
+			buf.Write(f.file)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,102 @@
+// Package idgen generates the identifiers of jobs, batches and requests.
+// The generator is swappable so tests get stable fixtures:
+//
+//	idgen.Use(idgen.Sequential("job"))
+//	defer idgen.Use(nil)
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// Generator returns a new unique identifier on every call
+type Generator interface {
+	NewID() string
+}
+
+// GeneratorFunc adapts a function to the Generator interface
+type GeneratorFunc func() string
+
+func (f GeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUID returns a Generator of random version 4 UUIDs
+func UUID() Generator {
+	return GeneratorFunc(func() string {
+		b := make([]byte, 16)
+		rand.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		h := hex.EncodeToString(b)
+		return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+	})
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns a Generator of ULIDs, 26 character identifiers that sort by
+// creation time, read from clock.Default()
+func ULID() Generator {
+	return GeneratorFunc(func() string {
+		var b [16]byte
+		ms := uint64(clock.Now().UnixMilli())
+		for i := 5; i >= 0; i-- {
+			b[i] = byte(ms)
+			ms >>= 8
+		}
+		rand.Read(b[6:])
+
+		// encode the 128 bits as 26 base32 digits, the first one holding 3 bits
+		var out [26]byte
+		hi := uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 | uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+		lo := uint64(b[8])<<56 | uint64(b[9])<<48 | uint64(b[10])<<40 | uint64(b[11])<<32 | uint64(b[12])<<24 | uint64(b[13])<<16 | uint64(b[14])<<8 | uint64(b[15])
+		for i := 25; i >= 0; i-- {
+			out[i] = crockford[lo&31]
+			lo = lo>>5 | hi<<59
+			hi >>= 5
+		}
+		return string(out[:])
+	})
+}
+
+// Sequential returns a Generator of prefix-1, prefix-2 and so on, for tests
+func Sequential(prefix string) Generator {
+	var n atomic.Uint64
+	return GeneratorFunc(func() string {
+		return fmt.Sprintf("%s-%d", prefix, n.Add(1))
+	})
+}
+
+var (
+	mu               sync.RWMutex
+	defaultGenerator = UUID()
+)
+
+// Use sets the Generator returned by Default, nil restores UUID
+func Use(g Generator) {
+	if g == nil {
+		g = UUID()
+	}
+	mu.Lock()
+	defaultGenerator = g
+	mu.Unlock()
+}
+
+// Default returns the Generator set with Use, UUID by default
+func Default() Generator {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultGenerator
+}
+
+// New returns an identifier from the default Generator
+func New() string {
+	return Default().NewID()
+}
@@ -0,0 +1,107 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"hash/fnv"
+	"time"
+)
+
+// Pool hands out connections that stay dedicated until closed, as *sql.DB
+// and sqlx.DB do
+type Pool interface {
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+// Postgres returns a Locker using PostgreSQL session advisory locks. Each held
+// lock pins a pool connection, so the lock is also released if the process dies.
+func Postgres(db Pool) Locker {
+	return &advisoryLocker{
+		db:      db,
+		acquire: "SELECT pg_try_advisory_lock($1)",
+		release: "SELECT pg_advisory_unlock($1)",
+		key: func(name string) any {
+			h := fnv.New64a()
+			h.Write([]byte(name))
+			return int64(h.Sum64())
+		},
+	}
+}
+
+// MySQL returns a Locker using MySQL GET_LOCK named locks. Each held lock pins
+// a pool connection, so the lock is also released if the process dies.
+func MySQL(db Pool) Locker {
+	return &advisoryLocker{
+		db:      db,
+		acquire: "SELECT COALESCE(GET_LOCK(?, 0), 0) = 1",
+		release: "SELECT RELEASE_LOCK(?)",
+		key: func(name string) any {
+			// lock names are limited to 64 characters
+			if len(name) <= 64 {
+				return name
+			}
+			sum := sha1.Sum([]byte(name))
+			return hex.EncodeToString(sum[:])
+		},
+	}
+}
+
+// SQLServer returns a Locker using SQL Server application locks owned by the
+// session. Each held lock pins a pool connection, so the lock is also
+// released if the process dies.
+func SQLServer(db Pool) Locker {
+	return &advisoryLocker{
+		db: db,
+		acquire: `DECLARE @result int;
+			EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+			SELECT CAST(IIF(@result >= 0, 1, 0) AS bit)`,
+		release: "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'",
+		key: func(name string) any {
+			// resource names are limited to 255 characters
+			if len(name) <= 255 {
+				return name
+			}
+			sum := sha1.Sum([]byte(name))
+			return hex.EncodeToString(sum[:])
+		},
+	}
+}
+
+type advisoryLocker struct {
+	db      Pool
+	acquire string
+	release string
+	key     func(name string) any
+}
+
+func (l *advisoryLocker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := l.key(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, l.acquire, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	return newLock(name, ttl, func(ctx context.Context) error {
+		defer conn.Close()
+		// the unlock runs even when ctx is done, and a connection it fails on
+		// is discarded, closing the session that still holds the lock
+		_, err := conn.ExecContext(context.WithoutCancel(ctx), l.release, key)
+		if err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		return err
+	}), nil
+}
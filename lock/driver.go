@@ -0,0 +1,32 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-bold/bold/driver"
+)
+
+// Driver returns a Locker backed by a driver.Locker such as the Redis client
+func Driver(d driver.Locker) Locker {
+	return &driverLocker{d: d}
+}
+
+type driverLocker struct {
+	d driver.Locker
+}
+
+func (l *driverLocker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	token, ok, err := l.d.Lock(ctx, "lock:"+name, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+
+	// the driver expires the key itself, so no local timer is needed
+	return newLock(name, 0, func(ctx context.Context) error {
+		return l.d.Unlock(ctx, "lock:"+name, token)
+	}), nil
+}
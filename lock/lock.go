@@ -0,0 +1,108 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotAcquired is returned when the lock is held by someone else
+var ErrNotAcquired = errors.New("lock: already held")
+
+// RetryInterval is how often Wait and Block retry a held lock
+var RetryInterval = 100 * time.Millisecond
+
+// Locker acquires named locks shared between processes
+type Locker interface {
+	// Acquire takes the lock without waiting, returning ErrNotAcquired if it is held.
+	// The lock is released automatically once ttl elapses.
+	Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error)
+}
+
+// Lock is a held lock
+type Lock struct {
+	Name string
+
+	once    sync.Once
+	timer   *time.Timer
+	release func(ctx context.Context) error
+	err     error
+}
+
+func newLock(name string, ttl time.Duration, release func(ctx context.Context) error) *Lock {
+	l := &Lock{Name: name, release: release}
+	if ttl > 0 {
+		l.timer = time.AfterFunc(ttl, func() {
+			l.Release(context.Background())
+		})
+	}
+	return l
+}
+
+// Release releases the lock; releasing it again is a no-op
+func (l *Lock) Release(ctx context.Context) error {
+	l.once.Do(func() {
+		if l.timer != nil {
+			l.timer.Stop()
+		}
+		l.err = l.release(ctx)
+	})
+	return l.err
+}
+
+var (
+	mu            sync.RWMutex
+	defaultLocker Locker
+)
+
+// Use sets the Locker used by the package-level Acquire and Block
+func Use(l Locker) {
+	mu.Lock()
+	defaultLocker = l
+	mu.Unlock()
+}
+
+// Default returns the Locker set with Use, or nil
+func Default() Locker {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLocker
+}
+
+// Acquire takes the named lock with the default Locker without waiting
+func Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	l := Default()
+	if l == nil {
+		return nil, errors.New("lock: no default locker configured")
+	}
+	return l.Acquire(ctx, name, ttl)
+}
+
+// Block waits until the named lock can be taken with the default Locker or ctx is done
+func Block(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	l := Default()
+	if l == nil {
+		return nil, errors.New("lock: no default locker configured")
+	}
+	return Wait(ctx, l, name, ttl)
+}
+
+// Wait retries acquiring the named lock on l until it succeeds or ctx is done
+func Wait(ctx context.Context, l Locker, name string, ttl time.Duration) (*Lock, error) {
+	ticker := time.NewTicker(RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := l.Acquire(ctx, name, ttl)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lock, err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package metrics is the facade subsystems record counters, gauges and
+// histograms through, so every part of an application reports to the same
+// backend. Nothing is recorded until a backend such as NewPrometheus is
+// registered with Use.
+//
+// Metrics are declared once, usually as package variables, and resolve the
+// backend when they are recorded, so declaring them before Use is fine:
+//
+//	var jobs = metrics.NewCounter("jobs_total", "Jobs processed", "queue", "status")
+//
+//	jobs.Inc("default", "processed")
+package metrics
+
+import (
+	"sync"
+)
+
+// DefaultBuckets are the histogram buckets used when none are given, suited to durations in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Counter is a value that only goes up. Label values are passed in the
+// order of the label names the metric was declared with.
+type Counter interface {
+	Add(delta float64, labelValues ...string)
+}
+
+// Gauge is a value that goes up and down
+type Gauge interface {
+	Set(value float64, labelValues ...string)
+	Add(delta float64, labelValues ...string)
+}
+
+// Histogram counts observations in buckets
+type Histogram interface {
+	Observe(value float64, labelValues ...string)
+}
+
+// Backend creates the metrics of a monitoring system
+type Backend interface {
+	Counter(name, help string, labelNames []string) Counter
+	Gauge(name, help string, labelNames []string) Gauge
+	Histogram(name, help string, buckets []float64, labelNames []string) Histogram
+}
+
+var (
+	mu      sync.RWMutex
+	backend Backend = noop{}
+)
+
+// Use sets the backend metrics are recorded to
+func Use(b Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+	backend = b
+}
+
+func current() Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+	return backend
+}
+
+// binding resolves a metric from the current backend, again when the backend changes
+type binding[T any] struct {
+	mu      sync.Mutex
+	backend Backend
+	metric  T
+	create  func(Backend) T
+}
+
+func (b *binding[T]) get() T {
+	be := current()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.backend != be {
+		b.backend, b.metric = be, b.create(be)
+	}
+	return b.metric
+}
+
+// CounterVec is a declared counter
+type CounterVec struct{ binding[Counter] }
+
+// NewCounter declares a counter
+func NewCounter(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{}
+	c.create = func(b Backend) Counter { return b.Counter(name, help, labelNames) }
+	return c
+}
+
+// Inc adds 1 to the counter
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.get().Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the counter
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.get().Add(delta, labelValues...)
+}
+
+// GaugeVec is a declared gauge
+type GaugeVec struct{ binding[Gauge] }
+
+// NewGauge declares a gauge
+func NewGauge(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{}
+	g.create = func(b Backend) Gauge { return b.Gauge(name, help, labelNames) }
+	return g
+}
+
+// Set sets the gauge to value
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.get().Set(value, labelValues...)
+}
+
+// Add adds delta, which may be negative, to the gauge
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.get().Add(delta, labelValues...)
+}
+
+// HistogramVec is a declared histogram
+type HistogramVec struct{ binding[Histogram] }
+
+// NewHistogram declares a histogram, using DefaultBuckets when buckets is nil
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{}
+	h.create = func(b Backend) Histogram { return b.Histogram(name, help, buckets, labelNames) }
+	return h
+}
+
+// Observe records value in the histogram
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.get().Observe(value, labelValues...)
+}
+
+// Noop returns a backend discarding every metric
+func Noop() Backend {
+	return noop{}
+}
+
+type noop struct{}
+
+func (noop) Counter(string, string, []string) Counter                { return noop{} }
+func (noop) Gauge(string, string, []string) Gauge                    { return noop{} }
+func (noop) Histogram(string, string, []float64, []string) Histogram { return noop{} }
+func (noop) Add(float64, ...string)                                  {}
+func (noop) Set(float64, ...string)                                  {}
+func (noop) Observe(float64, ...string)                              {}
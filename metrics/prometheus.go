@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prometheus is a Backend keeping metrics in memory and serving them in the
+// Prometheus text exposition format
+type Prometheus struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewPrometheus returns an empty Prometheus backend, mount it as an HTTP handler to be scraped
+func NewPrometheus() *Prometheus {
+	return &Prometheus{families: map[string]*family{}}
+}
+
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// histogram state, counts[i] is the number of observations in bucket i
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (p *Prometheus) family(name, help, kind string, buckets []float64, labelNames []string) *family {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if f, ok := p.families[name]; ok {
+		return f
+	}
+	f := &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     map[string]*series{},
+	}
+	p.families[name] = f
+	return f
+}
+
+func (p *Prometheus) Counter(name, help string, labelNames []string) Counter {
+	return p.family(name, help, "counter", nil, labelNames)
+}
+
+func (p *Prometheus) Gauge(name, help string, labelNames []string) Gauge {
+	return p.family(name, help, "gauge", nil, labelNames)
+}
+
+func (p *Prometheus) Histogram(name, help string, buckets []float64, labelNames []string) Histogram {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return p.family(name, help, "histogram", sorted, labelNames)
+}
+
+// with returns the series of the label values, padded or cut to the declared label names
+func (f *family) with(labelValues []string) *series {
+	values := make([]string, len(f.labelNames))
+	copy(values, labelValues)
+	key := strings.Join(values, "\xff")
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: values}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) Add(delta float64, labelValues ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.with(labelValues).value += delta
+}
+
+func (f *family) Set(value float64, labelValues ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.with(labelValues).value = value
+}
+
+func (f *family) Observe(value float64, labelValues ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.with(labelValues)
+	for i, upper := range f.buckets {
+		if value <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// ServeHTTP writes every metric in the Prometheus text format
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes every metric in the Prometheus text format to w
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	families := make([]*family, 0, len(p.families))
+	for _, f := range p.families {
+		families = append(families, f)
+	}
+	p.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	var b strings.Builder
+	for _, f := range families {
+		f.write(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (f *family) write(b *strings.Builder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, f.labels(s.labelValues, "", ""), formatFloat(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labels(s.labelValues, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labels(s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, f.labels(s.labelValues, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, f.labels(s.labelValues, "", ""), s.count)
+	}
+}
+
+// labels formats the label set of a series, with an extra label such as le when extraName is set
+func (f *family) labels(values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range f.labelNames {
+		pairs = append(pairs, name+"="+strconv.Quote(values[i]))
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+"="+strconv.Quote(extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
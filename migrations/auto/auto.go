@@ -0,0 +1,590 @@
+// Package auto generates migrations by comparing declared tables, as Go
+// structs or blueprint callbacks, against the live database:
+//
+//	diff, err := auto.CompareModel(ctx, db, "postgres", "users", User{})
+//	if err != nil {
+//		return err
+//	}
+//	if !diff.Empty() {
+//		err = diff.WriteMigration(os.Stdout, "database/migrations", time.Now())
+//	}
+//
+// Columns are compared by type and nullability and indexes by their columns,
+// so renames show up as a drop and an add. Defaults, comments and foreign keys
+// are not compared. Dropping is opt-in with WithDrops, as the live schema
+// usually has columns the declaration leaves out on purpose.
+package auto
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-bold/bold/console"
+	"github.com/go-bold/bold/migrations"
+)
+
+// Diff is the changes that reconcile a live table with its declaration
+type Diff struct {
+	Table  string
+	Driver string
+	// Create is set when the table does not exist, the other fields are then empty
+	Create        bool
+	AddColumns    []migrations.Column
+	ChangeColumns []migrations.Column
+	DropColumns   []string
+	AddIndexes    []migrations.IndexInfo
+	DropIndexes   []string
+
+	declared func(migrations.Blueprint)
+}
+
+// Option configures a comparison
+type Option func(*options)
+
+type options struct {
+	drops bool
+}
+
+// WithDrops also drops the columns and indexes the declaration does not have
+func WithDrops() Option {
+	return func(o *options) {
+		o.drops = true
+	}
+}
+
+// CompareModel compares table against the columns declared by model, see Model
+func CompareModel(ctx context.Context, db *sql.DB, driver, table string, model any, opts ...Option) (*Diff, error) {
+	return Compare(ctx, db, driver, table, Model(model), opts...)
+}
+
+// Compare returns the changes that bring table in db, opened with the named
+// driver, to the schema declared by callback
+func Compare(ctx context.Context, db *sql.DB, driver, table string, callback func(migrations.Blueprint), opts ...Option) (*Diff, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	provider, err := migrations.Dialect(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{Table: table, Driver: driver, declared: callback}
+	exists, err := provider.HasTableContext(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		diff.Create = true
+		return diff, nil
+	}
+
+	liveColumns, err := provider.ColumnsContext(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	liveIndexes, err := provider.IndexesContext(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	columns, indexes := migrations.Describe(provider, table, callback)
+	postgres := provider == migrations.PostgreSQL
+
+	live := map[string]migrations.ColumnInfo{}
+	for _, column := range liveColumns {
+		live[column.Name] = column
+	}
+	declared := map[string]bool{}
+	added := map[string]bool{}
+	for _, column := range columns {
+		declared[column.Name] = true
+		existing, ok := live[column.Name]
+		if !ok {
+			diff.AddColumns = append(diff.AddColumns, column)
+			added[column.Name] = true
+			continue
+		}
+		primary := column.Primary || strings.Contains(strings.ToUpper(column.Type), "PRIMARY KEY")
+		if normalizeType(declaredType(column, postgres), postgres) != normalizeType(existing.Type, postgres) ||
+			(!primary && column.Nullable != existing.Nullable) {
+			// keys already exist, redeclaring them would add them again
+			column.Unique, column.Primary = false, false
+			column.Type = primaryKey.ReplaceAllString(column.Type, "")
+			diff.ChangeColumns = append(diff.ChangeColumns, column)
+		}
+	}
+	if o.drops {
+		for _, column := range liveColumns {
+			if !declared[column.Name] {
+				diff.DropColumns = append(diff.DropColumns, column.Name)
+			}
+		}
+	}
+
+	// unique and primary keys declared on existing columns become indexes,
+	// added columns declare them inline
+	for _, column := range columns {
+		if added[column.Name] {
+			continue
+		}
+		if column.Unique {
+			indexes = append(indexes, migrations.IndexInfo{Columns: []string{column.Name}, Unique: true})
+		}
+		if column.Primary || strings.Contains(strings.ToUpper(column.Type), "PRIMARY KEY") {
+			indexes = append(indexes, migrations.IndexInfo{Columns: []string{column.Name}, Unique: true, Primary: true})
+		}
+	}
+	wanted := map[string]bool{}
+	for _, index := range indexes {
+		if len(index.Columns) == 0 {
+			continue
+		}
+		key := indexKey(index)
+		wanted[key] = true
+		if !slices.ContainsFunc(liveIndexes, func(live migrations.IndexInfo) bool { return indexKey(live) == key }) {
+			diff.AddIndexes = append(diff.AddIndexes, index)
+		}
+	}
+	if o.drops {
+		for _, index := range liveIndexes {
+			if len(index.Columns) > 0 && !index.Primary && !wanted[indexKey(index)] {
+				diff.DropIndexes = append(diff.DropIndexes, index.Name)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// Empty reports whether the table already matches its declaration
+func (d *Diff) Empty() bool {
+	return !d.Create && len(d.AddColumns) == 0 && len(d.ChangeColumns) == 0 && len(d.DropColumns) == 0 &&
+		len(d.AddIndexes) == 0 && len(d.DropIndexes) == 0
+}
+
+// Blueprint returns the callback applying the changes, for Schema.Create when
+// Create is set and Schema.Table otherwise
+func (d *Diff) Blueprint() func(migrations.Blueprint) {
+	if d.Create {
+		return d.declared
+	}
+	return func(table migrations.Blueprint) {
+		for _, column := range d.AddColumns {
+			declareColumn(table, column)
+		}
+		for _, column := range d.ChangeColumns {
+			declareColumn(table, column).Change()
+		}
+		for _, name := range d.DropColumns {
+			table.DropColumn(name)
+		}
+		for _, index := range d.AddIndexes {
+			declareIndex(table, index)
+		}
+		for _, name := range d.DropIndexes {
+			table.DropIndex(name)
+		}
+	}
+}
+
+func declareColumn(table migrations.Blueprint, column migrations.Column) migrations.ColumnBuilder {
+	b := table.AddColumn(column.Name, column.Type)
+	if column.Nullable {
+		b.Nullable()
+	}
+	if column.Default != nil {
+		b.Default(column.Default)
+	}
+	if column.Unique {
+		b.Unique()
+	}
+	if column.Primary {
+		b.Primary()
+	}
+	if column.Comment != "" {
+		b.Comment(column.Comment)
+	}
+	if column.Unsigned {
+		b.Unsigned()
+	}
+	if column.AutoIncrement {
+		b.AutoIncrement()
+	}
+	if column.Charset != "" {
+		b.Charset(column.Charset)
+	}
+	if column.Collation != "" {
+		b.Collation(column.Collation)
+	}
+	if column.Generated != "" && column.Stored {
+		b.StoredAs(column.Generated)
+	} else if column.Generated != "" {
+		b.VirtualAs(column.Generated)
+	}
+	if column.After != "" {
+		b.After(column.After)
+	}
+	return b
+}
+
+func declareIndex(table migrations.Blueprint, index migrations.IndexInfo) {
+	switch {
+	case index.Primary:
+		table.Primary(index.Columns...)
+	case index.Algorithm == "FULLTEXT":
+		table.FullTextIndex(index.Columns...)
+	case index.Algorithm == "SPATIAL":
+		if mysql, ok := table.(migrations.MySQLBlueprint); ok {
+			mysql.SpatialIndex(index.Columns...)
+		}
+	case index.Where != "":
+		if partial, ok := table.(interface{ PartialIndex([]string, string) }); ok {
+			partial.PartialIndex(index.Columns, index.Where)
+		}
+	case index.Unique:
+		usingAlgorithm(table.UniqueIndex(index.Columns...), index.Algorithm)
+	default:
+		usingAlgorithm(table.Index(index.Columns...), index.Algorithm)
+	}
+}
+
+func usingAlgorithm(b migrations.IndexBuilder, algorithm string) {
+	if algorithm != "" && algorithm != migrations.BTree {
+		b.Using(algorithm)
+	}
+}
+
+// SQL returns the statements that apply the changes, none when the diff is empty
+func (d *Diff) SQL() ([]string, error) {
+	if d.Empty() {
+		return nil, nil
+	}
+	provider, err := migrations.Dialect(d.Driver)
+	if err != nil {
+		return nil, err
+	}
+	schema := migrations.NewSchema(nil, provider)
+	if d.Create {
+		return schema.CreateSQL(d.Table, d.declared)
+	}
+	return schema.TableSQL(d.Table, d.Blueprint())
+}
+
+// Apply executes the changes against db
+func (d *Diff) Apply(ctx context.Context, db migrations.DB) error {
+	if d.Empty() {
+		return nil
+	}
+	schema := migrations.New(db, d.Driver)
+	if d.Create {
+		return schema.CreateContext(ctx, d.Table, d.declared)
+	}
+	return schema.TableContext(ctx, d.Table, d.Blueprint())
+}
+
+// WriteMigration writes the changes as a migration file in dir, like the ones
+// make:migration generates, named create_<table>_table or update_<table>_table.
+// A created table is written with its columns, indexes, foreign keys, checks
+// and comment; declarations partitioning the table or setting options of the
+// dialect, such as Engine or Unlogged, are refused rather than written in part.
+func (d *Diff) WriteMigration(out io.Writer, dir string, now time.Time) error {
+	provider, err := migrations.Dialect(d.Driver)
+	if err != nil {
+		return err
+	}
+	action := "update"
+	if d.Create {
+		action = "create"
+	}
+	name := fmt.Sprintf("%s_%s_%s_table", now.Format("2006_01_02_150405"), action, d.Table)
+
+	data := migrationData{
+		Package: filepath.Base(dir),
+		Name:    name,
+		Driver:  d.Driver,
+		Table:   d.Table,
+		Create:  d.Create,
+	}
+	declared := migrations.Declaration{Columns: d.AddColumns, Indexes: d.AddIndexes}
+	if d.Create {
+		declared = migrations.DescribeTable(provider, d.Table, d.declared)
+		if len(declared.Options) > 0 {
+			return fmt.Errorf("auto: %s declares %s, which WriteMigration cannot write", d.Table, strings.Join(declared.Options, ", "))
+		}
+	}
+
+	checks := map[string][]string{}
+	for _, check := range declared.Checks {
+		checks[check.Column] = append(checks[check.Column], check.Expression)
+	}
+	for _, column := range declared.Columns {
+		data.Lines = append(data.Lines, columnSource(column, checks[column.Name], false))
+	}
+	for _, column := range d.ChangeColumns {
+		data.Lines = append(data.Lines, columnSource(column, nil, true))
+	}
+	for _, name := range d.DropColumns {
+		data.Lines = append(data.Lines, fmt.Sprintf("table.DropColumn(%q)", name))
+	}
+	for _, index := range declared.Indexes {
+		line, err := indexSource(index, dialectBlueprint(provider))
+		if err != nil {
+			return fmt.Errorf("auto: %s: %w", d.Table, err)
+		}
+		data.Lines = append(data.Lines, line)
+	}
+	for _, fk := range declared.ForeignKeys {
+		data.Lines = append(data.Lines, foreignKeySource(fk))
+	}
+	for _, expression := range checks[""] {
+		data.Lines = append(data.Lines, fmt.Sprintf("table.Check(%q)", expression))
+	}
+	if declared.Comment != "" {
+		data.Lines = append(data.Lines, fmt.Sprintf("table.Comment(%q)", declared.Comment))
+	}
+	for _, name := range d.DropIndexes {
+		data.Lines = append(data.Lines, fmt.Sprintf("table.DropIndex(%q)", name))
+	}
+	return console.Generate(out, filepath.Join(dir, name+".go"), migrationStub, data)
+}
+
+func columnSource(column migrations.Column, checks []string, change bool) string {
+	line := fmt.Sprintf("table.AddColumn(%q, %q)", column.Name, column.Type)
+	if column.Nullable {
+		line += ".Nullable()"
+	}
+	if column.Default != nil {
+		line += fmt.Sprintf(".Default(%#v)", column.Default)
+	}
+	if column.Unique {
+		line += ".Unique()"
+	}
+	if column.Primary {
+		line += ".Primary()"
+	}
+	if column.Comment != "" {
+		line += fmt.Sprintf(".Comment(%q)", column.Comment)
+	}
+	if column.After != "" {
+		line += fmt.Sprintf(".After(%q)", column.After)
+	}
+	if column.Unsigned {
+		line += ".Unsigned()"
+	}
+	if column.AutoIncrement {
+		line += ".AutoIncrement()"
+	}
+	if column.Charset != "" {
+		line += fmt.Sprintf(".Charset(%q)", column.Charset)
+	}
+	if column.Collation != "" {
+		line += fmt.Sprintf(".Collation(%q)", column.Collation)
+	}
+	if column.Generated != "" && column.Stored {
+		line += fmt.Sprintf(".StoredAs(%q)", column.Generated)
+	} else if column.Generated != "" {
+		line += fmt.Sprintf(".VirtualAs(%q)", column.Generated)
+	}
+	for _, expression := range checks {
+		line += fmt.Sprintf(".Check(%q)", expression)
+	}
+	if change {
+		line += ".Change()"
+	}
+	return line
+}
+
+// indexSource returns the blueprint call adding index, reaching the dialect
+// blueprint named dialect for the indexes only it declares
+func indexSource(index migrations.IndexInfo, dialect string) (string, error) {
+	columns := make([]string, len(index.Columns))
+	for i, column := range index.Columns {
+		columns[i] = strconv.Quote(column)
+	}
+	list := strings.Join(columns, ", ")
+
+	switch {
+	case index.Expression != "":
+		if dialect != "PostgreSQLBlueprint" {
+			return "", fmt.Errorf("expression index %s is PostgreSQL only", index.Name)
+		}
+		return fmt.Sprintf("table.(migrations.PostgreSQLBlueprint).IndexExpression(%q)", index.Expression), nil
+	case len(index.Columns) == 0:
+		return "", fmt.Errorf("index %s has no columns", index.Name)
+	case index.Where != "":
+		if dialect != "PostgreSQLBlueprint" && dialect != "MSSQLBlueprint" {
+			return "", fmt.Errorf("partial index %s is PostgreSQL and SQL Server only", index.Name)
+		}
+		return fmt.Sprintf("table.(migrations.%s).PartialIndex([]string{%s}, %q)", dialect, list, index.Where), nil
+	case index.Primary:
+		return fmt.Sprintf("table.Primary(%s)", list), nil
+	case index.Algorithm == "FULLTEXT":
+		return fmt.Sprintf("table.FullTextIndex(%s)", list), nil
+	case index.Algorithm == "SPATIAL":
+		if dialect != "MySQLBlueprint" {
+			return "", fmt.Errorf("spatial index %s is MySQL only", index.Name)
+		}
+		return fmt.Sprintf("table.(migrations.MySQLBlueprint).SpatialIndex(%s)", list), nil
+	}
+
+	line := fmt.Sprintf("table.Index(%s)", list)
+	suffix := "_index"
+	if index.Unique {
+		line, suffix = fmt.Sprintf("table.UniqueIndex(%s)", list), "_unique"
+	}
+	if index.Algorithm != "" && index.Algorithm != migrations.BTree {
+		line += fmt.Sprintf(".Using(%q)", index.Algorithm)
+	}
+	if index.Name != "" && index.Name != strings.Join(index.Columns, "_")+suffix {
+		line += fmt.Sprintf(".Name(%q)", index.Name)
+	}
+	return line, nil
+}
+
+func foreignKeySource(fk migrations.ForeignKeyInfo) string {
+	line := fmt.Sprintf("table.Foreign(%q).References(%q).On(%q)", fk.Column, fk.ForeignColumn, fk.ForeignTable)
+	if fk.OnDelete != "" {
+		line += fmt.Sprintf(".OnDelete(%q)", fk.OnDelete)
+	}
+	if fk.OnUpdate != "" {
+		line += fmt.Sprintf(".OnUpdate(%q)", fk.OnUpdate)
+	}
+	return line
+}
+
+// dialectBlueprint returns the name of the blueprint interface of provider
+func dialectBlueprint(provider migrations.Provider) string {
+	switch provider {
+	case migrations.PostgreSQL, migrations.CockroachDB:
+		return "PostgreSQLBlueprint"
+	case migrations.SQLServer:
+		return "MSSQLBlueprint"
+	}
+	return "MySQLBlueprint"
+}
+
+type migrationData struct {
+	Package string
+	Name    string
+	Driver  string
+	Table   string
+	Create  bool
+	Lines   []string
+}
+
+var migrationStub = template.Must(template.New("migration").Parse(`package {{.Package}}
+
+import "github.com/go-bold/bold/migrations"
+
+func init() {
+	blueprint := func(table migrations.Blueprint) {
+{{- range .Lines}}
+		{{.}}
+{{- end}}
+	}
+
+	migrations.Register("{{.Name}}", func(db migrations.DB) error {
+{{- if .Create}}
+		return migrations.New(db, "{{.Driver}}").Create("{{.Table}}", blueprint)
+	}, func(db migrations.DB) error {
+		return migrations.New(db, "{{.Driver}}").DropIfExists("{{.Table}}")
+{{- else}}
+		return migrations.New(db, "{{.Driver}}").Table("{{.Table}}", blueprint)
+	}, func(db migrations.DB) error {
+		return migrations.New(db, "{{.Driver}}").Rollback("{{.Table}}", blueprint)
+{{- end}}
+	})
+}
+`))
+
+// indexKey identifies an index by what it indexes rather than by its name
+func indexKey(index migrations.IndexInfo) string {
+	kind := "index"
+	switch {
+	case index.Primary:
+		kind = "primary"
+	case index.Unique:
+		kind = "unique"
+	case index.Algorithm == "FULLTEXT" || index.Algorithm == "SPATIAL":
+		kind = strings.ToLower(index.Algorithm)
+	}
+	return kind + ":" + strings.Join(index.Columns, ",")
+}
+
+// declaredType returns the type a declared column has once created, with
+// the modifiers that follow the type in the column definition removed
+func declaredType(column migrations.Column, postgres bool) string {
+	t := column.Type
+	if column.Unsigned && !postgres {
+		t += " UNSIGNED"
+	}
+	return t
+}
+
+var (
+	primaryKey    = regexp.MustCompile(`(?i)\s+primary key`)
+	typeModifiers = regexp.MustCompile(`(?i)\s+(auto_increment|primary key|default\s.*|generated\s.*|not null|null|unique|on update\s.*)$`)
+	intWidth      = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+	// typeAliases maps spellings to the normalized type, longest spelling first
+	// so "character varying" is not taken for "character"
+	typeAliases = [][2]string{
+		{"timestamp without time zone", "timestamp"},
+		{"timestamp with time zone", "timestamptz"},
+		{"time without time zone", "time"},
+		{"time with time zone", "timetz"},
+		{"character varying", "varchar"},
+		{"double precision", "double"},
+		{"smallserial", "smallint"},
+		{"tinyint(1)", "boolean"},
+		{"bigserial", "bigint"},
+		{"character", "char"},
+		{"integer", "int"},
+		{"numeric", "decimal"},
+		{"serial", "int"},
+		{"float8", "double"},
+		{"float4", "real"},
+		{"int4", "int"},
+		{"int8", "bigint"},
+		{"int2", "smallint"},
+		{"bool", "boolean"},
+	}
+)
+
+// normalizeType reduces the spellings of a column type to one, so declared
+// types compare equal to the types the database reports
+func normalizeType(t string, postgres bool) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	for {
+		trimmed := typeModifiers.ReplaceAllString(t, "")
+		if trimmed == t {
+			break
+		}
+		t = trimmed
+	}
+	if t != "tinyint(1)" {
+		t = intWidth.ReplaceAllString(t, "$1")
+	}
+
+	for _, alias := range typeAliases {
+		if t == alias[0] || strings.HasPrefix(t, alias[0]+"(") || strings.HasPrefix(t, alias[0]+" ") {
+			t = alias[1] + t[len(alias[0]):]
+			break
+		}
+	}
+	// PostgreSQL's FLOAT is a double precision, MySQL's a single precision
+	if t == "float" {
+		if postgres {
+			return "double"
+		}
+		return "real"
+	}
+	return t
+}
@@ -0,0 +1,206 @@
+package auto
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-bold/bold/console"
+	"github.com/go-bold/bold/migrations"
+)
+
+// Model returns a blueprint callback declaring a column for each exported
+// field of the struct model points to or holds. The column is named by the
+// db tag, or the snake_case field name, and its type follows the Go type:
+// strings are VARCHAR(255), integers INT or BIGINT, time.Time a TIMESTAMP,
+// and maps, slices and other structs JSON. Pointers and sql.Null types are
+// nullable. The migrate tag adjusts a column with semicolon separated options:
+//
+//	type User struct {
+//		ID    int64  `migrate:"primary"`
+//		Email string `db:"email" migrate:"size:100;unique"`
+//		Bio   string `migrate:"type:TEXT;nullable"`
+//		Role  string `migrate:"default:member;index"`
+//		Cache string `migrate:"-"`
+//	}
+//
+// An int64 id primary key becomes an auto incrementing ID(). Embedded structs
+// contribute their fields, so shared columns can live in a common struct.
+func Model(model any) func(migrations.Blueprint) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return func(table migrations.Blueprint) {
+		if t == nil || t.Kind() != reflect.Struct {
+			return
+		}
+		declareFields(table, t)
+	}
+}
+
+func declareFields(table migrations.Blueprint, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("db") == "" {
+			declareFields(table, field.Type)
+			continue
+		}
+		if !field.IsExported() || field.Tag.Get("db") == "-" || field.Tag.Get("migrate") == "-" {
+			continue
+		}
+		declareField(table, field)
+	}
+}
+
+// fieldOptions are the options of a migrate tag
+type fieldOptions struct {
+	columnType string
+	size       int
+	nullable   bool
+	unique     bool
+	index      bool
+	primary    bool
+	defaultSet bool
+	defaultVal string
+}
+
+func parseOptions(tag string) fieldOptions {
+	var opts fieldOptions
+	for _, option := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), ":")
+		switch key {
+		case "type":
+			opts.columnType = value
+		case "size":
+			opts.size, _ = strconv.Atoi(value)
+		case "nullable":
+			opts.nullable = true
+		case "unique":
+			opts.unique = true
+		case "index":
+			opts.index = true
+		case "primary":
+			opts.primary = true
+		case "default":
+			opts.defaultSet, opts.defaultVal = true, value
+		}
+	}
+	return opts
+}
+
+func declareField(table migrations.Blueprint, field reflect.StructField) {
+	name, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+	if name == "" {
+		name = console.SnakeCase(field.Name)
+	}
+	opts := parseOptions(field.Tag.Get("migrate"))
+
+	t, nullable := field.Type, opts.nullable
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	if value, ok := nullValueType(t); ok {
+		t, nullable = value, true
+	}
+
+	if opts.primary && opts.columnType == "" && t.Kind() == reflect.Int64 && name == "id" {
+		table.ID()
+		return
+	}
+
+	var column migrations.ColumnBuilder
+	if opts.columnType != "" {
+		column = table.AddColumn(name, opts.columnType)
+	} else {
+		column = declareGoType(table, name, t, opts.size)
+	}
+	if nullable {
+		column.Nullable()
+	}
+	if opts.defaultSet {
+		column.Default(defaultValue(t, opts.defaultVal))
+	}
+	if opts.primary {
+		column.Primary()
+	}
+	if opts.unique {
+		column.Unique()
+	}
+	if opts.index {
+		column.Index()
+	}
+}
+
+// nullValueType returns the value type of sql.NullString and the like
+func nullValueType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || t.PkgPath() != "database/sql" || !strings.HasPrefix(t.Name(), "Null") {
+		return nil, false
+	}
+	return t.Field(0).Type, true
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	bytesType      = reflect.TypeOf([]byte{})
+)
+
+// declareGoType adds the column for values of t with the typed method of the
+// blueprint, so each dialect picks its own spelling of the type
+func declareGoType(table migrations.Blueprint, name string, t reflect.Type, size int) migrations.ColumnBuilder {
+	switch t {
+	case timeType:
+		return table.Timestamp(name)
+	case rawMessageType:
+		return table.JSON(name)
+	case bytesType:
+		return table.Binary(name)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if size == 0 {
+			size = 255
+		}
+		return table.String(name, size)
+	case reflect.Bool:
+		return table.Boolean(name)
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return table.Integer(name)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return table.Integer(name).Unsigned()
+	case reflect.Int, reflect.Int64:
+		return table.BigInteger(name)
+	case reflect.Uint, reflect.Uint64:
+		return table.BigInteger(name).Unsigned()
+	case reflect.Float32:
+		return table.Float(name)
+	case reflect.Float64:
+		return table.Double(name)
+	}
+	return table.JSON(name)
+}
+
+// defaultValue converts a default from a migrate tag to the Go type of the
+// column, the provider quoting it as its dialect requires
+func defaultValue(t reflect.Type, value string) any {
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
@@ -0,0 +1,342 @@
+// Package cli provides the migration commands of an application binary.
+//
+// Migrations are compiled into the application, so the commands run from its
+// own main function, usually behind a "migrate" subcommand:
+//
+//	c := &cli.CLI{DB: db, Driver: "postgres", Dir: "database/migrations"}
+//	if err := c.Run(ctx, os.Args[2:]); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// The supported commands are make:migration NAME, migrate [--lint],
+// migrate:rollback [--step N | --batch N] [--force], migrate:reset [--force],
+// migrate:status, migrate:plan [--lint], db:seed [--class NAME] [--force] and
+// db:wipe [--force]. With --lint, risky statements are reported before
+// migrating or listed in the plan. migrate:rollback reverts the last batch, the
+// last N migrations with --step or batch N with --batch. In production, db:seed
+// and db:wipe ask for confirmation unless forced, and migrate:rollback and
+// migrate:reset refuse to run. Generated files register themselves with migrations.Register, so the
+// package holding them must be imported by main.
+// Commands returns the same commands for a console.Console.
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/go-bold/bold/config"
+	"github.com/go-bold/bold/console"
+	"github.com/go-bold/bold/migrations"
+)
+
+// CLI runs migration commands against DB
+type CLI struct {
+	DB *sql.DB
+	// Driver is the database/sql driver name DB was opened with
+	Driver string
+	// Dir is where make:migration writes files, "migrations" by default
+	Dir string
+	// Package is the package name of generated files, the base name of Dir by default
+	Package string
+	// Out receives command output, os.Stdout by default
+	Out io.Writer
+	// In answers confirmation prompts, os.Stdin by default
+	In io.Reader
+}
+
+var migrationName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Run executes the command named by args[0] with the remaining arguments
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: make:migration NAME | migrate [--lint] | migrate:rollback [--step N | --batch N] [--force] | migrate:reset [--force] | migrate:status | migrate:plan [--lint] | db:seed [--class NAME] [--force] | db:wipe [--force]")
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	force := fs.Bool("force", false, "")
+	class := fs.String("class", "", "")
+	lint := fs.Bool("lint", false, "")
+	step := fs.Int("step", 0, "")
+	batch := fs.Int("batch", 0, "")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "make:migration":
+		if fs.NArg() != 1 {
+			return errors.New("usage: make:migration NAME")
+		}
+		return c.makeMigration(fs.Arg(0), time.Now())
+	case "migrate":
+		runner, err := c.runner()
+		if err != nil {
+			return err
+		}
+		if *lint {
+			runner.Lint(func(w migrations.Warning) {
+				fmt.Fprintln(c.out(), "Warning:", w)
+			})
+		}
+		return runner.UpContext(ctx, c.DB)
+	case "migrate:rollback":
+		runner, err := c.runner()
+		if err != nil {
+			return err
+		}
+		if *force {
+			runner.Force()
+		}
+		switch {
+		case *step > 0 && *batch > 0:
+			return errors.New("usage: migrate:rollback [--step N | --batch N] [--force]")
+		case *step > 0:
+			return runner.RollbackStepsContext(ctx, c.DB, *step)
+		case *batch > 0:
+			return runner.RollbackBatchContext(ctx, c.DB, *batch)
+		}
+		return runner.DownContext(ctx, c.DB)
+	case "migrate:reset":
+		runner, err := c.runner()
+		if err != nil {
+			return err
+		}
+		if *force {
+			runner.Force()
+		}
+		return runner.ResetContext(ctx, c.DB)
+	case "migrate:status":
+		return c.status(ctx)
+	case "migrate:plan":
+		runner, err := c.runner()
+		if err != nil {
+			return err
+		}
+		if *lint {
+			runner.Lint(func(migrations.Warning) {})
+		}
+		plan, err := runner.Plan(ctx, c.DB)
+		if err != nil {
+			return err
+		}
+		return plan.WriteJSON(c.out())
+	case "db:seed":
+		if !c.confirm(*force, "seed the database") {
+			return nil
+		}
+		var names []string
+		if *class != "" {
+			names = strings.Split(*class, ",")
+		}
+		if err := migrations.Seed(ctx, c.DB, names...); err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out(), "Seeded the database")
+		return nil
+	case "db:wipe":
+		if !c.confirm(*force, "drop all tables") {
+			return nil
+		}
+		provider, err := migrations.Dialect(c.Driver)
+		if err != nil {
+			return err
+		}
+		if err := provider.DropAllTablesContext(ctx, c.DB); err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out(), "Dropped all tables")
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// Commands returns the migration commands for registration with a console.Console
+func (c *CLI) Commands() []*console.Command {
+	command := func(name, description string, flags func(*flag.FlagSet)) *console.Command {
+		return &console.Command{
+			Name:        name,
+			Description: description,
+			Flags:       flags,
+			Run: func(ctx context.Context, in *console.Input) error {
+				args := []string{name}
+				in.Flags.Visit(func(f *flag.Flag) {
+					args = append(args, "--"+f.Name+"="+f.Value.String())
+				})
+				cli := *c
+				cli.Out, cli.In = in.Out, in.In
+				return cli.Run(ctx, append(args, in.Args...))
+			},
+		}
+	}
+
+	return []*console.Command{
+		command("make:migration", "Create a migration file, e.g. make:migration create_users_table", nil),
+		command("migrate", "Run the pending migrations", func(fs *flag.FlagSet) {
+			fs.Bool("lint", false, "report risky statements before migrating")
+		}),
+		command("migrate:rollback", "Roll back the last batch of migrations", func(fs *flag.FlagSet) {
+			fs.Int("step", 0, "roll back the last N migrations instead")
+			fs.Int("batch", 0, "roll back batch N instead")
+			fs.Bool("force", false, "roll back in production or with no environment set")
+		}),
+		command("migrate:reset", "Roll back every applied migration", func(fs *flag.FlagSet) {
+			fs.Bool("force", false, "roll back in production or with no environment set")
+		}),
+		command("migrate:status", "Show the status of each migration", nil),
+		command("migrate:plan", "Print the pending migrations and their SQL as JSON", func(fs *flag.FlagSet) {
+			fs.Bool("lint", false, "include the risky statements of each migration")
+		}),
+		command("db:seed", "Run the registered seeders", func(fs *flag.FlagSet) {
+			fs.String("class", "", "comma separated names of the seeders to run, all by default")
+			fs.Bool("force", false, "skip the confirmation in production or with no environment set")
+		}),
+		command("db:wipe", "Drop all tables", func(fs *flag.FlagSet) {
+			fs.Bool("force", false, "skip the confirmation in production or with no environment set")
+		}),
+	}
+}
+
+// confirm asks before a destructive command runs in production or with no
+// environment set, reporting whether to go ahead
+func (c *CLI) confirm(force bool, action string) bool {
+	if force || !config.IsProtected() {
+		return true
+	}
+	in := c.In
+	if in == nil {
+		in = os.Stdin
+	}
+	prompt := &console.Input{Out: c.out(), In: in}
+	if prompt.Confirm(fmt.Sprintf("The environment is production or not set, %s anyway?", action)) {
+		return true
+	}
+	fmt.Fprintln(c.out(), "Cancelled")
+	return false
+}
+
+func (c *CLI) runner() (*migrations.Runner, error) {
+	provider, err := migrations.Dialect(c.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return migrations.NewRunner(provider), nil
+}
+
+func (c *CLI) out() io.Writer {
+	if c.Out == nil {
+		return os.Stdout
+	}
+	return c.Out
+}
+
+func (c *CLI) status(ctx context.Context) error {
+	runner, err := c.runner()
+	if err != nil {
+		return err
+	}
+	statuses, err := runner.Status(ctx, c.DB)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Status\tBatch\tMigration")
+	for _, s := range statuses {
+		state, batch := "Pending", ""
+		if s.Batch > 0 {
+			state, batch = "Ran", fmt.Sprint(s.Batch)
+		}
+		if !s.Registered {
+			state = "Missing"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", state, batch, s.Name)
+	}
+	return w.Flush()
+}
+
+// makeMigration writes a timestamped migration file with Up and Down stubs
+func (c *CLI) makeMigration(name string, now time.Time) error {
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("invalid migration name %q, use snake_case such as create_users_table", name)
+	}
+
+	dir := c.Dir
+	if dir == "" {
+		dir = "migrations"
+	}
+	pkg := c.Package
+	if pkg == "" {
+		pkg = filepath.Base(dir)
+	}
+
+	full := now.Format("2006_01_02_150405") + "_" + name
+	data := stubData{Package: pkg, Name: full, Driver: c.Driver}
+	if m := createTable.FindStringSubmatch(name); m != nil {
+		data.Create, data.Table = true, m[1]
+	} else if m := alterTable.FindStringSubmatch(name); m != nil {
+		data.Table = m[1]
+	}
+
+	return console.Generate(c.out(), filepath.Join(dir, full+".go"), stub, data)
+}
+
+var (
+	createTable = regexp.MustCompile(`^create_([a-z0-9_]+)_table$`)
+	alterTable  = regexp.MustCompile(`_(?:to|from|in)_([a-z0-9_]+)_table$`)
+)
+
+type stubData struct {
+	Package string
+	Name    string
+	Driver  string
+	Table   string
+	Create  bool
+}
+
+var stub = template.Must(template.New("migration").Parse(`package {{.Package}}
+
+import "github.com/go-bold/bold/migrations"
+{{if .Create}}
+func init() {
+	migrations.Register("{{.Name}}", func(db migrations.DB) error {
+		return migrations.New(db, "{{.Driver}}").Create("{{.Table}}", func(table migrations.Blueprint) {
+			table.ID()
+			table.Timestamps()
+		})
+	}, func(db migrations.DB) error {
+		return migrations.New(db, "{{.Driver}}").DropIfExists("{{.Table}}")
+	})
+}
+{{- else if .Table}}
+func init() {
+	blueprint := func(table migrations.Blueprint) {
+	}
+
+	migrations.Register("{{.Name}}", func(db migrations.DB) error {
+		return migrations.New(db, "{{.Driver}}").Table("{{.Table}}", blueprint)
+	}, func(db migrations.DB) error {
+		return migrations.New(db, "{{.Driver}}").Rollback("{{.Table}}", blueprint)
+	})
+}
+{{- else}}
+func init() {
+	migrations.Register("{{.Name}}", func(db migrations.DB) error {
+		return nil
+	}, func(db migrations.DB) error {
+		return nil
+	})
+}
+{{- end}}
+`))
@@ -0,0 +1,31 @@
+package migrations
+
+// cockroachRowID is the type of CockroachDB keys. Sequences, which SERIAL
+// columns use on PostgreSQL, serialize every insert of the cluster on a
+// single range, unique_rowid combines the insert time with the node instead.
+const cockroachRowID = "INT8 DEFAULT unique_rowid()"
+
+func (bp *postgresqlBlueprint) dialect() string {
+	if bp.provider.cockroach {
+		return "CockroachDB"
+	}
+	return "PostgreSQL"
+}
+
+// ifNotExists returns the IF NOT EXISTS of CockroachDB statements, which
+// makes a migration interrupted between schema changes safe to run again,
+// and of the blueprints skipping what exists
+func (bp *postgresqlBlueprint) ifNotExists() string {
+	if bp.provider.cockroach || bp.skipExisting {
+		return " IF NOT EXISTS"
+	}
+	return ""
+}
+
+// ifExists returns the IF EXISTS of CockroachDB statements, see ifNotExists
+func (bp *postgresqlBlueprint) ifExists() string {
+	if bp.provider.cockroach {
+		return " IF EXISTS"
+	}
+	return ""
+}
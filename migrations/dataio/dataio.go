@@ -0,0 +1,333 @@
+// Package dataio loads CSV and JSON lines files into tables, for initial data
+// loads after migrating:
+//
+//	f, err := os.Open("countries.csv")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	n, err := dataio.ImportCSV(ctx, db, "postgres", "countries", f)
+//
+// Values are converted to the types of the live columns, so "42" fills an
+// integer column and "2024-01-31" a date one, and empty values of nullable
+// columns become NULL. Rows are inserted in batches inside one transaction,
+// so a failing row leaves the table as it was.
+package dataio
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-bold/bold/migrations"
+)
+
+// Option configures an import
+type Option func(*options)
+
+type options struct {
+	batchSize int
+	progress  func(rows int)
+}
+
+// WithBatchSize sets how many rows each INSERT adds, 500 by default, lowered
+// to what the parameter limits of the dialect allow
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithProgress calls report with the number of rows imported so far after each batch
+func WithProgress(report func(rows int)) Option {
+	return func(o *options) {
+		o.progress = report
+	}
+}
+
+// ImportCSV inserts the records of r into table, the first record naming the
+// columns, and returns how many rows it inserted
+func ImportCSV(ctx context.Context, db *sql.DB, driver, table string, r io.Reader, opts ...Option) (int, error) {
+	im, err := newImporter(ctx, db, driver, table, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("dataio: reading the header: %w", err)
+	}
+	columns, err := im.lookup(header)
+	if err != nil {
+		return 0, err
+	}
+
+	return im.run(func(add func([]migrations.ColumnInfo, []any) error) error {
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("dataio: %w", err)
+			}
+			values := make([]any, len(record))
+			for i, field := range record {
+				if values[i], err = coerce(field, columns[i]); err != nil {
+					return fmt.Errorf("dataio: line %d, column %s: %w", line, columns[i].Name, err)
+				}
+			}
+			if err := add(columns, values); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// ImportJSONLines inserts the JSON objects of r, one per line, into table
+// and returns how many rows it inserted. Keys name the columns, and columns
+// missing from an object get their default. Nested objects and arrays are
+// stored as JSON.
+func ImportJSONLines(ctx context.Context, db *sql.DB, driver, table string, r io.Reader, opts ...Option) (int, error) {
+	im, err := newImporter(ctx, db, driver, table, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return im.run(func(add func([]migrations.ColumnInfo, []any) error) error {
+		for line := 1; ; line++ {
+			var object map[string]any
+			err := decoder.Decode(&object)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("dataio: line %d: %w", line, err)
+			}
+
+			// keep the columns in table order, so objects with the same keys share a batch
+			var columns []migrations.ColumnInfo
+			var values []any
+			for _, column := range im.columns {
+				value, ok := object[column.Name]
+				if !ok {
+					continue
+				}
+				delete(object, column.Name)
+				converted, err := coerceJSON(value, column)
+				if err != nil {
+					return fmt.Errorf("dataio: line %d, column %s: %w", line, column.Name, err)
+				}
+				columns = append(columns, column)
+				values = append(values, converted)
+			}
+			for key := range object {
+				return fmt.Errorf("dataio: line %d: %s has no column %s", line, table, key)
+			}
+			if err := add(columns, values); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+type importer struct {
+	ctx      context.Context
+	db       *sql.DB
+	provider migrations.Provider
+	table    string
+	columns  []migrations.ColumnInfo
+	options  options
+}
+
+func newImporter(ctx context.Context, db *sql.DB, driver, table string, opts []Option) (*importer, error) {
+	provider, err := migrations.Dialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := provider.ColumnsContext(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("dataio: table %s does not exist", table)
+	}
+
+	im := &importer{ctx: ctx, db: db, provider: provider, table: table, columns: columns, options: options{batchSize: 500}}
+	for _, opt := range opts {
+		opt(&im.options)
+	}
+	return im, nil
+}
+
+// lookup returns the columns named by a CSV header
+func (im *importer) lookup(names []string) ([]migrations.ColumnInfo, error) {
+	columns := make([]migrations.ColumnInfo, len(names))
+	for i, name := range names {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		index := slices.IndexFunc(im.columns, func(column migrations.ColumnInfo) bool {
+			return column.Name == name
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("dataio: %s has no column %s", im.table, name)
+		}
+		columns[i] = im.columns[index]
+	}
+	return columns, nil
+}
+
+// run inserts the rows read passes to add in a transaction, batching
+// consecutive rows for the same columns
+func (im *importer) run(read func(add func([]migrations.ColumnInfo, []any) error) error) (int, error) {
+	tx, err := im.db.BeginTx(im.ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var names []string
+	var batch []any
+	inserted := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		rows := len(batch) / len(names)
+		if _, err := tx.ExecContext(im.ctx, migrations.InsertSQL(im.provider, im.table, names, rows), batch...); err != nil {
+			return fmt.Errorf("dataio: inserting rows %d to %d: %w", inserted+1, inserted+rows, err)
+		}
+		inserted += rows
+		batch = batch[:0]
+		if im.options.progress != nil {
+			im.options.progress(inserted)
+		}
+		return nil
+	}
+
+	err = read(func(columns []migrations.ColumnInfo, values []any) error {
+		if len(columns) == 0 {
+			return errors.New("dataio: a row names no columns")
+		}
+		if !sameColumns(names, columns) {
+			if err := flush(); err != nil {
+				return err
+			}
+			names = names[:0]
+			for _, column := range columns {
+				names = append(names, column.Name)
+			}
+		}
+		batch = append(batch, values...)
+		if rows := len(batch) / len(names); rows >= min(im.options.batchSize, migrations.InsertRows(im.provider, len(names))) {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+func sameColumns(names []string, columns []migrations.ColumnInfo) bool {
+	if len(names) != len(columns) {
+		return false
+	}
+	for i, column := range columns {
+		if names[i] != column.Name {
+			return false
+		}
+	}
+	return true
+}
+
+var integerType = regexp.MustCompile(`^((tiny|small|medium|big)?int(eger)?|(small|big)?serial)\b`)
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02", "15:04:05"}
+
+// coerce converts a text value to the type of column
+func coerce(value string, column migrations.ColumnInfo) (any, error) {
+	if value == "" && column.Nullable {
+		return nil, nil
+	}
+
+	switch columnType := strings.ToLower(column.Type); {
+	case columnType == "tinyint(1)" || strings.HasPrefix(columnType, "bool"):
+		switch strings.ToLower(value) {
+		case "1", "t", "true", "y", "yes":
+			return true, nil
+		case "0", "f", "false", "n", "no":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", value)
+	case integerType.MatchString(columnType):
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return n, nil
+	case strings.HasPrefix(columnType, "float") || strings.HasPrefix(columnType, "double") || strings.HasPrefix(columnType, "real"):
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return f, nil
+	case strings.HasPrefix(columnType, "decimal") || strings.HasPrefix(columnType, "numeric"):
+		// kept as text, floats would lose precision
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return value, nil
+	case strings.HasPrefix(columnType, "date") || strings.HasPrefix(columnType, "time"):
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a date or time", value)
+	case strings.HasPrefix(columnType, "json"):
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("%q is not JSON", value)
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+// coerceJSON converts a decoded JSON value to the type of column
+func coerceJSON(value any, column migrations.ColumnInfo) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return coerce(v, column)
+	case json.Number:
+		return coerce(v.String(), column)
+	case bool:
+		return coerce(strconv.FormatBool(v), column)
+	}
+	// objects and arrays
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
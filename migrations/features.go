@@ -0,0 +1,334 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Feature is a capability that only some dialects have, see Provider.Supports
+type Feature string
+
+const (
+	FeatureFullText Feature = "fulltext"
+	FeatureJSON     Feature = "json"
+	// FeaturePartialIndex is indexing only the rows matching a condition
+	FeaturePartialIndex Feature = "partial_index"
+	// FeatureExpressionIndex is indexing an expression instead of columns
+	FeatureExpressionIndex Feature = "expression_index"
+	// FeatureConcurrentIndex is building an index without blocking writes
+	FeatureConcurrentIndex Feature = "concurrent_index"
+	FeatureSpatialIndex    Feature = "spatial_index"
+	// FeatureTransactionalDDL is rolling back schema changes with the transaction
+	FeatureTransactionalDDL Feature = "transactional_ddl"
+	// FeatureColumnCharset is setting the character set of a single column
+	FeatureColumnCharset Feature = "column_charset"
+	// FeatureReturning is INSERT, UPDATE or DELETE ... RETURNING
+	FeatureReturning Feature = "returning"
+	// FeatureSequence is CREATE SEQUENCE
+	FeatureSequence Feature = "sequence"
+	// FeatureCheckConstraint is enforcing CHECK constraints, MySQL does since 8.0.16
+	FeatureCheckConstraint Feature = "check_constraint"
+	// FeatureMaterializedView is CreateMaterializedView on the PostgreSQL provider
+	FeatureMaterializedView Feature = "materialized_view"
+	// FeatureUnloggedTable is Unlogged on the PostgreSQL blueprint
+	FeatureUnloggedTable Feature = "unlogged_table"
+)
+
+var mysqlFeatures = map[Feature]bool{
+	FeatureFullText:        true,
+	FeatureJSON:            true,
+	FeatureSpatialIndex:    true,
+	FeatureColumnCharset:   true,
+	FeatureCheckConstraint: true,
+}
+
+// mariadbFeatures leaves out FeatureJSON, as MariaDB's JSON is a LONGTEXT
+// validated by a check constraint rather than a binary JSON type
+var mariadbFeatures = map[Feature]bool{
+	FeatureFullText:        true,
+	FeatureSpatialIndex:    true,
+	FeatureColumnCharset:   true,
+	FeatureCheckConstraint: true,
+	FeatureReturning:       true,
+	FeatureSequence:        true,
+}
+
+// tidbFeatures leaves out the full-text and spatial indexes TiDB does not build
+var tidbFeatures = map[Feature]bool{
+	FeatureJSON:            true,
+	FeatureColumnCharset:   true,
+	FeatureCheckConstraint: true,
+}
+
+var postgresqlFeatures = map[Feature]bool{
+	FeatureFullText:         true,
+	FeatureJSON:             true,
+	FeaturePartialIndex:     true,
+	FeatureExpressionIndex:  true,
+	FeatureConcurrentIndex:  true,
+	FeatureTransactionalDDL: true,
+	FeatureReturning:        true,
+	FeatureSequence:         true,
+	FeatureCheckConstraint:  true,
+	FeatureMaterializedView: true,
+	FeatureUnloggedTable:    true,
+}
+
+// cockroachFeatures leaves out FeatureTransactionalDDL, as CockroachDB
+// applies schema changes after the transaction commits, and the tables
+// Unlogged creates, which CockroachDB does not have
+var cockroachFeatures = map[Feature]bool{
+	FeatureFullText:         true,
+	FeatureJSON:             true,
+	FeaturePartialIndex:     true,
+	FeatureExpressionIndex:  true,
+	FeatureConcurrentIndex:  true,
+	FeatureReturning:        true,
+	FeatureSequence:         true,
+	FeatureCheckConstraint:  true,
+	FeatureMaterializedView: true,
+}
+
+var sqlserverFeatures = map[Feature]bool{
+	FeaturePartialIndex:     true,
+	FeatureTransactionalDDL: true,
+	FeatureSequence:         true,
+	FeatureCheckConstraint:  true,
+}
+
+// Supports reports whether MySQL, or the compatible system the provider
+// targets, has feature, for migrations shared between dialects
+func (m *mysqlProvider) Supports(feature Feature) bool {
+	switch {
+	case m.mariadb:
+		return mariadbFeatures[feature]
+	case m.tidb:
+		return tidbFeatures[feature]
+	}
+	return mysqlFeatures[feature]
+}
+
+// Supports reports whether PostgreSQL, or CockroachDB for that provider, has
+// feature, for migrations shared between dialects
+func (p *postgresqlProvider) Supports(feature Feature) bool {
+	if p.cockroach {
+		return cockroachFeatures[feature]
+	}
+	return postgresqlFeatures[feature]
+}
+
+// Supports reports whether SQL Server has feature, for migrations shared between dialects
+func (s *sqlserverProvider) Supports(feature Feature) bool {
+	return sqlserverFeatures[feature]
+}
+
+// ErrUnsupported is wrapped by the errors returned for blueprints using what
+// the dialect does not support, instead of executing invalid SQL
+var ErrUnsupported = errors.New("not supported")
+
+func unsupported(dialect, table, what string) error {
+	return fmt.Errorf("migrations: %s: %s %w by %s", table, what, ErrUnsupported, dialect)
+}
+
+func (bp *mysqlBlueprint) dialect() string {
+	switch {
+	case bp.provider.mariadb:
+		return "MariaDB"
+	case bp.provider.tidb:
+		return "TiDB"
+	case bp.provider.vitess:
+		return "Vitess"
+	}
+	return "MySQL"
+}
+
+// validate rejects index algorithms MySQL does not have, the indexes TiDB
+// does not build, foreign keys on Vitess and AUTO_RANDOM keys outside TiDB
+func (bp *mysqlBlueprint) validate() error {
+	var errs []error
+	for _, index := range bp.indexes {
+		if index.algorithm != "" && index.algorithm != BTree && index.algorithm != Hash {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("index %s using %s is", index.name, index.algorithm)))
+		}
+		if bp.provider.tidb && (index.kind == indexFullText || index.kind == indexSpatial) {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("%s index %s is", strings.ToLower(index.kind), index.name)))
+		}
+	}
+	if bp.provider.vitess {
+		for _, foreign := range bp.foreignKeys() {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("foreign key %s is", foreign.name)))
+		}
+		for _, name := range bp.droppedForeignKeys() {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("dropping foreign key %s is", name)))
+		}
+	}
+	if !bp.provider.tidb {
+		for _, column := range bp.columns {
+			if bp.autoRandom(column) {
+				errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("AUTO_RANDOM key %s is", column.Name)))
+			}
+		}
+	}
+	if bp.partitionMethod() != "" {
+		for _, foreign := range bp.foreignKeys() {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("foreign key %s of a partitioned table is", foreign.name)))
+		}
+	}
+	if bp.temporary {
+		for _, foreign := range bp.foreignKeys() {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("foreign key %s of a temporary table is", foreign.name)))
+		}
+		if bp.partitionMethod() != "" {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, "partitioning a temporary table is"))
+		}
+	}
+	errs = append(errs, bp.validatePartitions())
+	return errors.Join(errs...)
+}
+
+// validateAlter rejects the changes TiDB cannot make to an existing table:
+// primary keys, auto incrementing and stored generated columns, after what
+// validateSkipExisting rejects
+func (bp *mysqlBlueprint) validateAlter() error {
+	if !bp.provider.tidb {
+		return bp.validateSkipExisting()
+	}
+	errs := []error{bp.validateSkipExisting()}
+	for _, column := range bp.columns {
+		switch {
+		case column.Primary || strings.Contains(column.Type, "PRIMARY KEY"):
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding primary key column %s is", column.Name)))
+		case column.AutoIncrement || strings.Contains(column.Type, "AUTO_INCREMENT"):
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding auto incrementing column %s is", column.Name)))
+		case column.Generated != "" && column.Stored && !column.Change:
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding stored generated column %s is", column.Name)))
+		}
+	}
+	for _, index := range bp.indexes {
+		if index.kind == indexPrimary {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, "adding a primary key is"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate rejects unique hash indexes and column character sets, which
+// PostgreSQL does not have, and the partitioning and unlogged tables
+// CockroachDB does not have
+func (bp *postgresqlBlueprint) validate() error {
+	var errs []error
+	for _, column := range bp.columns {
+		if column.Charset != "" {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("character set of column %s is", column.Name)))
+		}
+	}
+	for _, index := range bp.indexes {
+		if index.kind == indexUnique && index.algorithm == Hash && !bp.provider.cockroach {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("unique index %s using HASH is", index.name)))
+		}
+	}
+	if bp.temporary {
+		for _, foreign := range bp.completeForeigns() {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("foreign key %s of a temporary table is", foreign.name)))
+		}
+		if bp.unlogged {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, "an unlogged temporary table is"))
+		}
+	}
+	if bp.provider.cockroach {
+		if bp.partitionMethod() != "" {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, "partitioning is"))
+		}
+		if bp.unlogged {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, "an unlogged table is"))
+		}
+	}
+	errs = append(errs, bp.validatePartitions())
+	return errors.Join(errs...)
+}
+
+// validateSkipExisting rejects adding to an existing table what MySQL cannot
+// skip when it exists: columns and indexes, which MariaDB and TiDB can, and
+// constraints
+func (bp *mysqlBlueprint) validateSkipExisting() error {
+	if !bp.skipExisting {
+		return nil
+	}
+	var errs []error
+	if !bp.provider.mariadb && !bp.provider.tidb {
+		for _, column := range bp.columns {
+			if !column.Change {
+				errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding column %s if not exists is", column.Name)))
+			}
+		}
+	}
+	for _, index := range bp.indexes {
+		switch {
+		case index.kind == indexPrimary:
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, "adding a primary key if not exists is"))
+		case !bp.provider.mariadb && !bp.provider.tidb:
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding index %s if not exists is", index.name)))
+		}
+	}
+	for _, foreign := range bp.foreignKeys() {
+		errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding foreign key %s if not exists is", foreign.name)))
+	}
+	for _, check := range bp.checks {
+		errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding check %s if not exists is", check.name)))
+	}
+	return errors.Join(errs...)
+}
+
+// validateAlter rejects partitioning an existing table, which PostgreSQL
+// cannot do, and adding constraints when skipping what exists, as they have
+// no IF NOT EXISTS
+func (bp *postgresqlBlueprint) validateAlter() error {
+	var errs []error
+	if bp.partitioning.method != "" {
+		errs = append(errs, unsupported(bp.dialect(), bp.tableName, "partitioning an existing table is"))
+	}
+	if bp.skipExisting {
+		for _, index := range bp.indexes {
+			if index.kind == indexPrimary {
+				errs = append(errs, unsupported(bp.dialect(), bp.tableName, "adding a primary key if not exists is"))
+			}
+		}
+		for _, foreign := range bp.completeForeigns() {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding foreign key %s if not exists is", foreign.name)))
+		}
+		for _, check := range bp.checks {
+			errs = append(errs, unsupported(bp.dialect(), bp.tableName, fmt.Sprintf("adding check %s if not exists is", check.name)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate rejects what SQL Server does not have: column character sets,
+// index algorithms, full-text indexes without a catalog, and partitioning
+// without a partition scheme
+func (bp *sqlserverBlueprint) validate() error {
+	var errs []error
+	for _, column := range bp.columns {
+		if column.Charset != "" {
+			errs = append(errs, unsupported("SQL Server", bp.tableName, fmt.Sprintf("character set of column %s is", column.Name)))
+		}
+	}
+	for _, index := range bp.indexes {
+		if index.algorithm != "" && index.algorithm != BTree {
+			errs = append(errs, unsupported("SQL Server", bp.tableName, fmt.Sprintf("index %s using %s is", index.name, index.algorithm)))
+		}
+		if index.kind == indexFullText {
+			errs = append(errs, unsupported("SQL Server", bp.tableName, fmt.Sprintf("full-text index %s is", index.name)))
+		}
+	}
+	if bp.partitionMethod() != "" {
+		errs = append(errs, unsupported("SQL Server", bp.tableName, "partitioning is"))
+	}
+	if bp.temporary {
+		for _, foreign := range bp.completeForeigns() {
+			errs = append(errs, unsupported("SQL Server", bp.tableName, fmt.Sprintf("foreign key %s of a temporary table is", foreign.name)))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// LoadFS registers the SQL migrations in the root directory of fsys, usually
+// an embed.FS, so a binary ships with its migrations and can run them on
+// startup:
+//
+//	//go:embed migrations/*.sql
+//	var files embed.FS
+//
+//	sub, _ := fs.Sub(files, "migrations")
+//	runner := migrations.NewRunner(migrations.PostgreSQL)
+//	if err := runner.LoadFS(sub); err != nil {
+//		return err
+//	}
+//	return runner.Up(db)
+//
+// A migration is a NAME.up.sql file with an optional NAME.down.sql, or a
+// NAME.sql file without down path. Files may hold several statements
+// separated by semicolons. Go migrations are compiled in and register
+// themselves instead, see Register.
+func (r *Runner) LoadFS(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+
+	ups, downs := map[string]string{}, map[string]string{}
+	var names []string
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || path.Ext(file) != ".sql" {
+			continue
+		}
+		name := strings.TrimSuffix(file, ".sql")
+		switch {
+		case strings.HasSuffix(name, ".down"):
+			downs[strings.TrimSuffix(name, ".down")] = file
+			continue
+		case strings.HasSuffix(name, ".up"):
+			name = strings.TrimSuffix(name, ".up")
+		}
+		if _, ok := ups[name]; ok {
+			return fmt.Errorf("migrations: %s and %s define the same migration", ups[name], file)
+		}
+		ups[name] = file
+		names = append(names, name)
+	}
+	for name, file := range downs {
+		if _, ok := ups[name]; !ok {
+			return fmt.Errorf("migrations: %s has no up migration", file)
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := r.find(name); ok {
+			return fmt.Errorf("migrations: %s is already registered", name)
+		}
+		up, err := r.sqlMigration(fsys, ups[name])
+		if err != nil {
+			return err
+		}
+		var down MigrationFunc
+		if file, ok := downs[name]; ok {
+			if down, err = r.sqlMigration(fsys, file); err != nil {
+				return err
+			}
+		}
+		r.Register(name, up, down)
+	}
+	return nil
+}
+
+// sqlMigration returns a MigrationFunc executing the statements of file
+func (r *Runner) sqlMigration(fsys fs.FS, file string) (MigrationFunc, error) {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	statements := splitStatements(string(data))
+	return func(db DB) error {
+		return r.provider.exec(context.Background(), db, statements)
+	}, nil
+}
+
+// splitStatements splits SQL on the semicolons ending statements, skipping
+// the ones inside quotes, comments and PostgreSQL dollar quoted bodies
+func splitStatements(src string) []string {
+	var statements []string
+	start := 0
+	add := func(end int) {
+		if statement := strings.TrimSpace(src[start:end]); statement != "" && !onlyComments(statement) {
+			statements = append(statements, statement)
+		}
+	}
+
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' && c != '`' {
+					i++
+				}
+			}
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(src)
+			}
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(src)
+			}
+		case c == '$':
+			if tag, ok := dollarTag(src[i:]); ok {
+				if end := strings.Index(src[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(src)
+				}
+			}
+		case c == ';':
+			add(i)
+			start = i + 1
+		}
+	}
+	add(len(src))
+	return statements
+}
+
+// dollarTag returns the opening $tag$ of a dollar quoted string at the start of s
+func dollarTag(s string) (string, bool) {
+	end := strings.IndexByte(s[1:], '$')
+	if end < 0 {
+		return "", false
+	}
+	tag := s[:end+2]
+	for _, c := range tag[1 : len(tag)-1] {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return tag, true
+}
+
+// onlyComments reports whether statement holds nothing but line comments
+func onlyComments(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
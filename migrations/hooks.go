@@ -0,0 +1,132 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MigrationEvent describes a migration the Runner applies or rolls back, for
+// the hooks registered with OnBeforeMigration, OnAfterMigration and OnError
+type MigrationEvent struct {
+	Name string
+	// Direction is "up" or "down"
+	Direction string
+	// Statements are the statements the migration executed, in order, nil
+	// before it runs. Queries reading the database are left out.
+	Statements []string
+	Duration   time.Duration
+	// Err is the error the migration failed with, for OnError hooks
+	Err error
+}
+
+// MigrationHook is called by the Runner around each migration
+type MigrationHook func(ctx context.Context, event MigrationEvent)
+
+// OnBeforeMigration registers a hook called before each migration runs
+func (r *Runner) OnBeforeMigration(hook MigrationHook) *Runner {
+	r.before = append(r.before, hook)
+	return r
+}
+
+// OnAfterMigration registers a hook called after each migration succeeded,
+// with the statements it executed and how long it took
+func (r *Runner) OnAfterMigration(hook MigrationHook) *Runner {
+	r.after = append(r.after, hook)
+	return r
+}
+
+// OnError registers a hook called when a migration fails, with the
+// statements it executed up to the failing one, before Up or Down returns the error
+func (r *Runner) OnError(hook MigrationHook) *Runner {
+	r.onError = append(r.onError, hook)
+	return r
+}
+
+// run applies or reverts a migration, reporting it to the hooks. The
+// statements are recorded only when a hook reads them, by handing the
+// migration a DB that records what it executes on db.
+func (r *Runner) run(ctx context.Context, db DB, name, direction string, fn MigrationFunc) error {
+	event := MigrationEvent{Name: name, Direction: direction}
+	for _, hook := range r.before {
+		hook(ctx, event)
+	}
+
+	target := db
+	var recorded *recordDB
+	if len(r.after) > 0 || len(r.onError) > 0 {
+		recorded = &recordDB{db: db, recorder: &recorder{}}
+		target = recorded
+	}
+
+	started := time.Now()
+	err := fn(target)
+	event.Duration = time.Since(started)
+	if recorded != nil {
+		event.Statements = recorded.recorder.statements
+	}
+
+	if err != nil {
+		event.Err = err
+		for _, hook := range r.onError {
+			hook(ctx, event)
+		}
+		return err
+	}
+	migrationDuration.Observe(event.Duration.Seconds(), direction)
+	migrationsRun.Inc(direction)
+	for _, hook := range r.after {
+		hook(ctx, event)
+	}
+	return nil
+}
+
+// recorder collects the statements executed through the recordDBs sharing it
+type recorder struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (r *recorder) record(query string) {
+	r.mu.Lock()
+	r.statements = append(r.statements, strings.TrimSpace(query))
+	r.mu.Unlock()
+}
+
+// recordDB executes statements on db, recording them. Statements run on a
+// transaction a migration opens with BeginTx are not recorded, those the
+// schema helpers run in their own transaction are.
+type recordDB struct {
+	db       DB
+	recorder *recorder
+}
+
+func (d *recordDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	d.recorder.record(query)
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+func (d *recordDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, query, args...)
+}
+
+func (d *recordDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx opens a transaction on db, failing when db cannot open one
+func (d *recordDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	b, ok := d.db.(beginner)
+	if !ok {
+		return nil, fmt.Errorf("migrations: %T cannot open a transaction", d.db)
+	}
+	return b.BeginTx(ctx, opts)
+}
+
+// on returns a recordDB executing on tx, recording to the same recorder
+func (d *recordDB) on(tx DB) *recordDB {
+	return &recordDB{db: tx, recorder: d.recorder}
+}
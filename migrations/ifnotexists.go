@@ -0,0 +1,119 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// IfNotExists makes Create skip an existing table and Table skip the columns
+// and indexes that already exist, so bootstrap scripts can run again. Rollback
+// still drops what the callback adds, existing before or not.
+//
+// MySQL only skips an existing table, MariaDB and TiDB also skip columns and
+// indexes. PostgreSQL has no IF NOT EXISTS for constraints, Create declares
+// the foreign keys in the CREATE TABLE it skips and Table rejects them. SQL
+// Server guards each statement with a lookup of what it creates.
+func (b *blueprint) IfNotExists() {
+	b.skipExisting = true
+}
+
+// CreateIfNotExists creates the table unless it exists, see Blueprint.IfNotExists
+func (m *mysqlProvider) CreateIfNotExists(db DB, tableName string, callback func(MySQLBlueprint)) error {
+	return m.CreateIfNotExistsContext(context.Background(), db, tableName, callback)
+}
+
+func (m *mysqlProvider) CreateIfNotExistsContext(ctx context.Context, db DB, tableName string, callback func(MySQLBlueprint)) error {
+	return m.CreateContext(ctx, db, tableName, func(bp MySQLBlueprint) {
+		bp.IfNotExists()
+		callback(bp)
+	})
+}
+
+// CreateIfNotExists creates the table unless it exists, see Blueprint.IfNotExists
+func (p *postgresqlProvider) CreateIfNotExists(db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	return p.CreateIfNotExistsContext(context.Background(), db, tableName, callback)
+}
+
+func (p *postgresqlProvider) CreateIfNotExistsContext(ctx context.Context, db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	return p.CreateContext(ctx, db, tableName, func(bp PostgreSQLBlueprint) {
+		bp.IfNotExists()
+		callback(bp)
+	})
+}
+
+// CreateIfNotExists creates the table unless it exists, see Blueprint.IfNotExists
+func (s *sqlserverProvider) CreateIfNotExists(db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	return s.CreateIfNotExistsContext(context.Background(), db, tableName, callback)
+}
+
+func (s *sqlserverProvider) CreateIfNotExistsContext(ctx context.Context, db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	return s.CreateContext(ctx, db, tableName, func(bp MSSQLBlueprint) {
+		bp.IfNotExists()
+		callback(bp)
+	})
+}
+
+// CreateIfNotExists creates the table unless it exists, see Blueprint.IfNotExists
+func (s *Schema) CreateIfNotExists(tableName string, callback func(Blueprint)) error {
+	return s.CreateIfNotExistsContext(context.Background(), tableName, callback)
+}
+
+func (s *Schema) CreateIfNotExistsContext(ctx context.Context, tableName string, callback func(Blueprint)) error {
+	return s.CreateContext(ctx, tableName, func(bp Blueprint) {
+		bp.IfNotExists()
+		callback(bp)
+	})
+}
+
+// addColumn returns the ADD COLUMN keywords, which MariaDB and TiDB let skip an existing column
+func (bp *mysqlBlueprint) addColumn() string {
+	if bp.skipExisting {
+		return "ADD COLUMN IF NOT EXISTS"
+	}
+	return "ADD COLUMN"
+}
+
+// addIndexSQL returns the clause adding index to an existing table, see addColumn
+func (bp *mysqlBlueprint) addIndexSQL(index *index) string {
+	sql := bp.indexSQL(index)
+	if bp.skipExisting {
+		sql = strings.Replace(sql, "INDEX ", "INDEX IF NOT EXISTS ", 1)
+	}
+	return "ADD " + sql
+}
+
+// unless guards statement with condition when the blueprint skips what
+// exists, SQL Server having no IF NOT EXISTS clause
+func (bp *sqlserverBlueprint) unless(condition, statement string) string {
+	if !bp.skipExisting {
+		return statement
+	}
+	return fmt.Sprintf("IF NOT EXISTS (%s) %s", condition, statement)
+}
+
+// objectID returns the OBJECT_ID of the table, temporary tables living in tempdb
+func (bp *sqlserverBlueprint) objectID() string {
+	if bp.temporary {
+		return fmt.Sprintf("OBJECT_ID(%s)", quoteString("tempdb.."+bp.table()))
+	}
+	return fmt.Sprintf("OBJECT_ID(%s)", quoteString(bp.table()))
+}
+
+func (bp *sqlserverBlueprint) unlessTable(statement string) string {
+	return bp.unless(fmt.Sprintf("SELECT 1 FROM sys.objects WHERE object_id = %s AND type = N'U'", bp.objectID()), statement)
+}
+
+func (bp *sqlserverBlueprint) unlessColumn(name, statement string) string {
+	return bp.unless(fmt.Sprintf("SELECT 1 FROM sys.columns WHERE object_id = %s AND name = %s", bp.objectID(), quoteString(name)), statement)
+}
+
+// unlessIndex guards indexes and the primary keys backed by one
+func (bp *sqlserverBlueprint) unlessIndex(name, statement string) string {
+	return bp.unless(fmt.Sprintf("SELECT 1 FROM sys.indexes WHERE object_id = %s AND name = %s", bp.objectID(), quoteString(name)), statement)
+}
+
+// unlessConstraint guards foreign keys and check constraints
+func (bp *sqlserverBlueprint) unlessConstraint(name, statement string) string {
+	return bp.unless(fmt.Sprintf("SELECT 1 FROM sys.objects WHERE parent_object_id = %s AND name = %s", bp.objectID(), quoteString(name)), statement)
+}
@@ -0,0 +1,188 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// ColumnInfo describes a column of a live table, as returned by Provider.Columns
+type ColumnInfo struct {
+	Name string
+	// Type is the full column type as the database reports it, such as
+	// "varchar(255)", "bigint unsigned" or "character varying(255)"
+	Type     string
+	Nullable bool
+	// Default is the default expression, nil when the column has none
+	Default       *string
+	Primary       bool
+	AutoIncrement bool
+}
+
+// IndexInfo describes an index of a live table, as returned by Provider.Indexes
+type IndexInfo struct {
+	Name string
+	// Columns are the indexed columns in index order, expression parts are left out
+	Columns []string
+	Unique  bool
+	Primary bool
+	// Algorithm is the index method in upper case, such as BTREE, GIN or FULLTEXT
+	Algorithm string
+	// Where is the predicate of a partial index
+	Where string
+	// Expression is the indexed expression of a declared expression index,
+	// Describe leaves Columns empty for those
+	Expression string
+}
+
+// ForeignKeyInfo describes a foreign key a blueprint declares
+type ForeignKeyInfo struct {
+	Name          string
+	Column        string
+	ForeignTable  string
+	ForeignColumn string
+	OnDelete      string
+	OnUpdate      string
+}
+
+// CheckInfo describes a CHECK constraint a blueprint declares
+type CheckInfo struct {
+	Name string
+	// Column is the column the check was declared on, empty for table checks
+	Column     string
+	Expression string
+}
+
+// Declaration is what a blueprint callback declares for a table
+type Declaration struct {
+	Columns     []Column
+	Indexes     []IndexInfo
+	ForeignKeys []ForeignKeyInfo
+	Checks      []CheckInfo
+	// Comment is the comment of the table
+	Comment string
+	// Options names what else the callback declares, Partition, IfNotExists
+	// or the table options of a dialect such as Engine or Unlogged, which the
+	// other fields leave out
+	Options []string
+}
+
+// queryColumns scans rows of name, type, nullable, default, primary and auto increment
+func queryColumns(ctx context.Context, db DB, query string, args ...any) ([]ColumnInfo, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var column ColumnInfo
+		var defaultValue sql.NullString
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &defaultValue, &column.Primary, &column.AutoIncrement); err != nil {
+			return nil, err
+		}
+		if defaultValue.Valid {
+			column.Default = &defaultValue.String
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// queryIndexes scans rows of index name, column, unique, primary, algorithm
+// and predicate, one row per indexed column in index order
+func queryIndexes(ctx context.Context, db DB, query string, args ...any) ([]IndexInfo, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []IndexInfo
+	for rows.Next() {
+		var name, algorithm string
+		var column, where sql.NullString
+		var unique, primary bool
+		if err := rows.Scan(&name, &column, &unique, &primary, &algorithm, &where); err != nil {
+			return nil, err
+		}
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != name {
+			indexes = append(indexes, IndexInfo{
+				Name:      name,
+				Columns:   []string{},
+				Unique:    unique,
+				Primary:   primary,
+				Algorithm: strings.ToUpper(algorithm),
+				Where:     where.String,
+			})
+		}
+		if column.Valid {
+			index := &indexes[len(indexes)-1]
+			index.Columns = append(index.Columns, column.String)
+		}
+	}
+	return indexes, rows.Err()
+}
+
+// Describe returns the columns and indexes callback declares for the dialect
+// of provider, for tools comparing blueprints against the live schema. Unique
+// and primary keys declared on a column are only reported on the column.
+func Describe(provider Provider, tableName string, callback func(Blueprint)) ([]Column, []IndexInfo) {
+	d := DescribeTable(provider, tableName, callback)
+	return d.Columns, d.Indexes
+}
+
+// DescribeTable returns everything callback declares for the dialect of
+// provider, for tools writing the declaration back out as code
+func DescribeTable(provider Provider, tableName string, callback func(Blueprint)) Declaration {
+	bp := provider.describe(tableName, callback)
+	d := Declaration{Comment: bp.comment, Options: append([]string{}, bp.options...)}
+
+	d.Columns = make([]Column, len(bp.columns))
+	for i, column := range bp.columns {
+		d.Columns[i] = *column
+	}
+
+	d.Indexes = make([]IndexInfo, 0, len(bp.indexes))
+	for _, index := range bp.indexes {
+		info := IndexInfo{
+			Name:       index.name,
+			Columns:    append([]string{}, index.columns...),
+			Unique:     index.kind == indexUnique || index.kind == indexPrimary,
+			Primary:    index.kind == indexPrimary,
+			Algorithm:  index.algorithm,
+			Where:      index.where,
+			Expression: index.expression,
+		}
+		if index.kind == indexFullText || index.kind == indexSpatial {
+			info.Algorithm = index.kind
+		}
+		if index.expression != "" {
+			info.Columns = []string{}
+		}
+		d.Indexes = append(d.Indexes, info)
+	}
+
+	for _, fk := range bp.completeForeigns() {
+		d.ForeignKeys = append(d.ForeignKeys, ForeignKeyInfo{
+			Name:          fk.name,
+			Column:        fk.column,
+			ForeignTable:  fk.foreignTable,
+			ForeignColumn: fk.foreignColumn,
+			OnDelete:      fk.onDelete,
+			OnUpdate:      fk.onUpdate,
+		})
+	}
+	for _, c := range bp.checks {
+		d.Checks = append(d.Checks, CheckInfo{Name: c.name, Column: c.column, Expression: c.expression})
+	}
+
+	if bp.partitionMethod() != "" {
+		d.Options = append(d.Options, "Partition")
+	}
+	if bp.skipExisting {
+		d.Options = append(d.Options, "IfNotExists")
+	}
+	return d
+}
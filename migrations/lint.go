@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Warning is a risky pattern Lint found in a statement
+type Warning struct {
+	// Rule names the pattern, such as "not-null-without-default"
+	Rule string `json:"rule"`
+	// Migration is the name of the migration executing the statement, when known
+	Migration string `json:"migration,omitempty"`
+	Statement string `json:"statement"`
+	Message   string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Migration == "" {
+		return fmt.Sprintf("%s: %s", w.Rule, w.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", w.Migration, w.Rule, w.Message)
+}
+
+// maxEnumValues is how many values an ENUM or SET can hold before Lint warns
+const maxEnumValues = 64
+
+var (
+	addColumn        = regexp.MustCompile(`(?i)\bADD\s+(?:COLUMN\s+)?([` + "`" + `"\[]?\w+[` + "`" + `"\]]?)\s+(.*)`)
+	notNull          = regexp.MustCompile(`(?i)\bNOT\s+NULL\b`)
+	generatedDefault = regexp.MustCompile(`(?i)\b(DEFAULT|SERIAL|BIGSERIAL|SMALLSERIAL|AUTO_INCREMENT|AUTO_RANDOM|GENERATED)\b`)
+	setNotNull       = regexp.MustCompile(`(?i)\bALTER\s+COLUMN\s+(\S+)\s+SET\s+NOT\s+NULL\b`)
+	dropColumn       = regexp.MustCompile(`(?i)\bDROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?([` + "`" + `"\[]?\w+[` + "`" + `"\]]?)`)
+	modifyEnum       = regexp.MustCompile(`(?i)\b(?:MODIFY|CHANGE)\s+(?:COLUMN\s+)?(\S+).*\b(ENUM|SET)\s*\(`)
+	enumValues       = regexp.MustCompile(`(?i)\b(?:ENUM|SET)\s*\(([^)]*)\)`)
+	alterStatement   = regexp.MustCompile(`(?i)^\s*ALTER\s+TABLE\b`)
+)
+
+// Lint flags statements that are risky to run against populated tables:
+// NOT NULL columns added without a default, which fail or rewrite the table
+// when it has rows, dropped columns, and changes to ENUM and SET columns or
+// ones with more than 64 values, which rebuild the table
+func Lint(statements []string) []Warning {
+	var warnings []Warning
+	warn := func(statement, rule, format string, args ...any) {
+		warnings = append(warnings, Warning{Rule: rule, Statement: statement, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, statement := range statements {
+		if alterStatement.MatchString(statement) {
+			for _, m := range addColumn.FindAllStringSubmatch(statement, -1) {
+				if !isKeyword(m[1]) && notNull.MatchString(m[2]) && !generatedDefault.MatchString(m[2]) {
+					warn(statement, "not-null-without-default", "column %s is added NOT NULL without a default, which fails on a table with rows", m[1])
+				}
+			}
+			for _, m := range setNotNull.FindAllStringSubmatch(statement, -1) {
+				warn(statement, "not-null-without-default", "column %s is made NOT NULL, which fails when a row holds NULL", m[1])
+			}
+			for _, m := range dropColumn.FindAllStringSubmatch(statement, -1) {
+				if isKeyword(m[1]) {
+					continue
+				}
+				warn(statement, "drop-column", "column %s is dropped with its data, and code still reading it breaks", m[1])
+			}
+			if m := modifyEnum.FindStringSubmatch(statement); m != nil {
+				warn(statement, "enum-change", "%s column %s is changed, which rebuilds the table unless values are only appended", strings.ToUpper(m[2]), m[1])
+			}
+		}
+		for _, m := range enumValues.FindAllStringSubmatch(statement, -1) {
+			if n := len(strings.Split(m[1], ",")); n > maxEnumValues {
+				warn(statement, "enum-change", "ENUM or SET has %d values, consider a lookup table", n)
+			}
+		}
+	}
+	return warnings
+}
+
+// isKeyword reports whether the name addColumn or dropColumn matched is a
+// target other than a column, such as ADD INDEX or DROP CONSTRAINT
+func isKeyword(name string) bool {
+	switch strings.ToUpper(name) {
+	case "INDEX", "KEY", "CONSTRAINT", "CHECK", "FOREIGN", "PRIMARY", "DEFAULT", "NOT",
+		"UNIQUE", "FULLTEXT", "SPATIAL":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package migrations
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Masker replaces a column value Copy reads from the source database, so
+// staging datasets hold no personal data. Values are as the driver returns
+// them, strings often as []byte, and nil for NULL.
+type Masker func(value any) any
+
+var masks = map[string]map[string]Masker{}
+
+// unmasked holds the columns declared safe to copy as they are
+var unmasked = map[string]map[string]bool{}
+
+// StrictMasks makes Copy refuse tables with columns that have neither a
+// masker nor an Unmasked declaration, instead of copying them with a warning
+var StrictMasks = false
+
+// RegisterMasks sets the maskers Copy applies to the columns of table,
+// usually from the migration file declaring the table:
+//
+//	migrations.RegisterMasks("users", map[string]migrations.Masker{
+//		"email": migrations.FakeEmail(),
+//		"name":  migrations.Hashed(),
+//		"phone": migrations.Nullify(),
+//	})
+func RegisterMasks(table string, columns map[string]Masker) {
+	if masks[table] == nil {
+		masks[table] = map[string]Masker{}
+	}
+	for column, masker := range columns {
+		masks[table][column] = masker
+	}
+}
+
+// Unmasked declares columns of table that hold no personal data, so Copy
+// copies them as they are without warning, or at all under StrictMasks
+func Unmasked(table string, columns ...string) {
+	if unmasked[table] == nil {
+		unmasked[table] = map[string]bool{}
+	}
+	for _, column := range columns {
+		unmasked[table][column] = true
+	}
+}
+
+// maskKey keys the hashes of the maskers, so masked values cannot be
+// reversed by hashing guesses while staying consistent within the process
+var maskKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+func maskHash(value any) string {
+	mac := hmac.New(sha256.New, maskKey)
+	fmt.Fprint(mac, maskString(value))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+func maskString(value any) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// FakeEmail replaces addresses with user_HASH@example.com. The same address
+// always gets the same replacement, so unique columns stay unique.
+func FakeEmail() Masker {
+	return func(value any) any {
+		if value == nil {
+			return nil
+		}
+		return "user_" + maskHash(value) + "@example.com"
+	}
+}
+
+// Hashed replaces values with a 16 character keyed hash, equal values
+// getting equal hashes so joins and unique columns keep working
+func Hashed() Masker {
+	return func(value any) any {
+		if value == nil {
+			return nil
+		}
+		return maskHash(value)
+	}
+}
+
+// Nullify replaces values with NULL, for nullable columns
+func Nullify() Masker {
+	return func(any) any { return nil }
+}
+
+// Fixed replaces values with replacement, such as a known password hash
+func Fixed(replacement any) Masker {
+	return func(any) any { return replacement }
+}
+
+// Copy copies the rows of tables from src to dst, applying the maskers
+// registered with RegisterMasks, to build a safe staging dataset from
+// production. Tables are copied in the given order, so referenced tables must
+// come first, and each in a transaction. dst should hold the migrated,
+// empty schema. Explicit ids are copied as they are, the sequences and
+// identities of dst continuing after them. Columns neither masked nor declared
+// Unmasked are logged as a warning, see StrictMasks. Without tables, every table of src but the migrations table
+// is copied in name order.
+func Copy(ctx context.Context, dst, src *sql.DB, provider Provider, tables ...string) error {
+	if len(tables) == 0 {
+		all, err := provider.TablesContext(ctx, src)
+		if err != nil {
+			return err
+		}
+		for _, table := range all {
+			if table != MigrationsTable {
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	for _, table := range tables {
+		if err := copyTable(ctx, dst, src, provider, table); err != nil {
+			return fmt.Errorf("migrations: copying %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func copyTable(ctx context.Context, dst, src *sql.DB, provider Provider, table string) error {
+	rows, err := src.QueryContext(ctx, "SELECT * FROM "+provider.quoteTable(table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	maskers := make([]Masker, len(columns))
+	var unchecked []string
+	for i, column := range columns {
+		maskers[i] = masks[table][column]
+		if maskers[i] == nil && !unmasked[table][column] {
+			unchecked = append(unchecked, column)
+		}
+	}
+	if len(unchecked) > 0 {
+		if StrictMasks {
+			return fmt.Errorf("columns %s have neither a masker nor an Unmasked declaration", strings.Join(unchecked, ", "))
+		}
+		slog.WarnContext(ctx, "migrations: copying columns unmasked", "table", table, "columns", unchecked)
+	}
+	insert := InsertSQL(provider, table, columns, 1)
+
+	described, err := provider.ColumnsContext(ctx, dst, table)
+	if err != nil {
+		return err
+	}
+	before, after := provider.copySQL(table, described)
+
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range before {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	stmt, err := tx.PrepareContext(ctx, insert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for i, masker := range maskers {
+			if masker != nil {
+				values[i] = masker(values[i])
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, statement := range after {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
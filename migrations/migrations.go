@@ -1,13 +1,147 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 )
 
 var MySQL = &mysqlProvider{}
-var PostgreSQL = &postgresqlProvider{}
+var PostgreSQL = &postgresqlProvider{transaction: true}
+
+// MariaDB is the MySQL provider adjusted for MariaDB 10.5 and later, whose
+// JSON columns are LONGTEXT and which drops check constraints with DROP CONSTRAINT
+var MariaDB = &mysqlProvider{mariadb: true}
+
+// TiDB is the MySQL provider rejecting the full-text and spatial indexes TiDB
+// does not build, and primary keys, auto incrementing and stored generated
+// columns added to existing tables. Chain WithAutoRandom for AUTO_RANDOM keys.
+var TiDB = &mysqlProvider{tidb: true}
+
+// CockroachDB is the PostgreSQL provider adjusted for CockroachDB 23.1 and
+// later: ID, Serial and BigSerial keys use unique_rowid instead of sequences,
+// Using(Hash) builds hash-sharded indexes, and tables, columns and indexes
+// are created and dropped with IF NOT EXISTS and IF EXISTS. Statements run
+// outside of a transaction, as CockroachDB applies schema changes in the
+// background after the transaction commits, and Runner takes no lock, see
+// Runner.Lock. Prefer UUIDPrimary keys.
+var CockroachDB = &postgresqlProvider{cockroach: true}
+
+// Vitess is the MySQL provider rejecting foreign keys, which Vitess cannot
+// enforce across shards. Chain WithForeignKeys(false) to leave them out instead.
+var Vitess = &mysqlProvider{vitess: true}
+
+// DB is what providers, Schema, the Runner and migrations run statements on.
+// *sql.DB, *sql.Conn and *sql.Tx implement it, as does sqlx.DB. A pgx.Conn
+// does not, its methods returning pgx types; adapt it with Pgx. On a *sql.Tx
+// the statements join the transaction instead of opening their own.
+type DB interface {
+	Execer
+	Queryer
+}
+
+// Execer executes statements
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Queryer runs queries
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// beginner opens transactions, as *sql.DB and *sql.Conn do
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// execStatements executes sqls in order, inside a single transaction when
+// transaction is set and db can open one
+func execStatements(ctx context.Context, db DB, sqls []string, transaction bool) error {
+	recorded, recording := db.(*recordDB)
+	var b beginner
+	var ok bool
+	if recording {
+		b, ok = recorded.db.(beginner)
+	} else {
+		b, ok = db.(beginner)
+	}
+	if !transaction || !ok {
+		for _, sql := range sqls {
+			if _, err := db.ExecContext(ctx, sql); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tx, err := b.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	var exec Execer = tx
+	if recording {
+		exec = recorded.on(tx)
+	}
+	for _, sql := range sqls {
+		if _, err := exec.ExecContext(ctx, sql); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// InsertSQL returns an INSERT statement adding rows rows of values for
+// columns of table, with the quoting and placeholders of the dialect of
+// provider, for tools loading data in batches
+func InsertSQL(provider Provider, table string, columns []string, rows int) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = provider.quote(column)
+	}
+	values := make([]string, rows)
+	placeholders := make([]string, len(columns))
+	for row := range values {
+		for i := range columns {
+			placeholders[i] = provider.placeholder(row*len(columns) + i + 1)
+		}
+		values[row] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		provider.quoteTable(table), strings.Join(quoted, ", "), strings.Join(values, ", "))
+}
+
+// InsertRows returns the most rows an InsertSQL statement for columns columns
+// can add on the dialect of provider, within its parameter and VALUES limits
+func InsertRows(provider Provider, columns int) int {
+	rows := provider.maxParams() / max(columns, 1)
+	if limit := provider.maxInsertRows(); limit > 0 {
+		rows = min(rows, limit)
+	}
+	return rows
+}
+
+// queryStrings returns the first column of the rows of query
+func queryStrings(ctx context.Context, db DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
 
 type Column struct {
 	Name      string
@@ -19,6 +153,15 @@ type Column struct {
 	Unique    bool
 	Comment   string
 	After     string
+	Change    bool
+	// Generated is the expression of a generated column, Stored tells whether its value is stored or computed on read
+	Generated string
+	Stored    bool
+	// Unsigned and Charset are MySQL only
+	Unsigned      bool
+	AutoIncrement bool
+	Charset       string
+	Collation     string
 }
 
 type Blueprint interface {
@@ -34,16 +177,45 @@ type Blueprint interface {
 	Date(name string) ColumnBuilder
 	DateTime(name string) ColumnBuilder
 	Timestamp(name string) ColumnBuilder
+	// TimestampTz adds a column holding an instant whatever the session time
+	// zone, TIMESTAMPTZ on PostgreSQL
+	TimestampTz(name string) ColumnBuilder
 	JSON(name string) ColumnBuilder
 	Binary(name string) ColumnBuilder
 	UUID(name string) ColumnBuilder
+	UUIDPrimary() ColumnBuilder
+	ULID(name string) ColumnBuilder
 	Timestamps()
-	Index(columns ...string)
-	UniqueIndex(columns ...string)
+	// TimestampsTz adds created_at and updated_at as TimestampTz columns
+	TimestampsTz()
+	// NullableTimestamps adds created_at and updated_at without defaults, for
+	// the application to set
+	NullableTimestamps()
+	SoftDeletes() ColumnBuilder
+	Index(columns ...string) IndexBuilder
+	UniqueIndex(columns ...string) IndexBuilder
 	Primary(columns ...string)
 	FullTextIndex(columns ...string)
 	Foreign(column string) ForeignKeyBuilder
+	ForeignID(column string) ForeignIDBuilder
 	AddColumn(name, columnType string) ColumnBuilder
+	DropColumn(name string)
+	RenameColumn(from, to string)
+	DropIndex(name string)
+	// RenameIndex renames an index of the table, Rollback renames it back
+	RenameIndex(from, to string)
+	DropUnique(columns ...string)
+	DropForeign(name string)
+	Check(expression string)
+	// Comment sets the comment of the table
+	Comment(text string)
+	// IfNotExists skips the table, columns and indexes that already exist,
+	// so bootstrap scripts can run again, see CreateIfNotExists
+	IfNotExists()
+	PartitionByRange(columns ...string)
+	PartitionByList(column string)
+	PartitionByHash(column string, n int)
+	Partition(name string) PartitionBuilder
 }
 
 type MySQLBlueprint interface {
@@ -52,6 +224,12 @@ type MySQLBlueprint interface {
 	Set(name string, values []string) ColumnBuilder
 	Point(name string) ColumnBuilder
 	Geometry(name string) ColumnBuilder
+	SpatialIndex(columns ...string)
+	// Engine, Charset and Collation set the table options, falling back to
+	// DefaultEngine, DefaultCharset and DefaultCollation on Create
+	Engine(engine string)
+	Charset(charset string)
+	Collation(collation string)
 }
 
 type PostgreSQLBlueprint interface {
@@ -67,6 +245,24 @@ type PostgreSQLBlueprint interface {
 	XML(name string) ColumnBuilder
 	Money(name string) ColumnBuilder
 	HStore(name string) ColumnBuilder
+	PartialIndex(columns []string, where string)
+	IndexExpression(expression string)
+	UpdatedAtTrigger()
+	Unlogged()
+}
+
+type MSSQLBlueprint interface {
+	Blueprint
+	// VarChar adds a non-Unicode string column, String adds NVARCHAR ones
+	VarChar(name string, length int) ColumnBuilder
+	// Identity adds a BIGINT column numbered from seed by increment
+	Identity(name string, seed, increment int) ColumnBuilder
+	DateTimeOffset(name string) ColumnBuilder
+	Money(name string) ColumnBuilder
+	// RowVersion adds a column the server changes on every update, for optimistic locking
+	RowVersion(name string) ColumnBuilder
+	// PartialIndex adds a filtered index over the rows matching where, such as "deleted_at IS NULL"
+	PartialIndex(columns []string, where string)
 }
 
 type ColumnBuilder interface {
@@ -78,6 +274,43 @@ type ColumnBuilder interface {
 	Comment(text string) ColumnBuilder
 	After(column string) ColumnBuilder
 	Index() ColumnBuilder
+	Change() ColumnBuilder
+	Check(expression string) ColumnBuilder
+	StoredAs(expression string) ColumnBuilder
+	Unsigned() ColumnBuilder
+	AutoIncrement() ColumnBuilder
+	Charset(charset string) ColumnBuilder
+	Collation(collation string) ColumnBuilder
+	VirtualAs(expression string) ColumnBuilder
+}
+
+// Index algorithms for IndexBuilder.Using. GIN and GiST are PostgreSQL only,
+// MySQL migrations using them fail with ErrUnsupported. MySQL's InnoDB
+// accepts HASH but builds a BTREE index anyway.
+const (
+	BTree = "BTREE"
+	Hash  = "HASH"
+	GIN   = "GIN"
+	GiST  = "GIST"
+)
+
+type IndexBuilder interface {
+	// Using sets the index algorithm, such as GIN for JSONB and TSVECTOR columns
+	Using(algorithm string) IndexBuilder
+	// Name replaces the name derived from the columns, such as email_index or
+	// email_unique, which DropIndex and Rollback then need
+	Name(name string) IndexBuilder
+}
+
+// ForeignIDBuilder configures a column added by ForeignID
+type ForeignIDBuilder interface {
+	ColumnBuilder
+	// Constrained adds the foreign key to the id column of table, by default
+	// the plural of the column name without _id, e.g. users for user_id
+	Constrained(table ...string) ForeignIDBuilder
+	CascadeOnDelete() ForeignIDBuilder
+	// NullOnDelete makes the column nullable and clears it when the referenced row is deleted
+	NullOnDelete() ForeignIDBuilder
 }
 
 type ForeignKeyBuilder interface {
@@ -89,30 +322,85 @@ type ForeignKeyBuilder interface {
 
 type blueprint struct {
 	tableName string
-	columns   []Column
-	indexes   []string
-	foreigns  []string
-	db        *sql.DB
+	columns   []*Column
+	indexes   []*index
+	foreigns  []*foreignKey
+	checks    []*check
+	drops     []string
+	renames   []rename
+
+	droppedIndexes  []string
+	renamedIndexes  []rename
+	droppedForeigns []string
+	comment         string
+	partitioning    partitioning
+	// temporary is set by CreateTemporary
+	temporary bool
+	// skipExisting is set by IfNotExists
+	skipExisting bool
+	// options names the dialect table options set, for DescribeTable
+	options []string
+}
+
+type rename struct {
+	from string
+	to   string
+}
+
+// check is a named CHECK constraint, named so Rollback can drop it
+type check struct {
+	name string
+	// column is set for the checks declared on a column
+	column     string
+	expression string
+}
+
+type index struct {
+	kind    string
+	name    string
+	columns []string
+	// expression replaces columns for expression indexes
+	expression string
+	// where restricts partial indexes to the matching rows
+	where string
+	// algorithm is the USING method, the database default when empty
+	algorithm string
+}
+
+const (
+	indexPlain    = "INDEX"
+	indexUnique   = "UNIQUE"
+	indexPrimary  = "PRIMARY"
+	indexFullText = "FULLTEXT"
+	indexSpatial  = "SPATIAL"
+)
+
+type foreignKey struct {
+	name          string
+	column        string
+	foreignTable  string
+	foreignColumn string
+	onDelete      string
+	onUpdate      string
 }
 
-func newBlueprint(tableName string, db *sql.DB) *blueprint {
+func newBlueprint(tableName string) *blueprint {
 	return &blueprint{
 		tableName: tableName,
-		columns:   []Column{},
-		indexes:   []string{},
-		foreigns:  []string{},
-		db:        db,
+		columns:   []*Column{},
+		indexes:   []*index{},
+		foreigns:  []*foreignKey{},
 	}
 }
 
 func (b *blueprint) AddColumn(name, columnType string) ColumnBuilder {
-	column := Column{
+	column := &Column{
 		Name: name,
 		Type: columnType,
 	}
 	b.columns = append(b.columns, column)
 	return &columnBuilder{
-		column:    &b.columns[len(b.columns)-1],
+		column:    column,
 		blueprint: b,
 	}
 }
@@ -165,6 +453,12 @@ func (b *blueprint) Timestamp(name string) ColumnBuilder {
 	return b.AddColumn(name, "TIMESTAMP")
 }
 
+// TimestampTz adds a TIMESTAMP column, which MySQL stores in UTC, converting
+// from and to the session time zone
+func (b *blueprint) TimestampTz(name string) ColumnBuilder {
+	return b.Timestamp(name)
+}
+
 func (b *blueprint) JSON(name string) ColumnBuilder {
 	return b.AddColumn(name, "JSON")
 }
@@ -177,35 +471,135 @@ func (b *blueprint) UUID(name string) ColumnBuilder {
 	return b.AddColumn(name, "CHAR(36)")
 }
 
+// UUIDPrimary adds a UUID id primary key. MySQL has no UUID default, so the
+// application sets it on insert, e.g. with idgen.UUID().
+func (b *blueprint) UUIDPrimary() ColumnBuilder {
+	return b.AddColumn("id", "CHAR(36) PRIMARY KEY")
+}
+
+// ULID adds a column holding a ULID in its 26 character text form
+func (b *blueprint) ULID(name string) ColumnBuilder {
+	return b.AddColumn(name, "CHAR(26)")
+}
+
 func (b *blueprint) Timestamps() {
 	b.AddColumn("created_at", "TIMESTAMP DEFAULT CURRENT_TIMESTAMP")
 	b.AddColumn("updated_at", "TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP")
 }
 
-func (b *blueprint) Index(columns ...string) {
-	indexName := strings.Join(columns, "_") + "_index"
-	b.indexes = append(b.indexes, fmt.Sprintf("INDEX %s (%s)", indexName, strings.Join(columns, ", ")))
+func (b *blueprint) TimestampsTz() {
+	b.Timestamps()
+}
+
+func (b *blueprint) NullableTimestamps() {
+	b.Timestamp("created_at").Nullable()
+	b.Timestamp("updated_at").Nullable()
+}
+
+// SoftDeletes adds the nullable deleted_at column marking soft deleted rows,
+// chain Index to index it
+func (b *blueprint) SoftDeletes() ColumnBuilder {
+	return b.Timestamp("deleted_at").Nullable()
+}
+
+func (b *blueprint) Index(columns ...string) IndexBuilder {
+	return &indexBuilder{index: b.addIndex(indexPlain, strings.Join(columns, "_")+"_index", columns)}
 }
 
-func (b *blueprint) UniqueIndex(columns ...string) {
-	indexName := strings.Join(columns, "_") + "_unique"
-	b.indexes = append(b.indexes, fmt.Sprintf("UNIQUE INDEX %s (%s)", indexName, strings.Join(columns, ", ")))
+func (b *blueprint) UniqueIndex(columns ...string) IndexBuilder {
+	return &indexBuilder{index: b.addIndex(indexUnique, strings.Join(columns, "_")+"_unique", columns)}
 }
 
 func (b *blueprint) Primary(columns ...string) {
-	b.indexes = append(b.indexes, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(columns, ", ")))
+	b.addIndex(indexPrimary, b.tableName+"_pkey", columns)
 }
 
 func (b *blueprint) FullTextIndex(columns ...string) {
-	indexName := strings.Join(columns, "_") + "_fulltext"
-	b.indexes = append(b.indexes, fmt.Sprintf("FULLTEXT INDEX %s (%s)", indexName, strings.Join(columns, ", ")))
+	b.addIndex(indexFullText, strings.Join(columns, "_")+"_fulltext", columns)
+}
+
+func (b *blueprint) addIndex(kind, name string, columns []string) *index {
+	index := &index{kind: kind, name: name, columns: columns}
+	b.indexes = append(b.indexes, index)
+	return index
+}
+
+func (b *blueprint) DropColumn(name string) {
+	b.drops = append(b.drops, name)
+}
+
+func (b *blueprint) RenameColumn(from, to string) {
+	b.renames = append(b.renames, rename{from: from, to: to})
+}
+
+func (b *blueprint) DropIndex(name string) {
+	b.droppedIndexes = append(b.droppedIndexes, name)
+}
+
+func (b *blueprint) RenameIndex(from, to string) {
+	b.renamedIndexes = append(b.renamedIndexes, rename{from: from, to: to})
+}
+
+// DropUnique drops the index UniqueIndex creates for the same columns
+func (b *blueprint) DropUnique(columns ...string) {
+	b.DropIndex(strings.Join(columns, "_") + "_unique")
+}
+
+func (b *blueprint) DropForeign(name string) {
+	b.droppedForeigns = append(b.droppedForeigns, name)
+}
+
+// Check adds a CHECK constraint such as "price >= 0" to the table
+func (b *blueprint) Check(expression string) {
+	b.addCheck(b.tableName+"_check", "", expression)
+}
+
+func (b *blueprint) Comment(text string) {
+	b.comment = text
+}
+
+// addCheck adds a CHECK constraint named name, numbering it when the name is taken
+func (b *blueprint) addCheck(name, column, expression string) {
+	unique := name
+	for n := 2; b.hasCheck(unique); n++ {
+		unique = fmt.Sprintf("%s%d", name, n)
+	}
+	b.checks = append(b.checks, &check{name: unique, column: column, expression: expression})
+}
+
+func (b *blueprint) hasCheck(name string) bool {
+	for _, c := range b.checks {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *blueprint) Foreign(column string) ForeignKeyBuilder {
-	return &foreignKeyBuilder{
-		localColumn: column,
-		blueprint:   b,
+	fk := &foreignKey{
+		name:   fmt.Sprintf("%s_%s_foreign", b.tableName, column),
+		column: column,
 	}
+	b.foreigns = append(b.foreigns, fk)
+	return &foreignKeyBuilder{foreignKey: fk}
+}
+
+// ForeignID adds an unsigned BIGINT column matching the ID() of another table,
+// chain Constrained to add the foreign key
+func (b *blueprint) ForeignID(column string) ForeignIDBuilder {
+	return &foreignIDBuilder{columnBuilder: b.BigInteger(column).Unsigned().(*columnBuilder)}
+}
+
+// completeForeigns returns the foreign keys that name both the referenced table and column
+func (b *blueprint) completeForeigns() []*foreignKey {
+	var foreigns []*foreignKey
+	for _, fk := range b.foreigns {
+		if fk.foreignTable != "" && fk.foreignColumn != "" {
+			foreigns = append(foreigns, fk)
+		}
+	}
+	return foreigns
 }
 
 type columnBuilder struct {
@@ -253,56 +647,176 @@ func (c *columnBuilder) Index() ColumnBuilder {
 	return c
 }
 
+// Change makes Table modify the existing column to this definition instead of adding it
+func (c *columnBuilder) Change() ColumnBuilder {
+	c.column.Change = true
+	return c
+}
+
+// Check adds a CHECK constraint on the column's values, named <table>_<column>_check
+func (c *columnBuilder) Check(expression string) ColumnBuilder {
+	c.blueprint.addCheck(fmt.Sprintf("%s_%s_check", c.blueprint.tableName, c.column.Name), c.column.Name, expression)
+	return c
+}
+
+// Unsigned restricts a MySQL numeric column to non-negative values. PostgreSQL
+// has no unsigned types, so it is ignored there.
+func (c *columnBuilder) Unsigned() ColumnBuilder {
+	c.column.Unsigned = true
+	return c
+}
+
+// AutoIncrement numbers the column from a sequence, as an identity column on PostgreSQL
+func (c *columnBuilder) AutoIncrement() ColumnBuilder {
+	c.column.AutoIncrement = true
+	return c
+}
+
+// Charset sets the character set of a MySQL string column. PostgreSQL uses
+// the database encoding for every column, so it is ignored there.
+func (c *columnBuilder) Charset(charset string) ColumnBuilder {
+	c.column.Charset = charset
+	return c
+}
+
+// Collation sets the collation of a string column, e.g. utf8mb4_bin on MySQL or "C" on PostgreSQL
+func (c *columnBuilder) Collation(collation string) ColumnBuilder {
+	c.column.Collation = collation
+	return c
+}
+
+// StoredAs makes the column a generated column whose value is computed from
+// expression on write and stored
+func (c *columnBuilder) StoredAs(expression string) ColumnBuilder {
+	c.column.Generated = expression
+	c.column.Stored = true
+	return c
+}
+
+// VirtualAs makes the column a generated column whose value is computed from
+// expression on read. PostgreSQL only has stored generated columns, so there
+// it is the same as StoredAs.
+func (c *columnBuilder) VirtualAs(expression string) ColumnBuilder {
+	c.column.Generated = expression
+	c.column.Stored = false
+	return c
+}
+
+type indexBuilder struct {
+	index *index
+}
+
+func (i *indexBuilder) Using(algorithm string) IndexBuilder {
+	i.index.algorithm = strings.ToUpper(algorithm)
+	return i
+}
+
+func (i *indexBuilder) Name(name string) IndexBuilder {
+	i.index.name = name
+	return i
+}
+
+type foreignIDBuilder struct {
+	*columnBuilder
+	foreignKey *foreignKey
+	// onDelete is the action set before Constrained creates the key
+	onDelete string
+}
+
+func (f *foreignIDBuilder) Constrained(table ...string) ForeignIDBuilder {
+	foreignTable := ""
+	if len(table) > 0 {
+		foreignTable = table[0]
+	} else {
+		foreignTable = pluralize(strings.TrimSuffix(f.column.Name, "_id"))
+	}
+	f.blueprint.Foreign(f.column.Name).References("id").On(foreignTable)
+	f.foreignKey = f.blueprint.foreigns[len(f.blueprint.foreigns)-1]
+	f.foreignKey.onDelete = f.onDelete
+	return f
+}
+
+// CascadeOnDelete deletes the row when the referenced one is, before or after Constrained
+func (f *foreignIDBuilder) CascadeOnDelete() ForeignIDBuilder {
+	f.setOnDelete("CASCADE")
+	return f
+}
+
+// NullOnDelete makes the column nullable and clears it when the referenced row
+// is deleted, before or after Constrained
+func (f *foreignIDBuilder) NullOnDelete() ForeignIDBuilder {
+	f.column.Nullable = true
+	f.setOnDelete("SET NULL")
+	return f
+}
+
+func (f *foreignIDBuilder) setOnDelete(action string) {
+	f.onDelete = action
+	if f.foreignKey != nil {
+		f.foreignKey.onDelete = action
+	}
+}
+
+// pluralize returns the English plural of a table name for the common cases
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "y") && !strings.HasSuffix(name, "ay") && !strings.HasSuffix(name, "ey") && !strings.HasSuffix(name, "oy"):
+		return strings.TrimSuffix(name, "y") + "ies"
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
+
 type foreignKeyBuilder struct {
-	localColumn    string
-	foreignTable   string
-	foreignColumn  string
-	onDelete       string
-	onUpdate       string
-	blueprint      *blueprint
+	foreignKey *foreignKey
 }
 
 func (f *foreignKeyBuilder) References(column string) ForeignKeyBuilder {
-	f.foreignColumn = column
+	f.foreignKey.foreignColumn = column
 	return f
 }
 
-
 func (f *foreignKeyBuilder) OnDelete(action string) ForeignKeyBuilder {
-	f.onDelete = action
-	f.build()
+	f.foreignKey.onDelete = action
 	return f
 }
 
 func (f *foreignKeyBuilder) OnUpdate(action string) ForeignKeyBuilder {
-	f.onUpdate = action
-	f.build()
+	f.foreignKey.onUpdate = action
 	return f
 }
 
 func (f *foreignKeyBuilder) On(table string) ForeignKeyBuilder {
-	f.foreignTable = table
-	f.build()
+	f.foreignKey.foreignTable = table
 	return f
 }
 
-func (f *foreignKeyBuilder) build() {
-	if f.foreignTable == "" || f.foreignColumn == "" {
-		return
+// clause returns the CONSTRAINT ... CHECK clause shared by both dialects
+func (c *check) clause(quote func(string) string) string {
+	return fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quote(c.name), c.expression)
+}
+
+// clause returns the FOREIGN KEY ... REFERENCES ... clause shared by both
+// dialects, table quotes the referenced table
+func (fk *foreignKey) clause(quote, table func(string) string) string {
+	parts := []string{
+		fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s)", quote(fk.name), quote(fk.column)),
+		fmt.Sprintf("REFERENCES %s (%s)", table(fk.foreignTable), quote(fk.foreignColumn)),
 	}
-	
-	var parts []string
-	parts = append(parts, fmt.Sprintf("FOREIGN KEY (%s)", f.localColumn))
-	parts = append(parts, fmt.Sprintf("REFERENCES %s (%s)", f.foreignTable, f.foreignColumn))
-	
-	if f.onDelete != "" {
-		parts = append(parts, fmt.Sprintf("ON DELETE %s", f.onDelete))
+
+	if fk.onDelete != "" {
+		parts = append(parts, fmt.Sprintf("ON DELETE %s", fk.onDelete))
 	}
-	
-	if f.onUpdate != "" {
-		parts = append(parts, fmt.Sprintf("ON UPDATE %s", f.onUpdate))
+
+	if fk.onUpdate != "" {
+		parts = append(parts, fmt.Sprintf("ON UPDATE %s", fk.onUpdate))
 	}
-	
-	foreignSQL := strings.Join(parts, " ")
-	f.blueprint.foreigns = append(f.blueprint.foreigns, foreignSQL)
-}
\ No newline at end of file
+
+	return strings.Join(parts, " ")
+}
+
+// quoteString returns s as a single quoted SQL string literal
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
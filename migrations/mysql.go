@@ -1,65 +1,359 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+
+	"github.com/go-bold/bold/lock"
 )
 
-type mysqlProvider struct{}
+type mysqlProvider struct {
+	transaction bool
+	// mariadb adjusts the statements MariaDB handles differently from MySQL
+	mariadb bool
+	// tidb and vitess reject the statements TiDB and Vitess do not accept
+	tidb   bool
+	vitess bool
+	// skipForeignKeys leaves foreign keys out of the statements
+	skipForeignKeys bool
+	// autoRandom declares ID() keys AUTO_RANDOM instead of AUTO_INCREMENT
+	autoRandom bool
+}
 
 type mysqlBlueprint struct {
 	*blueprint
+	provider  *mysqlProvider
+	engine    string
+	charset   string
+	collation string
+}
+
+// DefaultEngine, DefaultCharset and DefaultCollation are the options of the
+// tables MySQL creates, for blueprints that do not set their own. Empty ones
+// are left to the server defaults.
+var (
+	DefaultEngine    = "InnoDB"
+	DefaultCharset   = "utf8mb4"
+	DefaultCollation = "utf8mb4_unicode_ci"
+)
+
+func (m *mysqlProvider) newBlueprint(tableName string) *mysqlBlueprint {
+	return &mysqlBlueprint{blueprint: newBlueprint(tableName), provider: m}
+}
+
+// WithTransaction returns a copy of the provider running the statements of
+// each Create, Table and Rollback in a transaction. MySQL commits DDL
+// implicitly, so this is off by default and only groups the statements.
+func (m *mysqlProvider) WithTransaction(enabled bool) *mysqlProvider {
+	c := *m
+	c.transaction = enabled
+	return &c
+}
+
+// WithForeignKeys returns a copy of the provider emitting foreign keys or
+// leaving them out, for Vitess and other sharded setups that cannot enforce
+// them. Vitess rejects blueprints with foreign keys unless they are left out.
+func (m *mysqlProvider) WithForeignKeys(enabled bool) *mysqlProvider {
+	c := *m
+	c.skipForeignKeys = !enabled
+	return &c
+}
+
+// WithAutoRandom returns a copy of the provider declaring ID() keys
+// AUTO_RANDOM, which TiDB spreads across regions instead of writing every
+// insert to the same one. Other dialects reject blueprints using ID() then.
+func (m *mysqlProvider) WithAutoRandom(enabled bool) *mysqlProvider {
+	c := *m
+	c.autoRandom = enabled
+	return &c
+}
+
+func (m *mysqlProvider) Create(db DB, tableName string, callback func(MySQLBlueprint)) error {
+	return m.CreateContext(context.Background(), db, tableName, callback)
+}
+
+func (m *mysqlProvider) CreateContext(ctx context.Context, db DB, tableName string, callback func(MySQLBlueprint)) error {
+	sqls, err := m.CreateSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return m.exec(ctx, db, sqls)
+}
+
+func (m *mysqlProvider) Table(db DB, tableName string, callback func(MySQLBlueprint)) error {
+	return m.TableContext(context.Background(), db, tableName, callback)
+}
+
+func (m *mysqlProvider) TableContext(ctx context.Context, db DB, tableName string, callback func(MySQLBlueprint)) error {
+	sqls, err := m.TableSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return m.exec(ctx, db, sqls)
+}
+
+// Rollback reverts what Table applies for the same callback, dropping the
+// added foreign keys, indexes and columns in reverse order. Use Drop to revert Create.
+func (m *mysqlProvider) Rollback(db DB, tableName string, callback func(MySQLBlueprint)) error {
+	return m.RollbackContext(context.Background(), db, tableName, callback)
 }
 
-func (m *mysqlProvider) Create(db *sql.DB, tableName string, callback func(MySQLBlueprint)) error {
-	bp := &mysqlBlueprint{newBlueprint(tableName, db)}
+func (m *mysqlProvider) RollbackContext(ctx context.Context, db DB, tableName string, callback func(MySQLBlueprint)) error {
+	sqls, err := m.RollbackSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return m.exec(ctx, db, sqls)
+}
+
+// CreateSQL returns the statements Create would execute, without touching the
+// database, or an error when the blueprint uses what the dialect does not support
+func (m *mysqlProvider) CreateSQL(tableName string, callback func(MySQLBlueprint)) ([]string, error) {
+	bp := m.newBlueprint(tableName)
 	callback(bp)
-	
-	sql := bp.toCreateSQL()
-	_, err := db.Exec(sql)
-	return err
+	if err := errors.Join(bp.verifyCreate(), bp.validate()); err != nil {
+		return nil, err
+	}
+	return []string{bp.toCreateSQL()}, nil
 }
 
-func (m *mysqlProvider) Table(db *sql.DB, tableName string, callback func(MySQLBlueprint)) error {
-	bp := &mysqlBlueprint{newBlueprint(tableName, db)}
+// TableSQL returns the statements Table would execute, without touching the database
+func (m *mysqlProvider) TableSQL(tableName string, callback func(MySQLBlueprint)) ([]string, error) {
+	bp := m.newBlueprint(tableName)
 	callback(bp)
-	
+	if err := errors.Join(bp.verify(), bp.validate(), bp.validateAlter()); err != nil {
+		return nil, err
+	}
 	sqls := bp.toAlterSQL()
-	for _, sql := range sqls {
-		if _, err := db.Exec(sql); err != nil {
-			return err
-		}
+	if err := bp.verifyAlter(sqls); err != nil {
+		return nil, err
 	}
-	return nil
+	return sqls, nil
 }
 
-func (m *mysqlProvider) Drop(db *sql.DB, tableName string) error {
+// RollbackSQL returns the statements Rollback would execute, without touching the database
+func (m *mysqlProvider) RollbackSQL(tableName string, callback func(MySQLBlueprint)) ([]string, error) {
+	bp := m.newBlueprint(tableName)
+	callback(bp)
+	if err := errors.Join(bp.verify(), bp.validate(), bp.validateAlter()); err != nil {
+		return nil, err
+	}
+	return bp.toRollbackSQL(), nil
+}
+
+func (m *mysqlProvider) exec(ctx context.Context, db DB, sqls []string) error {
+	return execStatements(ctx, db, sqls, m.transaction)
+}
+
+// copySQL is empty, AUTO_INCREMENT taking explicit ids and continuing after them
+func (m *mysqlProvider) copySQL(tableName string, columns []ColumnInfo) (before, after []string) {
+	return nil, nil
+}
+
+func (m *mysqlProvider) Drop(db DB, tableName string) error {
+	return m.DropContext(context.Background(), db, tableName)
+}
+
+func (m *mysqlProvider) DropContext(ctx context.Context, db DB, tableName string) error {
 	sql := fmt.Sprintf("DROP TABLE `%s`", tableName)
-	_, err := db.Exec(sql)
+	_, err := db.ExecContext(ctx, sql)
 	return err
 }
 
-func (m *mysqlProvider) DropIfExists(db *sql.DB, tableName string) error {
+func (m *mysqlProvider) DropIfExists(db DB, tableName string) error {
+	return m.DropIfExistsContext(context.Background(), db, tableName)
+}
+
+func (m *mysqlProvider) DropIfExistsContext(ctx context.Context, db DB, tableName string) error {
 	sql := fmt.Sprintf("DROP TABLE IF EXISTS `%s`", tableName)
-	_, err := db.Exec(sql)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateView creates a view selecting query, such as "SELECT ... FROM orders GROUP BY ..."
+func (m *mysqlProvider) CreateView(db DB, name, query string) error {
+	return m.CreateViewContext(context.Background(), db, name, query)
+}
+
+func (m *mysqlProvider) CreateViewContext(ctx context.Context, db DB, name, query string) error {
+	sql := fmt.Sprintf("CREATE VIEW `%s` AS %s", name, query)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (m *mysqlProvider) DropView(db DB, name string) error {
+	return m.DropViewContext(context.Background(), db, name)
+}
+
+func (m *mysqlProvider) DropViewContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP VIEW `%s`", name)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (m *mysqlProvider) Rename(db DB, from, to string) error {
+	return m.RenameContext(context.Background(), db, from, to)
+}
+
+func (m *mysqlProvider) RenameContext(ctx context.Context, db DB, from, to string) error {
+	sql := fmt.Sprintf("RENAME TABLE `%s` TO `%s`", from, to)
+	_, err := db.ExecContext(ctx, sql)
 	return err
 }
 
-func (m *mysqlProvider) HasTable(db *sql.DB, tableName string) (bool, error) {
+func (m *mysqlProvider) HasTable(db DB, tableName string) (bool, error) {
+	return m.HasTableContext(context.Background(), db, tableName)
+}
+
+func (m *mysqlProvider) HasTableContext(ctx context.Context, db DB, tableName string) (bool, error) {
 	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
 	var count int
-	err := db.QueryRow(query, tableName).Scan(&count)
+	err := db.QueryRowContext(ctx, query, tableName).Scan(&count)
 	return count > 0, err
 }
 
-func (m *mysqlProvider) HasColumn(db *sql.DB, tableName, columnName string) (bool, error) {
+func (m *mysqlProvider) HasColumn(db DB, tableName, columnName string) (bool, error) {
+	return m.HasColumnContext(context.Background(), db, tableName, columnName)
+}
+
+func (m *mysqlProvider) HasColumnContext(ctx context.Context, db DB, tableName, columnName string) (bool, error) {
 	query := "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?"
 	var count int
-	err := db.QueryRow(query, tableName, columnName).Scan(&count)
+	err := db.QueryRowContext(ctx, query, tableName, columnName).Scan(&count)
 	return count > 0, err
 }
 
+// Columns returns the columns of a table in table order
+func (m *mysqlProvider) Columns(db DB, tableName string) ([]ColumnInfo, error) {
+	return m.ColumnsContext(context.Background(), db, tableName)
+}
+
+func (m *mysqlProvider) ColumnsContext(ctx context.Context, db DB, tableName string) ([]ColumnInfo, error) {
+	// MariaDB reports a missing default as the string NULL, and string
+	// defaults as quoted literals
+	defaultValue := "column_default"
+	if m.mariadb {
+		defaultValue = "NULLIF(column_default, 'NULL')"
+	}
+	query := `SELECT column_name, column_type, is_nullable = 'YES', ` + defaultValue + `, column_key = 'PRI', extra LIKE '%auto_increment%'
+		FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position`
+	return queryColumns(ctx, db, query, tableName)
+}
+
+// Indexes returns the indexes of a table in name order, including the primary key
+func (m *mysqlProvider) Indexes(db DB, tableName string) ([]IndexInfo, error) {
+	return m.IndexesContext(context.Background(), db, tableName)
+}
+
+func (m *mysqlProvider) IndexesContext(ctx context.Context, db DB, tableName string) ([]IndexInfo, error) {
+	query := `SELECT index_name, column_name, non_unique = 0, index_name = 'PRIMARY', index_type, NULL
+		FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? ORDER BY index_name, seq_in_index`
+	return queryIndexes(ctx, db, query, tableName)
+}
+
+func (m *mysqlProvider) Truncate(db DB, tableName string) error {
+	return m.TruncateContext(context.Background(), db, tableName)
+}
+
+func (m *mysqlProvider) TruncateContext(ctx context.Context, db DB, tableName string) error {
+	sql := fmt.Sprintf("TRUNCATE TABLE `%s`", tableName)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// Tables lists the tables of the current database
+func (m *mysqlProvider) Tables(db DB) ([]string, error) {
+	return m.TablesContext(context.Background(), db)
+}
+
+func (m *mysqlProvider) TablesContext(ctx context.Context, db DB) ([]string, error) {
+	return queryStrings(ctx, db, "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name")
+}
+
+// DropAllTables drops every table of the current database, including the migrations table
+func (m *mysqlProvider) DropAllTables(db DB) error {
+	return m.DropAllTablesContext(context.Background(), db)
+}
+
+func (m *mysqlProvider) DropAllTablesContext(ctx context.Context, db DB) error {
+	tables, err := m.TablesContext(ctx, db)
+	if err != nil || len(tables) == 0 {
+		return err
+	}
+
+	return m.withoutForeignKeyChecks(ctx, db, func(conn DB) error {
+		quoted := make([]string, len(tables))
+		for i, table := range tables {
+			quoted[i] = mysqlQuote(table)
+		}
+		_, err := conn.ExecContext(ctx, "DROP TABLE "+strings.Join(quoted, ", "))
+		return err
+	})
+}
+
+// TruncateAll empties every table of the current database but the except ones
+func (m *mysqlProvider) TruncateAll(db DB, except ...string) error {
+	return m.TruncateAllContext(context.Background(), db, except...)
+}
+
+func (m *mysqlProvider) TruncateAllContext(ctx context.Context, db DB, except ...string) error {
+	tables, err := m.TablesContext(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	return m.withoutForeignKeyChecks(ctx, db, func(conn DB) error {
+		for _, table := range tables {
+			if slices.Contains(except, table) {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE `%s`", table)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// withoutForeignKeyChecks runs fn on a dedicated connection, as foreign key
+// checks are disabled per session. A *sql.Conn or *sql.Tx is one already.
+func (m *mysqlProvider) withoutForeignKeyChecks(ctx context.Context, db DB, fn func(conn DB) error) error {
+	conn := db
+	if pool, ok := db.(interface {
+		Conn(ctx context.Context) (*sql.Conn, error)
+	}); ok {
+		c, err := pool.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		conn = c
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SET FOREIGN_KEY_CHECKS = 1")
+
+	return fn(conn)
+}
+
+func (bp *mysqlBlueprint) Engine(engine string) {
+	bp.engine = engine
+}
+
+func (bp *mysqlBlueprint) Charset(charset string) {
+	bp.charset = charset
+}
+
+func (bp *mysqlBlueprint) Collation(collation string) {
+	bp.collation = collation
+}
+
 func (bp *mysqlBlueprint) Enum(name string, values []string) ColumnBuilder {
 	quotedValues := make([]string, len(values))
 	for i, v := range values {
@@ -78,6 +372,11 @@ func (bp *mysqlBlueprint) Set(name string, values []string) ColumnBuilder {
 	return bp.AddColumn(name, setType)
 }
 
+// Binary adds a BLOB column, MySQL's BINARY holding a single byte without a length
+func (bp *mysqlBlueprint) Binary(name string) ColumnBuilder {
+	return bp.AddColumn(name, "BLOB")
+}
+
 func (bp *mysqlBlueprint) Point(name string) ColumnBuilder {
 	return bp.AddColumn(name, "POINT")
 }
@@ -86,65 +385,368 @@ func (bp *mysqlBlueprint) Geometry(name string) ColumnBuilder {
 	return bp.AddColumn(name, "GEOMETRY")
 }
 
+// SpatialIndex adds an R-tree index over geometry columns, which must be NOT NULL
+func (bp *mysqlBlueprint) SpatialIndex(columns ...string) {
+	bp.addIndex(indexSpatial, strings.Join(columns, "_")+"_spatial", columns)
+}
+
 func (bp *mysqlBlueprint) toCreateSQL() string {
 	var parts []string
-	
+
 	for _, column := range bp.columns {
-		columnSQL := fmt.Sprintf("`%s` %s", column.Name, column.Type)
-		
-		if !column.Nullable {
-			columnSQL += " NOT NULL"
-		}
-		
-		if column.Default != nil {
-			columnSQL += fmt.Sprintf(" DEFAULT %v", column.Default)
-		}
-		
-		if column.Comment != "" {
-			columnSQL += fmt.Sprintf(" COMMENT '%s'", column.Comment)
-		}
-		
-		parts = append(parts, columnSQL)
+		parts = append(parts, bp.columnSQL(column))
 	}
-	
+
 	for _, index := range bp.indexes {
-		parts = append(parts, index)
+		parts = append(parts, bp.indexSQL(index))
+	}
+
+	for _, foreign := range bp.foreignKeys() {
+		parts = append(parts, foreign.clause(mysqlQuote, mysqlQuote))
+	}
+
+	for _, check := range bp.checks {
+		parts = append(parts, check.clause(mysqlQuote))
+	}
+
+	engine, charset, collation := DefaultEngine, DefaultCharset, DefaultCollation
+	if bp.engine != "" {
+		engine = bp.engine
+	}
+	if bp.charset != "" {
+		// the default collation most likely belongs to another character set
+		charset, collation = bp.charset, ""
+	}
+	if bp.collation != "" {
+		collation = bp.collation
+	}
+
+	sql := fmt.Sprintf("%s `%s` (\n  %s\n)", bp.createTable(), bp.tableName, strings.Join(parts, ",\n  "))
+	if options := bp.tableOptions(engine, charset, collation); options != "" {
+		sql += " " + options
 	}
-	
-	for _, foreign := range bp.foreigns {
-		parts = append(parts, foreign)
+	if partitions := bp.toPartitionSQL(); partitions != "" {
+		sql += "\n" + partitions
 	}
-	
-	return fmt.Sprintf("CREATE TABLE `%s` (\n  %s\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci",
-		bp.tableName, strings.Join(parts, ",\n  "))
+	return sql
 }
 
 func (bp *mysqlBlueprint) toAlterSQL() []string {
 	var sqls []string
-	
+
+	for _, name := range bp.droppedForeignKeys() {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `%s`", bp.tableName, name))
+	}
+
+	for _, name := range bp.droppedIndexes {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP INDEX `%s`", bp.tableName, name))
+	}
+
+	for _, rename := range bp.renamedIndexes {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` RENAME INDEX `%s` TO `%s`", bp.tableName, rename.from, rename.to))
+	}
+
+	for _, rename := range bp.renames {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` RENAME COLUMN `%s` TO `%s`", bp.tableName, rename.from, rename.to))
+	}
+
+	for _, name := range bp.drops {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`", bp.tableName, name))
+	}
+
 	for _, column := range bp.columns {
-		columnSQL := fmt.Sprintf("`%s` %s", column.Name, column.Type)
-		
-		if !column.Nullable {
-			columnSQL += " NOT NULL"
-		}
-		
-		if column.Default != nil {
-			columnSQL += fmt.Sprintf(" DEFAULT %v", column.Default)
-		}
-		
+		columnSQL := bp.columnSQL(column)
+
 		if column.After != "" {
 			columnSQL += fmt.Sprintf(" AFTER `%s`", column.After)
 		}
-		
-		sql := fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN %s", bp.tableName, columnSQL)
-		sqls = append(sqls, sql)
+
+		action := bp.addColumn()
+		if column.Change {
+			action = "MODIFY COLUMN"
+		}
+
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` %s %s", bp.tableName, action, columnSQL))
 	}
-	
+
 	for _, index := range bp.indexes {
-		sql := fmt.Sprintf("ALTER TABLE `%s` ADD %s", bp.tableName, index)
-		sqls = append(sqls, sql)
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` %s", bp.tableName, bp.addIndexSQL(index)))
+	}
+
+	for _, foreign := range bp.foreignKeys() {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` ADD %s", bp.tableName, foreign.clause(mysqlQuote, mysqlQuote)))
+	}
+
+	for _, check := range bp.checks {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` ADD %s", bp.tableName, check.clause(mysqlQuote)))
+	}
+
+	if options := bp.tableOptions(bp.engine, bp.charset, bp.collation); options != "" {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` %s", bp.tableName, options))
+	}
+
+	return append(sqls, bp.toAlterPartitionSQL()...)
+}
+
+// tableOptions returns the ENGINE, DEFAULT CHARSET, COLLATE and COMMENT
+// options of the table, leaving out the empty ones
+func (bp *mysqlBlueprint) tableOptions(engine, charset, collation string) string {
+	var options []string
+	if engine != "" {
+		options = append(options, "ENGINE="+engine)
+	}
+	if charset != "" {
+		options = append(options, "DEFAULT CHARSET="+charset)
+	}
+	if collation != "" {
+		options = append(options, "COLLATE="+collation)
+	}
+	if bp.comment != "" {
+		options = append(options, "COMMENT="+quoteString(bp.comment))
+	}
+	return strings.Join(options, " ")
+}
+
+// dropCheck returns the clause dropping a check constraint, MariaDB has no DROP CHECK
+func (bp *mysqlBlueprint) dropCheck() string {
+	if bp.provider.mariadb {
+		return "DROP CONSTRAINT"
+	}
+	return "DROP CHECK"
+}
+
+// toRollbackSQL reverses toAlterSQL: check constraints, foreign keys, then indexes, then added columns are
+// dropped and renamed columns and indexes get their old name back. Dropped columns, indexes and
+// foreign keys and changed columns cannot be restored as their previous definition is unknown.
+func (bp *mysqlBlueprint) toRollbackSQL() []string {
+	sqls := bp.toRollbackPartitionSQL()
+
+	for i := len(bp.checks) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` %s `%s`", bp.tableName, bp.dropCheck(), bp.checks[i].name))
+	}
+
+	foreigns := bp.foreignKeys()
+	for i := len(foreigns) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `%s`", bp.tableName, foreigns[i].name))
+	}
+
+	for i := len(bp.indexes) - 1; i >= 0; i-- {
+		index := bp.indexes[i]
+		if index.kind == indexPrimary {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP PRIMARY KEY", bp.tableName))
+			continue
+		}
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP INDEX `%s`", bp.tableName, index.name))
+	}
+
+	for i := len(bp.columns) - 1; i >= 0; i-- {
+		if bp.columns[i].Change {
+			continue
+		}
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`", bp.tableName, bp.columns[i].Name))
+	}
+
+	for i := len(bp.renames) - 1; i >= 0; i-- {
+		rename := bp.renames[i]
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` RENAME COLUMN `%s` TO `%s`", bp.tableName, rename.to, rename.from))
 	}
-	
+
+	for i := len(bp.renamedIndexes) - 1; i >= 0; i-- {
+		rename := bp.renamedIndexes[i]
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` RENAME INDEX `%s` TO `%s`", bp.tableName, rename.to, rename.from))
+	}
+
 	return sqls
-}
\ No newline at end of file
+}
+
+// foreignKeys returns the foreign keys to add, none when they are left out
+func (bp *mysqlBlueprint) foreignKeys() []*foreignKey {
+	if bp.provider.skipForeignKeys {
+		return nil
+	}
+	return bp.completeForeigns()
+}
+
+// droppedForeignKeys returns the foreign keys to drop, none when they are left out
+func (bp *mysqlBlueprint) droppedForeignKeys() []string {
+	if bp.provider.skipForeignKeys {
+		return nil
+	}
+	return bp.droppedForeigns
+}
+
+// autoRandom reports whether column is an ID() or auto incrementing primary
+// key to declare AUTO_RANDOM
+func (bp *mysqlBlueprint) autoRandom(column *Column) bool {
+	if !bp.provider.autoRandom {
+		return false
+	}
+	return column.AutoIncrement && column.Primary ||
+		strings.Contains(column.Type, "AUTO_INCREMENT") && strings.Contains(column.Type, "PRIMARY KEY")
+}
+
+func (bp *mysqlBlueprint) columnSQL(column *Column) string {
+	columnType := column.Type
+	if bp.autoRandom(column) {
+		columnType = strings.Replace(columnType, "AUTO_INCREMENT", "AUTO_RANDOM", 1)
+	}
+	columnSQL := fmt.Sprintf("`%s` %s", column.Name, columnType)
+
+	if column.Unsigned {
+		columnSQL += " UNSIGNED"
+	}
+
+	if column.Charset != "" {
+		columnSQL += " CHARACTER SET " + column.Charset
+	}
+
+	if column.Collation != "" {
+		columnSQL += " COLLATE " + column.Collation
+	}
+
+	if column.Generated != "" {
+		storage := "VIRTUAL"
+		if column.Stored {
+			storage = "STORED"
+		}
+		columnSQL += fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", column.Generated, storage)
+	}
+
+	if !column.Nullable {
+		columnSQL += " NOT NULL"
+	}
+
+	// generated columns cannot have a default
+	if column.Default != nil && column.Generated == "" {
+		columnSQL += " DEFAULT " + bp.formatDefaultValue(column.Default)
+	}
+
+	if column.AutoIncrement {
+		if bp.autoRandom(column) {
+			columnSQL += " AUTO_RANDOM"
+		} else {
+			columnSQL += " AUTO_INCREMENT"
+		}
+	}
+
+	if column.Unique {
+		columnSQL += " UNIQUE"
+	}
+
+	if column.Primary {
+		columnSQL += " PRIMARY KEY"
+	}
+
+	if column.Comment != "" {
+		columnSQL += fmt.Sprintf(" COMMENT '%s'", column.Comment)
+	}
+
+	return columnSQL
+}
+
+func (bp *mysqlBlueprint) indexSQL(index *index) string {
+	columns := strings.Join(index.columns, ", ")
+
+	using := ""
+	if index.algorithm != "" {
+		using = " USING " + index.algorithm
+	}
+
+	switch index.kind {
+	case indexUnique:
+		return fmt.Sprintf("UNIQUE INDEX %s%s (%s)", index.name, using, columns)
+	case indexPrimary:
+		return fmt.Sprintf("PRIMARY KEY (%s)", columns)
+	case indexFullText:
+		return fmt.Sprintf("FULLTEXT INDEX %s (%s)", index.name, columns)
+	case indexSpatial:
+		return fmt.Sprintf("SPATIAL INDEX %s (%s)", index.name, columns)
+	default:
+		return fmt.Sprintf("INDEX %s%s (%s)", index.name, using, columns)
+	}
+}
+
+func (bp *mysqlBlueprint) formatDefaultValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return quoteString(v)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func mysqlQuote(name string) string {
+	return "`" + name + "`"
+}
+
+func (m *mysqlProvider) placeholder(n int) string {
+	return "?"
+}
+
+// maxParams is the most placeholders a prepared statement can hold
+func (m *mysqlProvider) maxParams() int {
+	return 65535
+}
+
+// maxInsertRows is zero, the rows of an INSERT being only limited by maxParams
+func (m *mysqlProvider) maxInsertRows() int {
+	return 0
+}
+
+func (m *mysqlProvider) qualifiedTable(tableName string) string {
+	return tableName
+}
+
+func (m *mysqlProvider) quoteTable(tableName string) string {
+	return mysqlQuote(tableName)
+}
+
+func (m *mysqlProvider) quote(name string) string {
+	return mysqlQuote(name)
+}
+
+func (m *mysqlProvider) createMigrationsTable(ctx context.Context, db DB, tableName string) error {
+	return m.CreateContext(ctx, db, tableName, func(table MySQLBlueprint) {
+		table.ID()
+		table.String("migration", 255)
+		table.Integer("batch")
+		table.Timestamp("applied_at")
+	})
+}
+
+// locker returns the GET_LOCK lock Runner holds while migrating
+func (m *mysqlProvider) locker(db lock.Pool) lock.Locker {
+	return lock.MySQL(db)
+}
+
+func (m *mysqlProvider) describe(tableName string, callback func(Blueprint)) *blueprint {
+	bp := m.newBlueprint(tableName)
+	callback(bp)
+	if bp.engine != "" {
+		bp.options = append(bp.options, "Engine")
+	}
+	if bp.charset != "" {
+		bp.options = append(bp.options, "Charset")
+	}
+	if bp.collation != "" {
+		bp.options = append(bp.options, "Collation")
+	}
+	return bp.blueprint
+}
+
+func (m *mysqlProvider) createSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return m.CreateSQL(tableName, func(bp MySQLBlueprint) { callback(bp) })
+}
+
+func (m *mysqlProvider) tableSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return m.TableSQL(tableName, func(bp MySQLBlueprint) { callback(bp) })
+}
+
+func (m *mysqlProvider) rollbackSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return m.RollbackSQL(tableName, func(bp MySQLBlueprint) { callback(bp) })
+}
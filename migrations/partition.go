@@ -0,0 +1,216 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	partitionRange = "RANGE"
+	partitionList  = "LIST"
+	partitionHash  = "HASH"
+)
+
+// PartitionBuilder sets the rows a partition declared with Partition holds.
+// Bounds and values are SQL literals, such as "'2025-01-01'" or "MAXVALUE".
+type PartitionBuilder interface {
+	// LessThan holds the rows of a range partition below bound
+	LessThan(bound string) PartitionBuilder
+	// From sets the lower bound of a range partition on PostgreSQL, by
+	// default the bound of the partition declared before or MINVALUE for the
+	// first. MySQL ranges always start at the bound before.
+	From(bound string) PartitionBuilder
+	// In holds the rows of a list partition with one of values
+	In(values ...string) PartitionBuilder
+}
+
+type partitioning struct {
+	method  string
+	columns []string
+	// count is the number of hash partitions
+	count      int
+	partitions []*partition
+}
+
+type partition struct {
+	name     string
+	from     string
+	lessThan string
+	in       []string
+}
+
+func (p *partition) LessThan(bound string) PartitionBuilder {
+	p.lessThan = bound
+	return p
+}
+
+func (p *partition) From(bound string) PartitionBuilder {
+	p.from = bound
+	return p
+}
+
+func (p *partition) In(values ...string) PartitionBuilder {
+	p.in = values
+	return p
+}
+
+// method returns the partitioning method the bounds of the partition belong to
+func (p *partition) method() string {
+	if p.in != nil {
+		return partitionList
+	}
+	return partitionRange
+}
+
+// PartitionByRange partitions a created table by ranges of columns, declared with Partition
+func (b *blueprint) PartitionByRange(columns ...string) {
+	b.partitioning.method, b.partitioning.columns = partitionRange, columns
+}
+
+// PartitionByList partitions a created table by the values of column, declared with Partition
+func (b *blueprint) PartitionByList(column string) {
+	b.partitioning.method, b.partitioning.columns = partitionList, []string{column}
+}
+
+// PartitionByHash spreads the rows of a created table over n partitions by
+// the hash of column. PostgreSQL partitions are named TABLE_p0 to TABLE_pN-1.
+func (b *blueprint) PartitionByHash(column string, n int) {
+	b.partitioning.method, b.partitioning.columns, b.partitioning.count = partitionHash, []string{column}, n
+}
+
+// Partition declares a range or list partition of the table, with the
+// partitioning of Create, or added by Table to a table partitioned before.
+// On PostgreSQL partitions are tables, named name.
+func (b *blueprint) Partition(name string) PartitionBuilder {
+	p := &partition{name: name}
+	b.partitioning.partitions = append(b.partitioning.partitions, p)
+	return p
+}
+
+// validatePartitions rejects partitions whose bounds do not match the partitioning method
+func (b *blueprint) validatePartitions() error {
+	var errs []error
+	method := b.partitionMethod()
+	if method == partitionHash && b.partitioning.count < 1 {
+		errs = append(errs, fmt.Errorf("migrations: %s: hash partitioning needs at least one partition", b.tableName))
+	}
+	for _, p := range b.partitioning.partitions {
+		switch {
+		case method == partitionHash:
+			errs = append(errs, fmt.Errorf("migrations: %s: partition %s: hash partitions are numbered by PartitionByHash", b.tableName, p.name))
+		case p.lessThan == "" && p.in == nil:
+			errs = append(errs, fmt.Errorf("migrations: %s: partition %s: set its bound with LessThan or In", b.tableName, p.name))
+		case p.lessThan != "" && p.in != nil:
+			errs = append(errs, fmt.Errorf("migrations: %s: partition %s: use either LessThan or In", b.tableName, p.name))
+		case method != "" && p.method() != method:
+			errs = append(errs, fmt.Errorf("migrations: %s: partition %s: the table is partitioned by %s", b.tableName, p.name, strings.ToLower(method)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// partitionMethod returns the method of the partitioning or, when Table adds
+// partitions only, the method their bounds belong to
+func (b *blueprint) partitionMethod() string {
+	if b.partitioning.method != "" || len(b.partitioning.partitions) == 0 {
+		return b.partitioning.method
+	}
+	return b.partitioning.partitions[0].method()
+}
+
+// toPartitionSQL returns the PARTITION BY clause of a created or altered table, "" when not partitioned
+func (bp *mysqlBlueprint) toPartitionSQL() string {
+	p := bp.partitioning
+	columns := make([]string, len(p.columns))
+	for i, column := range p.columns {
+		columns[i] = mysqlQuote(column)
+	}
+
+	switch p.method {
+	case partitionHash:
+		return fmt.Sprintf("PARTITION BY HASH (%s) PARTITIONS %d", strings.Join(columns, ", "), p.count)
+	case partitionRange, partitionList:
+		// COLUMNS partitioning takes dates and strings, not only integers
+		return fmt.Sprintf("PARTITION BY %s COLUMNS(%s) (\n  %s\n)", p.method, strings.Join(columns, ", "), bp.partitionList())
+	}
+	return ""
+}
+
+func (bp *mysqlBlueprint) partitionList() string {
+	definitions := make([]string, len(bp.partitioning.partitions))
+	for i, p := range bp.partitioning.partitions {
+		if p.method() == partitionList {
+			definitions[i] = fmt.Sprintf("PARTITION `%s` VALUES IN (%s)", p.name, strings.Join(p.in, ", "))
+		} else {
+			definitions[i] = fmt.Sprintf("PARTITION `%s` VALUES LESS THAN (%s)", p.name, p.lessThan)
+		}
+	}
+	return strings.Join(definitions, ",\n  ")
+}
+
+// toAlterPartitionSQL partitions an existing table, or adds the declared partitions to it
+func (bp *mysqlBlueprint) toAlterPartitionSQL() []string {
+	if bp.partitioning.method != "" {
+		return []string{fmt.Sprintf("ALTER TABLE `%s` %s", bp.tableName, bp.toPartitionSQL())}
+	}
+	if len(bp.partitioning.partitions) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("ALTER TABLE `%s` ADD PARTITION (\n  %s\n)", bp.tableName, bp.partitionList())}
+}
+
+func (bp *mysqlBlueprint) toRollbackPartitionSQL() []string {
+	if bp.partitioning.method != "" {
+		return []string{fmt.Sprintf("ALTER TABLE `%s` REMOVE PARTITIONING", bp.tableName)}
+	}
+	var sqls []string
+	for i := len(bp.partitioning.partitions) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE `%s` DROP PARTITION `%s`", bp.tableName, bp.partitioning.partitions[i].name))
+	}
+	return sqls
+}
+
+// toPartitionSQL returns the PARTITION BY clause of a created table, "" when not partitioned
+func (bp *postgresqlBlueprint) toPartitionSQL() string {
+	if bp.partitioning.method == "" {
+		return ""
+	}
+	return fmt.Sprintf(" PARTITION BY %s (%s)", bp.partitioning.method, bp.columnList(bp.partitioning.columns))
+}
+
+// toPartitionTablesSQL creates the partitions, which are tables of their own on PostgreSQL
+func (bp *postgresqlBlueprint) toPartitionTablesSQL() []string {
+	var sqls []string
+	if bp.partitioning.method == partitionHash {
+		for i := range bp.partitioning.count {
+			sqls = append(sqls, fmt.Sprintf("CREATE TABLE%s %s PARTITION OF %s FOR VALUES WITH (MODULUS %d, REMAINDER %d)",
+				bp.ifNotExists(), postgresqlQualify(bp.schema, fmt.Sprintf("%s_p%d", bp.tableName, i)), bp.table(), bp.partitioning.count, i))
+		}
+		return sqls
+	}
+
+	from := strings.TrimSuffix(strings.Repeat("MINVALUE, ", max(len(bp.partitioning.columns), 1)), ", ")
+	for _, p := range bp.partitioning.partitions {
+		var bound string
+		if p.method() == partitionList {
+			bound = fmt.Sprintf("IN (%s)", strings.Join(p.in, ", "))
+		} else {
+			if p.from != "" {
+				from = p.from
+			}
+			bound = fmt.Sprintf("FROM (%s) TO (%s)", from, p.lessThan)
+			from = p.lessThan
+		}
+		sqls = append(sqls, fmt.Sprintf("CREATE TABLE%s %s PARTITION OF %s FOR VALUES %s", bp.ifNotExists(), postgresqlQualify(bp.schema, p.name), bp.table(), bound))
+	}
+	return sqls
+}
+
+func (bp *postgresqlBlueprint) toDropPartitionTablesSQL() []string {
+	var sqls []string
+	for i := len(bp.partitioning.partitions) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("DROP TABLE %s", postgresqlQualify(bp.schema, bp.partitioning.partitions[i].name)))
+	}
+	return sqls
+}
@@ -0,0 +1,237 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// PgxConn is the part of a pgx.Conn Pgx uses, matched by method so this
+// package does not import pgx
+type PgxConn[T pgxResult, R pgxRows[F], F any] interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (T, error)
+	Query(ctx context.Context, sql string, args ...any) (R, error)
+}
+
+type pgxResult interface {
+	RowsAffected() int64
+}
+
+type pgxRows[F any] interface {
+	Next() bool
+	Values() ([]any, error)
+	FieldDescriptions() []F
+	Close()
+	Err() error
+}
+
+// Pgx adapts a pgx.Conn to the DB the Runner, Schema and migrations take:
+//
+//	db := migrations.Pgx(conn)
+//	defer db.Close()
+//	err := migrations.NewRunner(migrations.PostgreSQL).Up(db)
+//
+// Every connection of the returned pool runs on the session of conn, one
+// statement at a time, with query results read in full before they return.
+// Transactions and the advisory lock of the Runner are therefore those of
+// conn, which must not be used elsewhere until db is closed. Closing db
+// leaves conn open.
+func Pgx[T pgxResult, R pgxRows[F], F any](conn PgxConn[T, R, F]) *sql.DB {
+	return sql.OpenDB(&pgxConnector{
+		exec: func(ctx context.Context, query string, args []any) (int64, error) {
+			tag, err := conn.Exec(ctx, query, args...)
+			if err != nil {
+				return 0, err
+			}
+			return tag.RowsAffected(), nil
+		},
+		query: func(ctx context.Context, query string, args []any) ([]string, [][]any, error) {
+			rows, err := conn.Query(ctx, query, args...)
+			if err != nil {
+				return nil, nil, err
+			}
+			defer rows.Close()
+
+			var values [][]any
+			for rows.Next() {
+				row, err := rows.Values()
+				if err != nil {
+					return nil, nil, err
+				}
+				values = append(values, row)
+			}
+			if err := rows.Err(); err != nil {
+				return nil, nil, err
+			}
+			var columns []string
+			for _, field := range rows.FieldDescriptions() {
+				columns = append(columns, reflect.Indirect(reflect.ValueOf(field)).FieldByName("Name").String())
+			}
+			return columns, values, nil
+		},
+	})
+}
+
+// pgxConnector opens connections sharing the session of a pgx.Conn, which
+// mu serializes the statements of
+type pgxConnector struct {
+	mu    sync.Mutex
+	exec  func(ctx context.Context, query string, args []any) (int64, error)
+	query func(ctx context.Context, query string, args []any) ([]string, [][]any, error)
+}
+
+func (c *pgxConnector) Connect(context.Context) (driver.Conn, error) {
+	return &pgxDriverConn{connector: c}, nil
+}
+
+func (c *pgxConnector) Driver() driver.Driver {
+	return pgxDriver{c}
+}
+
+type pgxDriver struct {
+	connector *pgxConnector
+}
+
+func (d pgxDriver) Open(string) (driver.Conn, error) {
+	return &pgxDriverConn{connector: d.connector}, nil
+}
+
+type pgxDriverConn struct {
+	connector *pgxConnector
+}
+
+func (c *pgxDriverConn) Prepare(query string) (driver.Stmt, error) {
+	return &pgxStmt{conn: c, query: query}, nil
+}
+
+func (c *pgxDriverConn) Close() error {
+	return nil
+}
+
+func (c *pgxDriverConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *pgxDriverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	begin := "BEGIN"
+	if level := sql.IsolationLevel(opts.Isolation); level != sql.LevelDefault {
+		begin += " ISOLATION LEVEL " + strings.ToUpper(level.String())
+	}
+	if opts.ReadOnly {
+		begin += " READ ONLY"
+	}
+	if _, err := c.ExecContext(ctx, begin, nil); err != nil {
+		return nil, err
+	}
+	return pgxTx{conn: c}, nil
+}
+
+// CheckNamedValue passes every argument to pgx, which encodes its own types
+func (c *pgxDriverConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *pgxDriverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values, err := pgxArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	c.connector.mu.Lock()
+	defer c.connector.mu.Unlock()
+	n, err := c.connector.exec(ctx, query, values)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (c *pgxDriverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := pgxArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	c.connector.mu.Lock()
+	defer c.connector.mu.Unlock()
+	columns, rows, err := c.connector.query(ctx, query, values)
+	if err != nil {
+		return nil, err
+	}
+	return &pgxDriverRows{columns: columns, rows: rows}, nil
+}
+
+// pgxArgs returns the values of args, which pgx takes by position only
+func pgxArgs(args []driver.NamedValue) ([]any, error) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("migrations: pgx takes no named arguments")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+type pgxStmt struct {
+	conn  *pgxDriverConn
+	query string
+}
+
+func (s *pgxStmt) Close() error {
+	return nil
+}
+
+func (s *pgxStmt) NumInput() int {
+	return -1
+}
+
+func (s *pgxStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *pgxStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+type pgxTx struct {
+	conn *pgxDriverConn
+}
+
+func (t pgxTx) Commit() error {
+	_, err := t.conn.ExecContext(context.Background(), "COMMIT", nil)
+	return err
+}
+
+func (t pgxTx) Rollback() error {
+	_, err := t.conn.ExecContext(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+// pgxDriverRows are the rows of a query, read in full
+type pgxDriverRows struct {
+	columns []string
+	rows    [][]any
+}
+
+func (r *pgxDriverRows) Columns() []string {
+	return r.columns
+}
+
+func (r *pgxDriverRows) Close() error {
+	return nil
+}
+
+func (r *pgxDriverRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for i, value := range r.rows[0] {
+		dest[i] = value
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,324 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Destructiveness estimates how much a migration can harm existing data
+type Destructiveness string
+
+const (
+	// NonDestructive statements only add tables, columns, indexes or rows
+	NonDestructive Destructiveness = "none"
+	// PotentiallyDestructive statements change or rename existing schema, update
+	// rows or drop constraints, which can break code or fail on existing data
+	PotentiallyDestructive Destructiveness = "potential"
+	// Destructive statements drop tables or columns or delete rows
+	Destructive Destructiveness = "destructive"
+)
+
+var destructivenessRank = map[Destructiveness]int{NonDestructive: 0, PotentiallyDestructive: 1, Destructive: 2}
+
+// Plan describes what Up would do, for deployment pipelines to inspect
+type Plan struct {
+	Migrations []PlannedMigration `json:"migrations"`
+	// Destructiveness is the highest destructiveness of the pending migrations
+	Destructiveness Destructiveness `json:"destructiveness"`
+	// Tables are the tables the pending migrations touch, in name order
+	Tables []string `json:"tables"`
+}
+
+// PlannedMigration is a pending migration and the SQL it would execute
+type PlannedMigration struct {
+	Name            string          `json:"name"`
+	Statements      []string        `json:"statements"`
+	Destructiveness Destructiveness `json:"destructiveness"`
+	Tables          []string        `json:"tables"`
+	// Warnings are what Lint found in the statements, when the Runner lints
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// WriteJSON writes the plan as an indented JSON document
+func (p *Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// Plan runs the pending migrations against a connection that records the
+// statements they execute instead of executing them, while queries such as
+// HasTable still read db. Nothing is written to db, the tracking table is not
+// even created: the queries run in a READ ONLY transaction rolled back after
+// planning, where the driver supports one, and queries other than reads fail.
+// A migration that branches on the result of its own writes can plan
+// differently from how it runs.
+func (r *Runner) Plan(ctx context.Context, db DB) (*Plan, error) {
+	if b, ok := db.(beginner); ok {
+		if tx, err := b.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}); err == nil {
+			defer tx.Rollback()
+			db = tx
+		}
+	}
+
+	applied := map[string]struct{}{}
+	exists, err := r.provider.HasTableContext(ctx, db, r.table)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if applied, err = r.applied(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &Plan{Migrations: []PlannedMigration{}, Destructiveness: NonDestructive, Tables: []string{}}
+	tables := map[string]struct{}{}
+	for _, m := range r.sorted() {
+		if _, ok := applied[m.Name]; ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		recorder := &planConnector{db: db}
+		dry := sql.OpenDB(recorder)
+		err := m.Up(dry)
+		dry.Close()
+		if err != nil {
+			return nil, fmt.Errorf("planning %s: %w", m.Name, err)
+		}
+
+		planned := PlannedMigration{
+			Name:            m.Name,
+			Statements:      recorder.statements,
+			Destructiveness: NonDestructive,
+			Tables:          statementTables(recorder.statements),
+		}
+		if planned.Statements == nil {
+			planned.Statements = []string{}
+		}
+		for _, statement := range planned.Statements {
+			planned.Destructiveness = maxDestructiveness(planned.Destructiveness, statementDestructiveness(statement))
+		}
+		if r.lint != nil {
+			planned.Warnings = Lint(planned.Statements)
+			for i := range planned.Warnings {
+				planned.Warnings[i].Migration = m.Name
+			}
+		}
+		for _, table := range planned.Tables {
+			tables[table] = struct{}{}
+		}
+		plan.Destructiveness = maxDestructiveness(plan.Destructiveness, planned.Destructiveness)
+		plan.Migrations = append(plan.Migrations, planned)
+	}
+
+	for table := range tables {
+		plan.Tables = append(plan.Tables, table)
+	}
+	sort.Strings(plan.Tables)
+	return plan, nil
+}
+
+func maxDestructiveness(a, b Destructiveness) Destructiveness {
+	if destructivenessRank[b] > destructivenessRank[a] {
+		return b
+	}
+	return a
+}
+
+var (
+	destructiveStatement = regexp.MustCompile(`(?i)^\s*(DROP\s+(TABLE|SCHEMA|DATABASE)|TRUNCATE|DELETE\s)|\bDROP\s+(COLUMN\b|[^,]*\bCASCADE\b)`)
+	additiveStatement    = regexp.MustCompile(`(?i)^\s*(CREATE\s|INSERT\s|COMMENT\s|SET\s)`)
+	additiveAlter        = regexp.MustCompile(`(?i)^\s*ALTER\s+TABLE\s+\S+\s+ADD\s`)
+)
+
+// statementDestructiveness estimates the destructiveness of a single statement
+func statementDestructiveness(statement string) Destructiveness {
+	switch {
+	case destructiveStatement.MatchString(statement):
+		return Destructive
+	case additiveStatement.MatchString(statement):
+		return NonDestructive
+	case additiveAlter.MatchString(statement) && !strings.Contains(strings.ToUpper(statement), "UNIQUE") && !strings.Contains(strings.ToUpper(statement), "PRIMARY KEY"):
+		// new unique and primary keys fail on existing duplicates
+		return NonDestructive
+	}
+	return PotentiallyDestructive
+}
+
+var statementTable = regexp.MustCompile("(?i)\\b(?:(?:CREATE|ALTER|DROP)\\s+TABLE(?:\\s+IF(?:\\s+NOT)?\\s+EXISTS)?|TRUNCATE(?:\\s+TABLE)?|INSERT\\s+INTO|^\\s*UPDATE|DELETE\\s+FROM|REFERENCES|RENAME\\s+(?:TABLE\\s+\\S+\\s+)?TO|INDEX\\s+\\S+\\s+ON)\\s+(?:[\"`\\[]?[A-Za-z_][A-Za-z0-9_$]*[\"`\\]]?\\.)?[\"`\\[]?([A-Za-z_][A-Za-z0-9_$.]*)")
+
+// statementTables returns the tables named in statements, in name order
+func statementTables(statements []string) []string {
+	seen := map[string]struct{}{}
+	tables := []string{}
+	for _, statement := range statements {
+		for _, match := range statementTable.FindAllStringSubmatch(statement, -1) {
+			table := match[1]
+			if _, ok := seen[table]; !ok {
+				seen[table] = struct{}{}
+				tables = append(tables, table)
+			}
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// planConnector opens connections that record executed statements and read
+// through to db for queries
+type planConnector struct {
+	db         Queryer
+	statements []string
+}
+
+func (c *planConnector) Connect(context.Context) (driver.Conn, error) {
+	return &planConn{connector: c}, nil
+}
+
+func (c *planConnector) Driver() driver.Driver {
+	return planDriver{c}
+}
+
+type planDriver struct {
+	connector *planConnector
+}
+
+func (d planDriver) Open(string) (driver.Conn, error) {
+	return &planConn{connector: d.connector}, nil
+}
+
+type planConn struct {
+	connector *planConnector
+}
+
+func (c *planConn) Prepare(query string) (driver.Stmt, error) {
+	return &planStmt{conn: c, query: query}, nil
+}
+
+func (c *planConn) Close() error {
+	return nil
+}
+
+func (c *planConn) Begin() (driver.Tx, error) {
+	return planTx{}, nil
+}
+
+func (c *planConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.connector.statements = append(c.connector.statements, strings.TrimSpace(query))
+	return driver.RowsAffected(0), nil
+}
+
+// readQuery matches queries that only read, reads with a writing
+// function or clause outside of string literals are told apart by writingQuery
+var (
+	readQuery     = regexp.MustCompile(`(?i)^\s*(SELECT|SHOW|PRAGMA|EXPLAIN|DESCRIBE)\b`)
+	writingQuery  = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|INTO|NEXTVAL|SETVAL)\b`)
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+)
+
+// QueryContext reads through to the real database, refusing queries that
+// could write, such as INSERT ... RETURNING or SELECT nextval(...)
+func (c *planConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !readQuery.MatchString(query) || writingQuery.MatchString(stringLiteral.ReplaceAllString(query, "''")) {
+		return nil, fmt.Errorf("migrations: planning runs only queries reading the database, not %q", strings.TrimSpace(query))
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	rows, err := c.connector.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &planRows{rows: rows, columns: columns}, nil
+}
+
+type planStmt struct {
+	conn  *planConn
+	query string
+}
+
+func (s *planStmt) Close() error {
+	return nil
+}
+
+func (s *planStmt) NumInput() int {
+	return -1
+}
+
+func (s *planStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *planStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+type planTx struct{}
+
+func (planTx) Commit() error {
+	return nil
+}
+
+func (planTx) Rollback() error {
+	return nil
+}
+
+// planRows adapts the rows of a query read through to the real database
+type planRows struct {
+	rows    *sql.Rows
+	columns []string
+}
+
+func (r *planRows) Columns() []string {
+	return r.columns
+}
+
+func (r *planRows) Close() error {
+	return r.rows.Close()
+}
+
+func (r *planRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	values := make([]any, len(dest))
+	pointers := make([]any, len(dest))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := r.rows.Scan(pointers...); err != nil {
+		return err
+	}
+	for i, value := range values {
+		dest[i] = value
+	}
+	return nil
+}
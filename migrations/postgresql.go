@@ -1,88 +1,395 @@
 package migrations
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/go-bold/bold/lock"
 )
 
-type postgresqlProvider struct{}
+type postgresqlProvider struct {
+	transaction bool
+	// schema qualifies every table, the search path applies when empty
+	schema string
+	// cockroach adjusts the statements to CockroachDB
+	cockroach bool
+}
 
 type postgresqlBlueprint struct {
 	*blueprint
+	provider         *postgresqlProvider
+	schema           string
+	updatedAtTrigger bool
+	unlogged         bool
 }
 
-func (p *postgresqlProvider) Create(db *sql.DB, tableName string, callback func(PostgreSQLBlueprint)) error {
-	bp := &postgresqlBlueprint{newBlueprint(tableName, db)}
-	callback(bp)
-	
-	// Create table
-	createSQL := bp.toCreateTableSQL()
-	if _, err := db.Exec(createSQL); err != nil {
+func (p *postgresqlProvider) newBlueprint(tableName string) *postgresqlBlueprint {
+	return &postgresqlBlueprint{blueprint: newBlueprint(tableName), provider: p, schema: p.schema}
+}
+
+// WithTransaction returns a copy of the provider with transactions enabled or
+// disabled. They are enabled by default so a failing index or foreign key
+// does not leave a half built table behind.
+func (p *postgresqlProvider) WithTransaction(enabled bool) *postgresqlProvider {
+	c := *p
+	c.transaction = enabled
+	return &c
+}
+
+// WithSchema returns a copy of the provider qualifying every table with
+// schema and introspecting schema instead of public, for schema per tenant
+// setups. The migrations table lives in schema too, which is created with it.
+func (p *postgresqlProvider) WithSchema(schema string) *postgresqlProvider {
+	c := *p
+	c.schema = schema
+	return &c
+}
+
+// schemaName returns the schema introspection queries look in
+func (p *postgresqlProvider) schemaName() string {
+	if p.schema == "" {
+		return "public"
+	}
+	return p.schema
+}
+
+// qualify returns the quoted table name, prefixed by the schema when set
+func (p *postgresqlProvider) qualify(tableName string) string {
+	return postgresqlQualify(p.schema, tableName)
+}
+
+func postgresqlQualify(schema, name string) string {
+	if schema == "" {
+		return postgresqlQuote(name)
+	}
+	return postgresqlQuote(schema) + "." + postgresqlQuote(name)
+}
+
+func (p *postgresqlProvider) Create(db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	return p.CreateContext(context.Background(), db, tableName, callback)
+}
+
+func (p *postgresqlProvider) CreateContext(ctx context.Context, db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	sqls, err := p.CreateSQL(tableName, callback)
+	if err != nil {
 		return err
 	}
-	
-	// Create indexes
-	for _, indexSQL := range bp.toIndexSQL() {
-		if _, err := db.Exec(indexSQL); err != nil {
-			return err
-		}
+	return p.exec(ctx, db, sqls)
+}
+
+func (p *postgresqlProvider) Table(db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	return p.TableContext(context.Background(), db, tableName, callback)
+}
+
+func (p *postgresqlProvider) TableContext(ctx context.Context, db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	sqls, err := p.TableSQL(tableName, callback)
+	if err != nil {
+		return err
 	}
-	
-	// Create foreign keys
-	for _, foreignSQL := range bp.toForeignKeySQL() {
-		if _, err := db.Exec(foreignSQL); err != nil {
-			return err
-		}
+	return p.exec(ctx, db, sqls)
+}
+
+// Rollback reverts what Table applies for the same callback, dropping the
+// added foreign keys, indexes and columns in reverse order. Use Drop to revert Create.
+func (p *postgresqlProvider) Rollback(db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	return p.RollbackContext(context.Background(), db, tableName, callback)
+}
+
+func (p *postgresqlProvider) RollbackContext(ctx context.Context, db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	sqls, err := p.RollbackSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return p.exec(ctx, db, sqls)
+}
+
+// CreateSQL returns the statements Create would execute, without touching the
+// database, or an error when the blueprint uses what the dialect does not support
+func (p *postgresqlProvider) CreateSQL(tableName string, callback func(PostgreSQLBlueprint)) ([]string, error) {
+	bp := p.newBlueprint(tableName)
+	callback(bp)
+	if err := errors.Join(bp.verifyCreate(), bp.validate()); err != nil {
+		return nil, err
+	}
+
+	sqls := []string{bp.toCreateTableSQL()}
+	sqls = append(sqls, bp.toPartitionTablesSQL()...)
+	sqls = append(sqls, bp.toIndexSQL()...)
+	if !bp.skipExisting {
+		sqls = append(sqls, bp.toForeignKeySQL()...)
 	}
-	
-	return nil
+	sqls = append(sqls, bp.toTriggerSQL()...)
+	return append(sqls, bp.toCommentSQL()...), nil
 }
 
-func (p *postgresqlProvider) Table(db *sql.DB, tableName string, callback func(PostgreSQLBlueprint)) error {
-	bp := &postgresqlBlueprint{newBlueprint(tableName, db)}
+// TableSQL returns the statements Table would execute, without touching the database
+func (p *postgresqlProvider) TableSQL(tableName string, callback func(PostgreSQLBlueprint)) ([]string, error) {
+	bp := p.newBlueprint(tableName)
 	callback(bp)
-	
+	if err := errors.Join(bp.verify(), bp.validate(), bp.validateAlter()); err != nil {
+		return nil, err
+	}
 	sqls := bp.toAlterSQL()
-	for _, sql := range sqls {
-		if _, err := db.Exec(sql); err != nil {
-			return err
+	if err := bp.verifyAlter(sqls); err != nil {
+		return nil, err
+	}
+	return sqls, nil
+}
+
+// RollbackSQL returns the statements Rollback would execute, without touching the database
+func (p *postgresqlProvider) RollbackSQL(tableName string, callback func(PostgreSQLBlueprint)) ([]string, error) {
+	bp := p.newBlueprint(tableName)
+	callback(bp)
+	if err := errors.Join(bp.verify(), bp.validate(), bp.validateAlter()); err != nil {
+		return nil, err
+	}
+	return bp.toRollbackSQL(), nil
+}
+
+func (p *postgresqlProvider) exec(ctx context.Context, db DB, sqls []string) error {
+	return execStatements(ctx, db, sqls, p.transaction)
+}
+
+// copySQL moves the sequences of the identity and serial columns past the
+// copied ids, which inserting them explicitly leaves behind
+func (p *postgresqlProvider) copySQL(tableName string, columns []ColumnInfo) (before, after []string) {
+	for _, column := range columns {
+		if column.AutoIncrement {
+			after = append(after, fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+				quoteString(p.qualify(tableName)), quoteString(column.Name), p.quote(column.Name), p.qualify(tableName)))
 		}
 	}
-	return nil
+	return nil, after
+}
+
+func (p *postgresqlProvider) Drop(db DB, tableName string) error {
+	return p.DropContext(context.Background(), db, tableName)
 }
 
-func (p *postgresqlProvider) Drop(db *sql.DB, tableName string) error {
-	sql := fmt.Sprintf("DROP TABLE \"%s\"", tableName)
-	_, err := db.Exec(sql)
+func (p *postgresqlProvider) DropContext(ctx context.Context, db DB, tableName string) error {
+	sql := fmt.Sprintf("DROP TABLE %s", p.qualify(tableName))
+	_, err := db.ExecContext(ctx, sql)
 	return err
 }
 
-func (p *postgresqlProvider) DropIfExists(db *sql.DB, tableName string) error {
-	sql := fmt.Sprintf("DROP TABLE IF EXISTS \"%s\"", tableName)
-	_, err := db.Exec(sql)
+func (p *postgresqlProvider) DropIfExists(db DB, tableName string) error {
+	return p.DropIfExistsContext(context.Background(), db, tableName)
+}
+
+func (p *postgresqlProvider) DropIfExistsContext(ctx context.Context, db DB, tableName string) error {
+	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s", p.qualify(tableName))
+	_, err := db.ExecContext(ctx, sql)
 	return err
 }
 
-func (p *postgresqlProvider) HasTable(db *sql.DB, tableName string) (bool, error) {
-	query := "SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_schema = 'public' AND table_name = $1)"
+// CreateView creates a view selecting query, such as "SELECT ... FROM orders GROUP BY ..."
+func (p *postgresqlProvider) CreateView(db DB, name, query string) error {
+	return p.CreateViewContext(context.Background(), db, name, query)
+}
+
+func (p *postgresqlProvider) CreateViewContext(ctx context.Context, db DB, name, query string) error {
+	sql := fmt.Sprintf("CREATE VIEW %s AS %s", p.qualify(name), query)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (p *postgresqlProvider) DropView(db DB, name string) error {
+	return p.DropViewContext(context.Background(), db, name)
+}
+
+func (p *postgresqlProvider) DropViewContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP VIEW %s", p.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateMaterializedView creates a view storing the rows of query, for
+// reports too slow to compute on every read. It is filled on creation and
+// then only by RefreshMaterializedView.
+func (p *postgresqlProvider) CreateMaterializedView(db DB, name, query string) error {
+	return p.CreateMaterializedViewContext(context.Background(), db, name, query)
+}
+
+func (p *postgresqlProvider) CreateMaterializedViewContext(ctx context.Context, db DB, name, query string) error {
+	sql := fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS %s", p.qualify(name), query)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// RefreshMaterializedView recomputes the rows of a materialized view,
+// usually from a scheduled job. Concurrent refreshes do not block reads but
+// need a unique index on the view.
+func (p *postgresqlProvider) RefreshMaterializedView(db DB, name string, concurrently bool) error {
+	return p.RefreshMaterializedViewContext(context.Background(), db, name, concurrently)
+}
+
+func (p *postgresqlProvider) RefreshMaterializedViewContext(ctx context.Context, db DB, name string, concurrently bool) error {
+	sql := "REFRESH MATERIALIZED VIEW "
+	if concurrently {
+		sql += "CONCURRENTLY "
+	}
+	_, err := db.ExecContext(ctx, sql+p.qualify(name))
+	return err
+}
+
+func (p *postgresqlProvider) DropMaterializedView(db DB, name string) error {
+	return p.DropMaterializedViewContext(context.Background(), db, name)
+}
+
+func (p *postgresqlProvider) DropMaterializedViewContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP MATERIALIZED VIEW %s", p.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (p *postgresqlProvider) Rename(db DB, from, to string) error {
+	return p.RenameContext(context.Background(), db, from, to)
+}
+
+func (p *postgresqlProvider) RenameContext(ctx context.Context, db DB, from, to string) error {
+	sql := fmt.Sprintf("ALTER TABLE %s RENAME TO \"%s\"", p.qualify(from), to)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (p *postgresqlProvider) HasTable(db DB, tableName string) (bool, error) {
+	return p.HasTableContext(context.Background(), db, tableName)
+}
+
+func (p *postgresqlProvider) HasTableContext(ctx context.Context, db DB, tableName string) (bool, error) {
+	query := "SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2)"
 	var exists bool
-	err := db.QueryRow(query, tableName).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, p.schemaName(), tableName).Scan(&exists)
 	return exists, err
 }
 
-func (p *postgresqlProvider) HasColumn(db *sql.DB, tableName, columnName string) (bool, error) {
-	query := "SELECT EXISTS (SELECT FROM information_schema.columns WHERE table_schema = 'public' AND table_name = $1 AND column_name = $2)"
+func (p *postgresqlProvider) HasColumn(db DB, tableName, columnName string) (bool, error) {
+	return p.HasColumnContext(context.Background(), db, tableName, columnName)
+}
+
+func (p *postgresqlProvider) HasColumnContext(ctx context.Context, db DB, tableName, columnName string) (bool, error) {
+	query := "SELECT EXISTS (SELECT FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 AND column_name = $3)"
 	var exists bool
-	err := db.QueryRow(query, tableName, columnName).Scan(&exists)
+	err := db.QueryRowContext(ctx, query, p.schemaName(), tableName, columnName).Scan(&exists)
 	return exists, err
 }
 
+// Columns returns the columns of a table in table order
+func (p *postgresqlProvider) Columns(db DB, tableName string) ([]ColumnInfo, error) {
+	return p.ColumnsContext(context.Background(), db, tableName)
+}
+
+func (p *postgresqlProvider) ColumnsContext(ctx context.Context, db DB, tableName string) ([]ColumnInfo, error) {
+	query := `SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull, pg_get_expr(d.adbin, d.adrelid),
+			EXISTS (SELECT FROM pg_index i WHERE i.indrelid = c.oid AND i.indisprimary AND a.attnum = ANY (i.indkey)),
+			a.attidentity <> '' OR COALESCE(pg_get_expr(d.adbin, d.adrelid), '') LIKE 'nextval(%'
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname = $1 AND c.relname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`
+	return queryColumns(ctx, db, query, p.schemaName(), tableName)
+}
+
+// Indexes returns the indexes of a table in name order, including the primary key
+func (p *postgresqlProvider) Indexes(db DB, tableName string) ([]IndexInfo, error) {
+	return p.IndexesContext(context.Background(), db, tableName)
+}
+
+func (p *postgresqlProvider) IndexesContext(ctx context.Context, db DB, tableName string) ([]IndexInfo, error) {
+	query := `SELECT ic.relname, a.attname, ix.indisunique, ix.indisprimary, am.amname, pg_get_expr(ix.indpred, ix.indrelid)
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class ic ON ic.oid = ix.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = $1 AND t.relname = $2
+		ORDER BY ic.relname, k.position`
+	return queryIndexes(ctx, db, query, p.schemaName(), tableName)
+}
+
+// Truncate empties the table, restarting its sequences and cascading to the tables referencing it
+func (p *postgresqlProvider) Truncate(db DB, tableName string) error {
+	return p.TruncateContext(context.Background(), db, tableName)
+}
+
+func (p *postgresqlProvider) TruncateContext(ctx context.Context, db DB, tableName string) error {
+	sql := fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", p.qualify(tableName))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// DropAllTables drops every table of the schema, public by default, including the migrations table
+func (p *postgresqlProvider) DropAllTables(db DB) error {
+	return p.DropAllTablesContext(context.Background(), db)
+}
+
+func (p *postgresqlProvider) DropAllTablesContext(ctx context.Context, db DB) error {
+	tables, err := p.TablesContext(ctx, db)
+	if err != nil || len(tables) == 0 {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s CASCADE", p.quoteTables(tables)))
+	return err
+}
+
+// Tables lists the tables of the schema, public by default
+func (p *postgresqlProvider) Tables(db DB) ([]string, error) {
+	return p.TablesContext(context.Background(), db)
+}
+
+func (p *postgresqlProvider) TablesContext(ctx context.Context, db DB) ([]string, error) {
+	return queryStrings(ctx, db, "SELECT tablename FROM pg_tables WHERE schemaname = $1 ORDER BY tablename", p.schemaName())
+}
+
+// TruncateAll empties every table of the schema but the except ones in a single statement
+func (p *postgresqlProvider) TruncateAll(db DB, except ...string) error {
+	return p.TruncateAllContext(context.Background(), db, except...)
+}
+
+func (p *postgresqlProvider) TruncateAllContext(ctx context.Context, db DB, except ...string) error {
+	tables, err := p.TablesContext(ctx, db)
+	if err != nil {
+		return err
+	}
+	tables = slices.DeleteFunc(tables, func(table string) bool {
+		return slices.Contains(except, table)
+	})
+	if len(tables) == 0 {
+		return nil
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", p.quoteTables(tables)))
+	return err
+}
+
+func (p *postgresqlProvider) quoteTables(tables []string) string {
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = p.qualify(table)
+	}
+	return strings.Join(quoted, ", ")
+}
+
 func (bp *postgresqlBlueprint) Serial(name string) ColumnBuilder {
+	if bp.provider.cockroach {
+		return bp.AddColumn(name, cockroachRowID)
+	}
 	return bp.AddColumn(name, "SERIAL")
 }
 
 func (bp *postgresqlBlueprint) BigSerial(name string) ColumnBuilder {
+	if bp.provider.cockroach {
+		return bp.AddColumn(name, cockroachRowID)
+	}
 	return bp.AddColumn(name, "BIGSERIAL")
 }
 
@@ -122,7 +429,33 @@ func (bp *postgresqlBlueprint) HStore(name string) ColumnBuilder {
 	return bp.AddColumn(name, "HSTORE")
 }
 
+// PartialIndex adds an index over the rows matching where, such as "deleted_at IS NULL"
+func (bp *postgresqlBlueprint) PartialIndex(columns []string, where string) {
+	bp.indexes = append(bp.indexes, &index{
+		kind:    indexPlain,
+		name:    strings.Join(columns, "_") + "_partial_index",
+		columns: columns,
+		where:   where,
+	})
+}
+
+// IndexExpression adds an index over an expression such as "lower(email)",
+// named after the identifiers in the expression
+func (bp *postgresqlBlueprint) IndexExpression(expression string) {
+	name := strings.Trim(nonIdentifier.ReplaceAllString(strings.ToLower(expression), "_"), "_")
+	bp.indexes = append(bp.indexes, &index{
+		kind:       indexPlain,
+		name:       name + "_index",
+		expression: expression,
+	})
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
 func (bp *postgresqlBlueprint) ID() ColumnBuilder {
+	if bp.provider.cockroach {
+		return bp.AddColumn("id", cockroachRowID+" PRIMARY KEY")
+	}
 	return bp.AddColumn("id", "BIGSERIAL PRIMARY KEY")
 }
 
@@ -130,98 +463,314 @@ func (bp *postgresqlBlueprint) UUID(name string) ColumnBuilder {
 	return bp.AddColumn(name, "UUID")
 }
 
+// UUIDPrimary adds a UUID id primary key generated by the database
+func (bp *postgresqlBlueprint) UUIDPrimary() ColumnBuilder {
+	return bp.AddColumn("id", "UUID DEFAULT gen_random_uuid() PRIMARY KEY")
+}
+
 func (bp *postgresqlBlueprint) Timestamps() {
 	bp.AddColumn("created_at", "TIMESTAMP DEFAULT CURRENT_TIMESTAMP")
 	bp.AddColumn("updated_at", "TIMESTAMP DEFAULT CURRENT_TIMESTAMP")
 }
 
+func (bp *postgresqlBlueprint) Double(name string) ColumnBuilder {
+	return bp.AddColumn(name, "DOUBLE PRECISION")
+}
+
+func (bp *postgresqlBlueprint) Binary(name string) ColumnBuilder {
+	return bp.AddColumn(name, "BYTEA")
+}
+
+func (bp *postgresqlBlueprint) TimestampTz(name string) ColumnBuilder {
+	return bp.AddColumn(name, "TIMESTAMPTZ")
+}
+
+func (bp *postgresqlBlueprint) TimestampsTz() {
+	bp.AddColumn("created_at", "TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP")
+	bp.AddColumn("updated_at", "TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP")
+}
+
 func (bp *postgresqlBlueprint) toCreateTableSQL() string {
 	var parts []string
-	
+
 	for _, column := range bp.columns {
-		columnSQL := fmt.Sprintf("\"%s\" %s", column.Name, column.Type)
-		
-		if !column.Nullable && !strings.Contains(column.Type, "SERIAL") {
-			columnSQL += " NOT NULL"
+		parts = append(parts, bp.columnSQL(column))
+	}
+
+	for _, index := range bp.indexes {
+		if index.kind == indexPrimary {
+			parts = append(parts, fmt.Sprintf("PRIMARY KEY (%s)", bp.columnList(index.columns)))
 		}
-		
-		if column.Default != nil {
-			defaultValue := bp.formatDefaultValue(column.Default)
-			columnSQL += fmt.Sprintf(" DEFAULT %s", defaultValue)
+	}
+
+	for _, check := range bp.checks {
+		parts = append(parts, check.clause(postgresqlQuote))
+	}
+
+	// skipped along with the table, as constraints have no IF NOT EXISTS
+	if bp.skipExisting {
+		for _, foreign := range bp.completeForeigns() {
+			parts = append(parts, foreign.clause(postgresqlQuote, bp.qualifyForeign))
 		}
-		
-		parts = append(parts, columnSQL)
 	}
-	
-	return fmt.Sprintf("CREATE TABLE \"%s\" (\n  %s\n)", bp.tableName, strings.Join(parts, ",\n  "))
+
+	return fmt.Sprintf("%s %s (\n  %s\n)%s", bp.createTable(), bp.table(), strings.Join(parts, ",\n  "), bp.toPartitionSQL())
 }
 
 func (bp *postgresqlBlueprint) toIndexSQL() []string {
 	var sqls []string
-	
+
 	for _, index := range bp.indexes {
-		indexSQL := bp.formatIndexSQL(index)
-		sqls = append(sqls, indexSQL)
+		if index.kind != indexPrimary {
+			sqls = append(sqls, bp.indexSQL(index))
+		}
 	}
-	
+
 	return sqls
 }
 
 func (bp *postgresqlBlueprint) toForeignKeySQL() []string {
 	var sqls []string
-	
-	for _, foreign := range bp.foreigns {
-		foreignSQL := bp.formatForeignKeySQL(foreign)
-		sqls = append(sqls, foreignSQL)
+
+	for _, foreign := range bp.completeForeigns() {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD %s", bp.table(), foreign.clause(postgresqlQuote, bp.qualifyForeign)))
 	}
-	
+
 	return sqls
 }
 
 func (bp *postgresqlBlueprint) toAlterSQL() []string {
 	var sqls []string
-	
+
+	for _, name := range bp.droppedForeigns {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT \"%s\"", bp.table(), name))
+	}
+
+	for _, name := range bp.droppedIndexes {
+		sqls = append(sqls, fmt.Sprintf("DROP INDEX%s %s", bp.ifExists(), postgresqlQualify(bp.schema, name)))
+	}
+
+	for _, rename := range bp.renamedIndexes {
+		sqls = append(sqls, fmt.Sprintf("ALTER INDEX %s RENAME TO \"%s\"", postgresqlQualify(bp.schema, rename.from), rename.to))
+	}
+
+	for _, rename := range bp.renames {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN \"%s\" TO \"%s\"", bp.table(), rename.from, rename.to))
+	}
+
+	for _, name := range bp.drops {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP COLUMN%s \"%s\"", bp.table(), bp.ifExists(), name))
+	}
+
 	for _, column := range bp.columns {
-		columnSQL := fmt.Sprintf("\"%s\" %s", column.Name, column.Type)
-		
-		if !column.Nullable && !strings.Contains(column.Type, "SERIAL") {
-			columnSQL += " NOT NULL"
+		if column.Change {
+			sqls = append(sqls, bp.changeColumnSQL(column))
+			continue
 		}
-		
-		if column.Default != nil {
-			columnSQL += fmt.Sprintf(" DEFAULT %v", column.Default)
-		}
-		
-		sql := fmt.Sprintf("ALTER TABLE \"%s\" ADD COLUMN %s", bp.tableName, columnSQL)
-		sqls = append(sqls, sql)
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD COLUMN%s %s", bp.table(), bp.ifNotExists(), bp.columnSQL(column)))
 	}
-	
+
 	for _, index := range bp.indexes {
-		indexSQL := strings.ReplaceAll(index, "`", "\"")
-		sql := fmt.Sprintf("ALTER TABLE \"%s\" ADD %s", bp.tableName, indexSQL)
-		sqls = append(sqls, sql)
+		if index.kind == indexPrimary {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s)", bp.table(), bp.columnList(index.columns)))
+			continue
+		}
+		sqls = append(sqls, bp.indexSQL(index))
+	}
+
+	for _, check := range bp.checks {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD %s", bp.table(), check.clause(postgresqlQuote)))
+	}
+
+	sqls = append(sqls, bp.toForeignKeySQL()...)
+	sqls = append(sqls, bp.toTriggerSQL()...)
+	sqls = append(sqls, bp.toCommentSQL()...)
+	sqls = append(sqls, bp.toPartitionTablesSQL()...)
+	return append(sqls, bp.toLoggedSQL(false)...)
+}
+
+func (bp *postgresqlBlueprint) toCommentSQL() []string {
+	if bp.comment == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("COMMENT ON TABLE %s IS %s", bp.table(), quoteString(bp.comment))}
+}
+
+// toRollbackSQL reverses toAlterSQL: check constraints, foreign keys, then indexes, then added columns are
+// dropped and renamed columns and indexes get their old name back. Dropped columns, indexes and
+// foreign keys and changed columns cannot be restored as their previous definition is unknown.
+func (bp *postgresqlBlueprint) toRollbackSQL() []string {
+	sqls := append(bp.toLoggedSQL(true), bp.toDropPartitionTablesSQL()...)
+	sqls = append(sqls, bp.toDropTriggerSQL()...)
+
+	for i := len(bp.checks) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT%s \"%s\"", bp.table(), bp.ifExists(), bp.checks[i].name))
+	}
+
+	foreigns := bp.completeForeigns()
+	for i := len(foreigns) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT%s \"%s\"", bp.table(), bp.ifExists(), foreigns[i].name))
+	}
+
+	for i := len(bp.indexes) - 1; i >= 0; i-- {
+		index := bp.indexes[i]
+		if index.kind == indexPrimary {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT \"%s\"", bp.table(), index.name))
+			continue
+		}
+		sqls = append(sqls, fmt.Sprintf("DROP INDEX%s %s", bp.ifExists(), postgresqlQualify(bp.schema, index.name)))
 	}
-	
+
+	for i := len(bp.columns) - 1; i >= 0; i-- {
+		if bp.columns[i].Change {
+			continue
+		}
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP COLUMN%s \"%s\"", bp.table(), bp.ifExists(), bp.columns[i].Name))
+	}
+
+	for i := len(bp.renames) - 1; i >= 0; i-- {
+		rename := bp.renames[i]
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN \"%s\" TO \"%s\"", bp.table(), rename.to, rename.from))
+	}
+
+	for i := len(bp.renamedIndexes) - 1; i >= 0; i-- {
+		rename := bp.renamedIndexes[i]
+		sqls = append(sqls, fmt.Sprintf("ALTER INDEX %s RENAME TO \"%s\"", postgresqlQualify(bp.schema, rename.to), rename.from))
+	}
+
 	return sqls
 }
 
+// changeColumnSQL alters the type, nullability and default of an existing column in one statement
+func (bp *postgresqlBlueprint) changeColumnSQL(column *Column) string {
+	name := postgresqlQuote(column.Name)
+	columnType := column.Type
+	if column.Collation != "" {
+		columnType += fmt.Sprintf(" COLLATE \"%s\"", column.Collation)
+	}
+	actions := []string{fmt.Sprintf("ALTER COLUMN %s TYPE %s", name, columnType)}
+
+	if column.Nullable {
+		actions = append(actions, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", name))
+	} else {
+		actions = append(actions, fmt.Sprintf("ALTER COLUMN %s SET NOT NULL", name))
+	}
+
+	if column.Default != nil {
+		actions = append(actions, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %s", name, bp.formatDefaultValue(column.Default)))
+	} else {
+		actions = append(actions, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", name))
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s %s", bp.table(), strings.Join(actions, ", "))
+}
+
+func (bp *postgresqlBlueprint) columnSQL(column *Column) string {
+	columnSQL := fmt.Sprintf("\"%s\" %s", column.Name, column.Type)
+
+	if column.Collation != "" {
+		columnSQL += fmt.Sprintf(" COLLATE \"%s\"", column.Collation)
+	}
+
+	if column.AutoIncrement {
+		columnSQL += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+
+	if column.Generated != "" {
+		columnSQL += fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED", column.Generated)
+	}
+
+	if !column.Nullable && !strings.Contains(column.Type, "SERIAL") {
+		columnSQL += " NOT NULL"
+	}
+
+	// generated columns cannot have a default
+	if column.Default != nil && column.Generated == "" {
+		columnSQL += fmt.Sprintf(" DEFAULT %s", bp.formatDefaultValue(column.Default))
+	}
+
+	if column.Unique {
+		columnSQL += " UNIQUE"
+	}
+
+	if column.Primary {
+		columnSQL += " PRIMARY KEY"
+	}
+
+	return columnSQL
+}
+
+// indexSQL returns the CREATE INDEX statement of a non-primary index. PostgreSQL
+// has no FULLTEXT indexes, so those become GIN indexes over to_tsvector.
+func (bp *postgresqlBlueprint) indexSQL(index *index) string {
+	sql := bp.indexTargetSQL(index)
+	if index.where != "" {
+		sql += " WHERE " + index.where
+	}
+	return sql
+}
+
+func (bp *postgresqlBlueprint) indexTargetSQL(index *index) string {
+	using, sharded := "", ""
+	switch {
+	case bp.provider.cockroach && index.algorithm == Hash:
+		// CockroachDB spreads the rows of hash-sharded indexes over buckets
+		sharded = " USING HASH"
+	case index.algorithm != "":
+		using = " USING " + index.algorithm
+	}
+	create := "CREATE INDEX" + bp.ifNotExists()
+
+	if index.expression != "" {
+		return fmt.Sprintf("%s \"%s\" ON %s%s ((%s))%s", create, index.name, bp.table(), using, index.expression, sharded)
+	}
+
+	switch index.kind {
+	case indexUnique:
+		return fmt.Sprintf("CREATE UNIQUE INDEX%s \"%s\" ON %s%s (%s)%s", bp.ifNotExists(), index.name, bp.table(), using, bp.columnList(index.columns), sharded)
+	case indexFullText:
+		vectors := make([]string, len(index.columns))
+		for i, column := range index.columns {
+			vectors[i] = fmt.Sprintf("to_tsvector('english', \"%s\")", column)
+		}
+		return fmt.Sprintf("%s \"%s\" ON %s USING GIN ((%s))", create, index.name, bp.table(), strings.Join(vectors, " || "))
+	default:
+		return fmt.Sprintf("%s \"%s\" ON %s%s (%s)%s", create, index.name, bp.table(), using, bp.columnList(index.columns), sharded)
+	}
+}
+
+// table returns the quoted table name, prefixed by the schema when set
+func (bp *postgresqlBlueprint) table() string {
+	return postgresqlQualify(bp.schema, bp.tableName)
+}
+
+// qualifyForeign returns the quoted referenced table, in the schema of the blueprint
+func (bp *postgresqlBlueprint) qualifyForeign(tableName string) string {
+	return postgresqlQualify(bp.schema, tableName)
+}
+
+func (bp *postgresqlBlueprint) columnList(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = postgresqlQuote(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func postgresqlQuote(name string) string {
+	return "\"" + name + "\""
+}
+
 func (bp *postgresqlBlueprint) formatDefaultValue(value interface{}) string {
 	switch v := value.(type) {
 	case string:
-		// Special handling for PostgreSQL arrays and JSON
-		if v == "{}" {
-			return "'{}'"
-		}
-		if v == "[]" {
-			return "'[]'"
+		// array and JSON literals such as '{}' are quoted strings too
+		return quoteString(v)
+	case bool:
+		if v {
+			return "TRUE"
 		}
-		if v == "{user}" {
-			return "'{user}'"
-		}
-		if strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}") {
-			return "'" + v + "'"
-		}
-		return "'" + v + "'"
+		return "FALSE"
 	case int, int64, float64:
 		return fmt.Sprintf("%v", v)
 	default:
@@ -229,30 +778,80 @@ func (bp *postgresqlBlueprint) formatDefaultValue(value interface{}) string {
 	}
 }
 
-func (bp *postgresqlBlueprint) formatIndexSQL(index string) string {
-	// Replace MySQL syntax with PostgreSQL
-	index = strings.ReplaceAll(index, "`", "\"")
-	
-	if strings.Contains(index, "INDEX") && !strings.Contains(index, "CREATE") {
-		// Convert "INDEX name (columns)" to "CREATE INDEX name ON table (columns)"
-		parts := strings.Fields(index)
-		if len(parts) >= 3 {
-			indexName := parts[1]
-			columns := strings.Join(parts[2:], " ")
-			return fmt.Sprintf("CREATE INDEX %s ON \"%s\" %s", indexName, bp.tableName, columns)
+func (p *postgresqlProvider) placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// maxParams is the most parameters the wire protocol's 16-bit count allows
+func (p *postgresqlProvider) maxParams() int {
+	return 65535
+}
+
+// maxInsertRows is zero, the rows of an INSERT being only limited by maxParams
+func (p *postgresqlProvider) maxInsertRows() int {
+	return 0
+}
+
+// qualifiedTable returns the migrations table for the queries of the Runner,
+// prefixed by the schema when set
+func (p *postgresqlProvider) qualifiedTable(tableName string) string {
+	if p.schema == "" {
+		return tableName
+	}
+	return p.qualify(tableName)
+}
+
+func (p *postgresqlProvider) quoteTable(tableName string) string {
+	return p.qualify(tableName)
+}
+
+func (p *postgresqlProvider) quote(name string) string {
+	return postgresqlQuote(name)
+}
+
+func (p *postgresqlProvider) createMigrationsTable(ctx context.Context, db DB, tableName string) error {
+	if p.schema != "" {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", postgresqlQuote(p.schema))); err != nil {
+			return err
 		}
 	}
-	
-	return index
+	return p.CreateContext(ctx, db, tableName, func(table PostgreSQLBlueprint) {
+		table.ID()
+		table.String("migration", 255)
+		table.Integer("batch")
+		table.Timestamp("applied_at")
+	})
 }
 
-func (bp *postgresqlBlueprint) formatForeignKeySQL(foreign string) string {
-	// Replace MySQL syntax with PostgreSQL
-	foreign = strings.ReplaceAll(foreign, "`", "\"")
-	
-	if !strings.Contains(foreign, "ALTER TABLE") {
-		return fmt.Sprintf("ALTER TABLE \"%s\" ADD %s", bp.tableName, foreign)
+// locker returns the advisory lock Runner holds while migrating, none on
+// CockroachDB, which has no advisory locks
+func (p *postgresqlProvider) locker(db lock.Pool) lock.Locker {
+	if p.cockroach {
+		return nil
+	}
+	return lock.Postgres(db)
+}
+
+func (p *postgresqlProvider) describe(tableName string, callback func(Blueprint)) *blueprint {
+	bp := p.newBlueprint(tableName)
+	callback(bp)
+	if bp.unlogged {
+		bp.options = append(bp.options, "Unlogged")
 	}
-	
-	return foreign
-}
\ No newline at end of file
+	if bp.updatedAtTrigger {
+		bp.options = append(bp.options, "UpdatedAtTrigger")
+	}
+	return bp.blueprint
+}
+
+func (p *postgresqlProvider) createSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return p.CreateSQL(tableName, func(bp PostgreSQLBlueprint) { callback(bp) })
+}
+
+func (p *postgresqlProvider) tableSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return p.TableSQL(tableName, func(bp PostgreSQLBlueprint) { callback(bp) })
+}
+
+func (p *postgresqlProvider) rollbackSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return p.RollbackSQL(tableName, func(bp PostgreSQLBlueprint) { callback(bp) })
+}
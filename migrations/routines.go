@@ -0,0 +1,209 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Trigger runs a statement for each row a change to a table touches
+type Trigger struct {
+	Name  string
+	Table string
+	// Timing is BEFORE or AFTER, or INSTEAD OF for PostgreSQL views
+	Timing string
+	// Event is INSERT, UPDATE or DELETE
+	Event string
+	// Body is what runs for each row. On MySQL it is a statement such as
+	// "SET NEW.total = NEW.price * NEW.quantity", or several in BEGIN ... END.
+	// On PostgreSQL it is the call of a trigger function such as "set_total()",
+	// see CreateFunction.
+	Body string
+}
+
+func (t Trigger) validate() error {
+	if !slices.Contains([]string{"BEFORE", "AFTER", "INSTEAD OF"}, strings.ToUpper(t.Timing)) {
+		return fmt.Errorf("migrations: trigger %s: invalid timing %q, use BEFORE or AFTER", t.Name, t.Timing)
+	}
+	if !slices.Contains([]string{"INSERT", "UPDATE", "DELETE"}, strings.ToUpper(t.Event)) {
+		return fmt.Errorf("migrations: trigger %s: invalid event %q, use INSERT, UPDATE or DELETE", t.Name, t.Event)
+	}
+	return nil
+}
+
+func (m *mysqlProvider) CreateTrigger(db DB, trigger Trigger) error {
+	return m.CreateTriggerContext(context.Background(), db, trigger)
+}
+
+func (m *mysqlProvider) CreateTriggerContext(ctx context.Context, db DB, trigger Trigger) error {
+	if err := trigger.validate(); err != nil {
+		return err
+	}
+	sql := fmt.Sprintf("CREATE TRIGGER `%s` %s %s ON `%s` FOR EACH ROW %s",
+		trigger.Name, strings.ToUpper(trigger.Timing), strings.ToUpper(trigger.Event), trigger.Table, trigger.Body)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// DropTrigger drops a trigger, MySQL names triggers per database so table is
+// only used by PostgreSQL
+func (m *mysqlProvider) DropTrigger(db DB, table, name string) error {
+	return m.DropTriggerContext(context.Background(), db, table, name)
+}
+
+func (m *mysqlProvider) DropTriggerContext(ctx context.Context, db DB, table, name string) error {
+	sql := fmt.Sprintf("DROP TRIGGER `%s`", name)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateFunction creates a stored function from its definition following the
+// name, such as "(price DECIMAL(8,2)) RETURNS DECIMAL(8,2) DETERMINISTIC RETURN price * 1.2"
+func (m *mysqlProvider) CreateFunction(db DB, name, definition string) error {
+	return m.CreateFunctionContext(context.Background(), db, name, definition)
+}
+
+func (m *mysqlProvider) CreateFunctionContext(ctx context.Context, db DB, name, definition string) error {
+	sql := fmt.Sprintf("CREATE FUNCTION `%s`%s", name, definition)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (m *mysqlProvider) DropFunction(db DB, name string) error {
+	return m.DropFunctionContext(context.Background(), db, name)
+}
+
+func (m *mysqlProvider) DropFunctionContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP FUNCTION `%s`", name)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateProcedure creates a stored procedure from its definition following
+// the name, such as "(IN days INT) BEGIN DELETE FROM sessions WHERE ...; END"
+func (m *mysqlProvider) CreateProcedure(db DB, name, definition string) error {
+	return m.CreateProcedureContext(context.Background(), db, name, definition)
+}
+
+func (m *mysqlProvider) CreateProcedureContext(ctx context.Context, db DB, name, definition string) error {
+	sql := fmt.Sprintf("CREATE PROCEDURE `%s`%s", name, definition)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (m *mysqlProvider) DropProcedure(db DB, name string) error {
+	return m.DropProcedureContext(context.Background(), db, name)
+}
+
+func (m *mysqlProvider) DropProcedureContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP PROCEDURE `%s`", name)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (p *postgresqlProvider) CreateTrigger(db DB, trigger Trigger) error {
+	return p.CreateTriggerContext(context.Background(), db, trigger)
+}
+
+func (p *postgresqlProvider) CreateTriggerContext(ctx context.Context, db DB, trigger Trigger) error {
+	if err := trigger.validate(); err != nil {
+		return err
+	}
+	sql := fmt.Sprintf("CREATE TRIGGER \"%s\" %s %s ON %s FOR EACH ROW EXECUTE FUNCTION %s",
+		trigger.Name, strings.ToUpper(trigger.Timing), strings.ToUpper(trigger.Event), p.qualify(trigger.Table), trigger.Body)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (p *postgresqlProvider) DropTrigger(db DB, table, name string) error {
+	return p.DropTriggerContext(context.Background(), db, table, name)
+}
+
+func (p *postgresqlProvider) DropTriggerContext(ctx context.Context, db DB, table, name string) error {
+	sql := fmt.Sprintf("DROP TRIGGER \"%s\" ON %s", name, p.qualify(table))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateFunction creates a function from its definition following the name,
+// such as "() RETURNS trigger LANGUAGE plpgsql AS $$ BEGIN ... END $$"
+func (p *postgresqlProvider) CreateFunction(db DB, name, definition string) error {
+	return p.CreateFunctionContext(context.Background(), db, name, definition)
+}
+
+func (p *postgresqlProvider) CreateFunctionContext(ctx context.Context, db DB, name, definition string) error {
+	sql := fmt.Sprintf("CREATE FUNCTION %s%s", p.qualify(name), definition)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// DropFunction drops a function, whose name must not be overloaded
+func (p *postgresqlProvider) DropFunction(db DB, name string) error {
+	return p.DropFunctionContext(context.Background(), db, name)
+}
+
+func (p *postgresqlProvider) DropFunctionContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP FUNCTION %s", p.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateProcedure creates a procedure from its definition following the name,
+// such as "(days int) LANGUAGE sql AS $$ DELETE FROM sessions WHERE ... $$"
+func (p *postgresqlProvider) CreateProcedure(db DB, name, definition string) error {
+	return p.CreateProcedureContext(context.Background(), db, name, definition)
+}
+
+func (p *postgresqlProvider) CreateProcedureContext(ctx context.Context, db DB, name, definition string) error {
+	sql := fmt.Sprintf("CREATE PROCEDURE %s%s", p.qualify(name), definition)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (p *postgresqlProvider) DropProcedure(db DB, name string) error {
+	return p.DropProcedureContext(context.Background(), db, name)
+}
+
+func (p *postgresqlProvider) DropProcedureContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP PROCEDURE %s", p.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// updatedAtFunction is the trigger function of UpdatedAtTrigger, shared by every table
+const updatedAtFunction = "bold_set_updated_at"
+
+// UpdatedAtTrigger keeps updated_at current on every update, as MySQL does
+// with ON UPDATE CURRENT_TIMESTAMP. Rollback drops the trigger, the function
+// shared by the tables is kept.
+func (bp *postgresqlBlueprint) UpdatedAtTrigger() {
+	bp.updatedAtTrigger = true
+}
+
+func (bp *postgresqlBlueprint) toTriggerSQL() []string {
+	if !bp.updatedAtTrigger {
+		return nil
+	}
+	function := postgresqlQualify(bp.schema, updatedAtFunction)
+	return []string{
+		fmt.Sprintf("CREATE OR REPLACE FUNCTION %s() RETURNS trigger LANGUAGE plpgsql AS $$ BEGIN NEW.updated_at = CURRENT_TIMESTAMP; RETURN NEW; END $$", function),
+		fmt.Sprintf("%s \"%s_updated_at\" BEFORE UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", bp.createTrigger(), bp.tableName, bp.table(), function),
+	}
+}
+
+// createTrigger returns the CREATE TRIGGER keywords, replacing the trigger of
+// the blueprints skipping what exists, which needs PostgreSQL 14
+func (bp *postgresqlBlueprint) createTrigger() string {
+	if bp.skipExisting {
+		return "CREATE OR REPLACE TRIGGER"
+	}
+	return "CREATE TRIGGER"
+}
+
+func (bp *postgresqlBlueprint) toDropTriggerSQL() []string {
+	if !bp.updatedAtTrigger {
+		return nil
+	}
+	return []string{fmt.Sprintf("DROP TRIGGER IF EXISTS \"%s_updated_at\" ON %s", bp.tableName, bp.table())}
+}
@@ -0,0 +1,507 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-bold/bold/config"
+	"github.com/go-bold/bold/lock"
+	"github.com/go-bold/bold/metrics"
+)
+
+// ErrProduction is returned by destructive Runner operations in production,
+// or with no environment set, unless forced
+var ErrProduction = errors.New("migrations: refusing to roll back in production or with no environment set without Force")
+
+var (
+	migrationsRun     = metrics.NewCounter("bold_migrations_total", "Migrations applied or rolled back", "direction")
+	migrationDuration = metrics.NewHistogram("bold_migration_duration_seconds", "Time spent running migrations", nil, "direction")
+)
+
+// Provider is implemented by the dialect providers, MySQL, PostgreSQL and SQL Server
+type Provider interface {
+	Drop(db DB, tableName string) error
+	DropIfExists(db DB, tableName string) error
+	Rename(db DB, from, to string) error
+	Truncate(db DB, tableName string) error
+	DropAllTables(db DB) error
+	Tables(db DB) ([]string, error)
+	TruncateAll(db DB, except ...string) error
+	HasTable(db DB, tableName string) (bool, error)
+	HasColumn(db DB, tableName, columnName string) (bool, error)
+	Columns(db DB, tableName string) ([]ColumnInfo, error)
+	Indexes(db DB, tableName string) ([]IndexInfo, error)
+	CreateView(db DB, name, query string) error
+	DropView(db DB, name string) error
+	CreateTrigger(db DB, trigger Trigger) error
+	DropTrigger(db DB, table, name string) error
+	CreateFunction(db DB, name, definition string) error
+	DropFunction(db DB, name string) error
+	CreateProcedure(db DB, name, definition string) error
+	DropProcedure(db DB, name string) error
+
+	DropContext(ctx context.Context, db DB, tableName string) error
+	DropIfExistsContext(ctx context.Context, db DB, tableName string) error
+	RenameContext(ctx context.Context, db DB, from, to string) error
+	TruncateContext(ctx context.Context, db DB, tableName string) error
+	DropAllTablesContext(ctx context.Context, db DB) error
+	TablesContext(ctx context.Context, db DB) ([]string, error)
+	TruncateAllContext(ctx context.Context, db DB, except ...string) error
+	HasTableContext(ctx context.Context, db DB, tableName string) (bool, error)
+	HasColumnContext(ctx context.Context, db DB, tableName, columnName string) (bool, error)
+	ColumnsContext(ctx context.Context, db DB, tableName string) ([]ColumnInfo, error)
+	IndexesContext(ctx context.Context, db DB, tableName string) ([]IndexInfo, error)
+	CreateViewContext(ctx context.Context, db DB, name, query string) error
+	DropViewContext(ctx context.Context, db DB, name string) error
+	CreateTriggerContext(ctx context.Context, db DB, trigger Trigger) error
+	DropTriggerContext(ctx context.Context, db DB, table, name string) error
+	CreateFunctionContext(ctx context.Context, db DB, name, definition string) error
+	DropFunctionContext(ctx context.Context, db DB, name string) error
+	CreateProcedureContext(ctx context.Context, db DB, name, definition string) error
+	DropProcedureContext(ctx context.Context, db DB, name string) error
+
+	// Supports reports whether the dialect has feature, so migrations shared
+	// between dialects can branch on it
+	Supports(feature Feature) bool
+
+	placeholder(n int) string
+	maxParams() int
+	maxInsertRows() int
+	qualifiedTable(tableName string) string
+	quoteTable(tableName string) string
+	quote(name string) string
+	createMigrationsTable(ctx context.Context, db DB, tableName string) error
+	locker(db lock.Pool) lock.Locker
+	exec(ctx context.Context, db DB, sqls []string) error
+	copySQL(tableName string, columns []ColumnInfo) (before, after []string)
+	describe(tableName string, callback func(Blueprint)) *blueprint
+	createSQL(tableName string, callback func(Blueprint)) ([]string, error)
+	createTemporarySQL(tableName string, callback func(Blueprint)) ([]string, error)
+	tableSQL(tableName string, callback func(Blueprint)) ([]string, error)
+	rollbackSQL(tableName string, callback func(Blueprint)) ([]string, error)
+}
+
+// MigrationFunc applies or reverts a migration, on the DB given to the
+// Runner or a connection of it
+type MigrationFunc func(db DB) error
+
+// Migration is a named schema change with its up and optional down path
+type Migration struct {
+	Name string
+	Up   MigrationFunc
+	Down MigrationFunc
+}
+
+// Runner applies registered migrations once, tracking the applied ones in
+// the bold_migrations table so repeated deploys only run pending migrations.
+// Migrations are applied in name order, so names should start with a sortable
+// timestamp such as 2024_01_31_120000_create_users_table.
+type Runner struct {
+	provider   Provider
+	table      string
+	migrations []Migration
+	force      bool
+	lint       func(Warning)
+	// locker replaces the advisory lock of the provider, held for lockTTL
+	locker  lock.Locker
+	lockTTL time.Duration
+	before  []MigrationHook
+	after   []MigrationHook
+	onError []MigrationHook
+}
+
+// MigrationsTable is the table a Runner records the applied migrations in
+const MigrationsTable = "bold_migrations"
+
+var registry []Migration
+
+// Register adds a migration to every Runner created afterwards, letting
+// generated migration files register themselves from an init function
+func Register(name string, up, down MigrationFunc) {
+	registry = append(registry, Migration{Name: name, Up: up, Down: down})
+}
+
+// NewRunner returns a Runner with the migrations added by the package level Register
+func NewRunner(provider Provider) *Runner {
+	return &Runner{
+		provider:   provider,
+		table:      MigrationsTable,
+		migrations: append([]Migration{}, registry...),
+	}
+}
+
+// Register adds a migration; down may be nil for irreversible migrations
+func (r *Runner) Register(name string, up, down MigrationFunc) *Runner {
+	r.migrations = append(r.migrations, Migration{Name: name, Up: up, Down: down})
+	return r
+}
+
+// Force allows rolling back migrations when config.IsProtected reports true
+func (r *Runner) Force() *Runner {
+	r.force = true
+	return r
+}
+
+// Lint enables linting the SQL of pending migrations: Up plans them first
+// and passes report each Warning before applying any, and Plan lists the
+// warnings of each migration. Up plans under the migration lock, so every
+// pending migration function runs twice, once against Plan's recording
+// connection and once for real.
+func (r *Runner) Lint(report func(Warning)) *Runner {
+	r.lint = report
+	return r
+}
+
+// Lock replaces the lock Up and Down hold while migrating, by default an
+// advisory lock of the database, for instance with a lock.Driver shared by
+// databases without one. ttl bounds how long a runner that crashed keeps it.
+func (r *Runner) Lock(l lock.Locker, ttl time.Duration) *Runner {
+	r.locker, r.lockTTL = l, ttl
+	return r
+}
+
+// Up applies all pending migrations as a new batch
+func (r *Runner) Up(db DB) error {
+	return r.UpContext(context.Background(), db)
+}
+
+// UpContext is like Up but stops before the next migration once ctx is done
+func (r *Runner) UpContext(ctx context.Context, db DB) error {
+	unlock, err := r.lock(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if r.lint != nil {
+		plan, err := r.Plan(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, m := range plan.Migrations {
+			for _, warning := range m.Warnings {
+				r.lint(warning)
+			}
+		}
+	}
+
+	if err := r.ensureTable(ctx, db); err != nil {
+		return err
+	}
+
+	applied, err := r.applied(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	batch, err := r.lastBatch(ctx, db)
+	if err != nil {
+		return err
+	}
+	batch++
+
+	for _, m := range r.sorted() {
+		if _, ok := applied[m.Name]; ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.run(ctx, db, m.Name, "up", m.Up); err != nil {
+			return fmt.Errorf("migrating %s: %w", m.Name, err)
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (migration, batch, applied_at) VALUES (%s, %s, %s)",
+			r.provider.qualifiedTable(r.table), r.provider.placeholder(1), r.provider.placeholder(2), r.provider.placeholder(3))
+		if _, err := db.ExecContext(ctx, query, m.Name, batch, time.Now()); err != nil {
+			return fmt.Errorf("recording %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Down reverts the migrations of the last batch, most recent first
+func (r *Runner) Down(db DB) error {
+	return r.DownContext(context.Background(), db)
+}
+
+// DownContext is like Down but stops before the next migration once ctx is done
+func (r *Runner) DownContext(ctx context.Context, db DB) error {
+	return r.rollback(ctx, db, func(applied []appliedMigration) []appliedMigration {
+		if len(applied) == 0 {
+			return nil
+		}
+		return inBatch(applied, applied[0].batch)
+	})
+}
+
+// RollbackBatch reverts the migrations of batch, most recent first, such as
+// the batch of a failed release that later batches do not depend on. Status
+// reports the batch of each migration.
+func (r *Runner) RollbackBatch(db DB, batch int) error {
+	return r.RollbackBatchContext(context.Background(), db, batch)
+}
+
+// RollbackBatchContext is like RollbackBatch but stops before the next migration once ctx is done
+func (r *Runner) RollbackBatchContext(ctx context.Context, db DB, batch int) error {
+	return r.rollback(ctx, db, func(applied []appliedMigration) []appliedMigration {
+		return inBatch(applied, batch)
+	})
+}
+
+// RollbackSteps reverts the last n applied migrations, most recent first,
+// whatever their batch
+func (r *Runner) RollbackSteps(db DB, n int) error {
+	return r.RollbackStepsContext(context.Background(), db, n)
+}
+
+// RollbackStepsContext is like RollbackSteps but stops before the next migration once ctx is done
+func (r *Runner) RollbackStepsContext(ctx context.Context, db DB, n int) error {
+	return r.rollback(ctx, db, func(applied []appliedMigration) []appliedMigration {
+		return applied[:min(max(n, 0), len(applied))]
+	})
+}
+
+// Reset reverts every applied migration, most recent first
+func (r *Runner) Reset(db DB) error {
+	return r.ResetContext(context.Background(), db)
+}
+
+// ResetContext is like Reset but stops before the next migration once ctx is done
+func (r *Runner) ResetContext(ctx context.Context, db DB) error {
+	return r.rollback(ctx, db, func(applied []appliedMigration) []appliedMigration {
+		return applied
+	})
+}
+
+// appliedMigration is a row of the migrations table
+type appliedMigration struct {
+	name  string
+	batch int
+}
+
+func inBatch(applied []appliedMigration, batch int) []appliedMigration {
+	var migrations []appliedMigration
+	for _, m := range applied {
+		if m.batch == batch {
+			migrations = append(migrations, m)
+		}
+	}
+	return migrations
+}
+
+// rollback reverts the migrations pick selects among the applied ones, which
+// it receives most recent first. Every selected migration is checked for a
+// down path before the first one is reverted.
+func (r *Runner) rollback(ctx context.Context, db DB, pick func([]appliedMigration) []appliedMigration) error {
+	if config.IsProtected() && !r.force {
+		return ErrProduction
+	}
+
+	unlock, err := r.lock(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := r.ensureTable(ctx, db); err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT migration, batch FROM %s ORDER BY id DESC", r.provider.qualifiedTable(r.table)))
+	if err != nil {
+		return err
+	}
+	var applied []appliedMigration
+	for rows.Next() {
+		var m appliedMigration
+		if err := rows.Scan(&m.name, &m.batch); err != nil {
+			rows.Close()
+			return err
+		}
+		applied = append(applied, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	picked := pick(applied)
+	downs := make([]MigrationFunc, len(picked))
+	for i, applied := range picked {
+		m, ok := r.find(applied.name)
+		if !ok {
+			return fmt.Errorf("rolling back %s: migration is not registered", applied.name)
+		}
+		if m.Down == nil {
+			return fmt.Errorf("rolling back %s: migration has no down path", applied.name)
+		}
+		downs[i] = m.Down
+	}
+
+	for i, applied := range picked {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.run(ctx, db, applied.name, "down", downs[i]); err != nil {
+			return fmt.Errorf("rolling back %s: %w", applied.name, err)
+		}
+
+		query := fmt.Sprintf("DELETE FROM %s WHERE migration = %s", r.provider.qualifiedTable(r.table), r.provider.placeholder(1))
+		if _, err := db.ExecContext(ctx, query, applied.name); err != nil {
+			return fmt.Errorf("unrecording %s: %w", applied.name, err)
+		}
+	}
+	return nil
+}
+
+// Status is the state of a migration as reported by Runner.Status
+type Status struct {
+	Name string
+	// Batch is the batch the migration was applied in, 0 while it is pending
+	Batch int
+	// Registered is false for applied migrations the Runner does not know about
+	Registered bool
+}
+
+// Status lists the registered migrations in name order along with applied
+// migrations that are no longer registered
+func (r *Runner) Status(ctx context.Context, db DB) ([]Status, error) {
+	if err := r.ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT migration, batch FROM %s", r.provider.qualifiedTable(r.table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := map[string]int{}
+	for rows.Next() {
+		var name string
+		var batch int
+		if err := rows.Scan(&name, &batch); err != nil {
+			return nil, err
+		}
+		batches[name] = batch
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range r.sorted() {
+		statuses = append(statuses, Status{Name: m.Name, Batch: batches[m.Name], Registered: true})
+		delete(batches, m.Name)
+	}
+	for name, batch := range batches {
+		statuses = append(statuses, Status{Name: name, Batch: batch})
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, nil
+}
+
+// Version returns the name of the last applied migration, "" when none was,
+// without creating the migrations table, for version endpoints and health checks
+func (r *Runner) Version(ctx context.Context, db DB) (string, error) {
+	exists, err := r.provider.HasTableContext(ctx, db, r.table)
+	if err != nil || !exists {
+		return "", err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT migration FROM %s ORDER BY id DESC", r.provider.qualifiedTable(r.table)))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var name string
+	if rows.Next() {
+		if err := rows.Scan(&name); err != nil {
+			return "", err
+		}
+	}
+	return name, rows.Err()
+}
+
+// applied returns the names of the applied migrations
+func (r *Runner) applied(ctx context.Context, db DB) (map[string]struct{}, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT migration FROM %s", r.provider.qualifiedTable(r.table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]struct{}{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		applied[name] = struct{}{}
+	}
+	return applied, rows.Err()
+}
+
+// lastBatch returns the number of the most recent batch, 0 when nothing was applied
+func (r *Runner) lastBatch(ctx context.Context, db DB) (int, error) {
+	var batch sql.NullInt64
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(batch) FROM %s", r.provider.qualifiedTable(r.table))).Scan(&batch)
+	return int(batch.Int64), err
+}
+
+// lock waits for the migration lock, so of the instances deploying at the
+// same time one applies the pending migrations and the others find none left.
+// The advisory locks pin a connection, db must allow one more than the
+// migrations use. They need a lock.Pool to pin it from, such as *sql.DB,
+// sqlx.DB or the DB of Pgx, so without a Lock a *sql.Tx or *sql.Conn
+// migrates unlocked, the caller serializing deploys.
+func (r *Runner) lock(ctx context.Context, db DB) (func(), error) {
+	locker := r.locker
+	if pool, ok := db.(lock.Pool); ok && locker == nil {
+		locker = r.provider.locker(pool)
+	}
+	if locker == nil {
+		return func() {}, nil
+	}
+
+	l, err := lock.Wait(ctx, locker, "migrations:"+r.provider.qualifiedTable(r.table), r.lockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("migrations: acquiring the migration lock: %w", err)
+	}
+	return func() { l.Release(context.Background()) }, nil
+}
+
+// ensureTable creates the tracking table on first use
+func (r *Runner) ensureTable(ctx context.Context, db DB) error {
+	exists, err := r.provider.HasTableContext(ctx, db, r.table)
+	if err != nil || exists {
+		return err
+	}
+	return r.provider.createMigrationsTable(ctx, db, r.table)
+}
+
+// sorted returns the registered migrations in name order
+func (r *Runner) sorted() []Migration {
+	migrations := append([]Migration{}, r.migrations...)
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Name < migrations[j].Name
+	})
+	return migrations
+}
+
+func (r *Runner) find(name string) (Migration, bool) {
+	for _, m := range r.migrations {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
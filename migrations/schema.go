@@ -0,0 +1,197 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+)
+
+// Dialect returns the provider for a database/sql driver name
+func Dialect(driver string) (Provider, error) {
+	switch driver {
+	case "mysql":
+		return MySQL, nil
+	case "mariadb":
+		return MariaDB, nil
+	case "tidb":
+		return TiDB, nil
+	case "vitess":
+		return Vitess, nil
+	case "postgres", "postgresql", "pgx":
+		return PostgreSQL, nil
+	case "cockroach", "cockroachdb":
+		return CockroachDB, nil
+	case "sqlserver", "mssql", "azuresql":
+		return SQLServer, nil
+	}
+	return nil, fmt.Errorf("migrations: unsupported driver %q", driver)
+}
+
+// Schema runs dialect agnostic migrations against a database, so code
+// shared between MySQL, PostgreSQL and SQL Server applications uses the generic
+// Blueprint instead of branching on the provider
+type Schema struct {
+	db       DB
+	provider Provider
+}
+
+// New returns a Schema for db using the dialect of the driver name, as passed
+// to sql.Open. It panics for drivers without a provider, see Dialect.
+func New(db DB, driver string) *Schema {
+	provider, err := Dialect(driver)
+	if err != nil {
+		panic(err)
+	}
+	return NewSchema(db, provider)
+}
+
+// NewSchema returns a Schema for db using provider
+func NewSchema(db DB, provider Provider) *Schema {
+	return &Schema{db: db, provider: provider}
+}
+
+// Provider returns the dialect provider of the schema
+func (s *Schema) Provider() Provider {
+	return s.provider
+}
+
+func (s *Schema) Create(tableName string, callback func(Blueprint)) error {
+	return s.CreateContext(context.Background(), tableName, callback)
+}
+
+func (s *Schema) CreateContext(ctx context.Context, tableName string, callback func(Blueprint)) error {
+	sqls, err := s.provider.createSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.provider.exec(ctx, s.db, sqls)
+}
+
+// CreateTemporary creates a table only the connection creating it sees,
+// dropped when it closes
+func (s *Schema) CreateTemporary(tableName string, callback func(Blueprint)) error {
+	return s.CreateTemporaryContext(context.Background(), tableName, callback)
+}
+
+func (s *Schema) CreateTemporaryContext(ctx context.Context, tableName string, callback func(Blueprint)) error {
+	sqls, err := s.provider.createTemporarySQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.provider.exec(ctx, s.db, sqls)
+}
+
+func (s *Schema) Table(tableName string, callback func(Blueprint)) error {
+	return s.TableContext(context.Background(), tableName, callback)
+}
+
+func (s *Schema) TableContext(ctx context.Context, tableName string, callback func(Blueprint)) error {
+	sqls, err := s.provider.tableSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.provider.exec(ctx, s.db, sqls)
+}
+
+func (s *Schema) Rollback(tableName string, callback func(Blueprint)) error {
+	return s.RollbackContext(context.Background(), tableName, callback)
+}
+
+func (s *Schema) RollbackContext(ctx context.Context, tableName string, callback func(Blueprint)) error {
+	sqls, err := s.provider.rollbackSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.provider.exec(ctx, s.db, sqls)
+}
+
+func (s *Schema) CreateSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.provider.createSQL(tableName, callback)
+}
+
+func (s *Schema) CreateTemporarySQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.provider.createTemporarySQL(tableName, callback)
+}
+
+func (s *Schema) TableSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.provider.tableSQL(tableName, callback)
+}
+
+func (s *Schema) RollbackSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.provider.rollbackSQL(tableName, callback)
+}
+
+func (s *Schema) Drop(tableName string) error {
+	return s.provider.DropContext(context.Background(), s.db, tableName)
+}
+
+func (s *Schema) DropIfExists(tableName string) error {
+	return s.provider.DropIfExistsContext(context.Background(), s.db, tableName)
+}
+
+func (s *Schema) CreateView(name, query string) error {
+	return s.provider.CreateViewContext(context.Background(), s.db, name, query)
+}
+
+func (s *Schema) DropView(name string) error {
+	return s.provider.DropViewContext(context.Background(), s.db, name)
+}
+
+func (s *Schema) CreateTrigger(trigger Trigger) error {
+	return s.provider.CreateTriggerContext(context.Background(), s.db, trigger)
+}
+
+func (s *Schema) DropTrigger(table, name string) error {
+	return s.provider.DropTriggerContext(context.Background(), s.db, table, name)
+}
+
+func (s *Schema) CreateFunction(name, definition string) error {
+	return s.provider.CreateFunctionContext(context.Background(), s.db, name, definition)
+}
+
+func (s *Schema) DropFunction(name string) error {
+	return s.provider.DropFunctionContext(context.Background(), s.db, name)
+}
+
+func (s *Schema) CreateProcedure(name, definition string) error {
+	return s.provider.CreateProcedureContext(context.Background(), s.db, name, definition)
+}
+
+func (s *Schema) DropProcedure(name string) error {
+	return s.provider.DropProcedureContext(context.Background(), s.db, name)
+}
+
+func (s *Schema) Rename(from, to string) error {
+	return s.provider.RenameContext(context.Background(), s.db, from, to)
+}
+
+func (s *Schema) Truncate(tableName string) error {
+	return s.provider.TruncateContext(context.Background(), s.db, tableName)
+}
+
+func (s *Schema) DropAllTables() error {
+	return s.provider.DropAllTablesContext(context.Background(), s.db)
+}
+
+func (s *Schema) Tables() ([]string, error) {
+	return s.provider.TablesContext(context.Background(), s.db)
+}
+
+func (s *Schema) TruncateAll(except ...string) error {
+	return s.provider.TruncateAllContext(context.Background(), s.db, except...)
+}
+
+func (s *Schema) HasTable(tableName string) (bool, error) {
+	return s.provider.HasTableContext(context.Background(), s.db, tableName)
+}
+
+func (s *Schema) HasColumn(tableName, columnName string) (bool, error) {
+	return s.provider.HasColumnContext(context.Background(), s.db, tableName, columnName)
+}
+
+func (s *Schema) Columns(tableName string) ([]ColumnInfo, error) {
+	return s.provider.ColumnsContext(context.Background(), s.db, tableName)
+}
+
+func (s *Schema) Indexes(tableName string) ([]IndexInfo, error) {
+	return s.provider.IndexesContext(context.Background(), s.db, tableName)
+}
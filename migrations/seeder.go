@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Seeder fills the database with data, for development or a fresh install
+type Seeder interface {
+	Run(ctx context.Context, db *sql.DB) error
+}
+
+// SeederFunc adapts a function to the Seeder interface
+type SeederFunc func(ctx context.Context, db *sql.DB) error
+
+func (f SeederFunc) Run(ctx context.Context, db *sql.DB) error {
+	return f(ctx, db)
+}
+
+type namedSeeder struct {
+	name   string
+	seeder Seeder
+}
+
+var seeders []namedSeeder
+
+// RegisterSeeder adds a seeder run by Seed, replacing any registered under the same name
+func RegisterSeeder(name string, seeder Seeder) {
+	for i, s := range seeders {
+		if s.name == name {
+			seeders[i].seeder = seeder
+			return
+		}
+	}
+	seeders = append(seeders, namedSeeder{name: name, seeder: seeder})
+}
+
+// Seed runs the seeders with the given names, or every registered seeder in
+// registration order when no name is given
+func Seed(ctx context.Context, db *sql.DB, names ...string) error {
+	run := seeders
+	if len(names) > 0 {
+		run = nil
+		for _, name := range names {
+			s, ok := findSeeder(name)
+			if !ok {
+				return fmt.Errorf("migrations: unknown seeder %q", name)
+			}
+			run = append(run, s)
+		}
+	}
+
+	for _, s := range run {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.seeder.Run(ctx, db); err != nil {
+			return fmt.Errorf("seeding %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func findSeeder(name string) (namedSeeder, bool) {
+	for _, s := range seeders {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return namedSeeder{}, false
+}
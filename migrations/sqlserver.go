@@ -0,0 +1,978 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-bold/bold/lock"
+)
+
+// SQLServer is the provider for SQL Server 2016 and later and Azure SQL,
+// used with a driver accepting @p1 style parameters such as go-mssqldb
+var SQLServer = &sqlserverProvider{transaction: true}
+
+type sqlserverProvider struct {
+	transaction bool
+	// schema qualifies every table, the default schema of the login applies when empty
+	schema string
+}
+
+type sqlserverBlueprint struct {
+	*blueprint
+	schema string
+}
+
+func (s *sqlserverProvider) newBlueprint(tableName string) *sqlserverBlueprint {
+	return &sqlserverBlueprint{blueprint: newBlueprint(tableName), schema: s.schema}
+}
+
+// WithTransaction returns a copy of the provider with transactions enabled or
+// disabled. SQL Server rolls DDL back with the transaction, so they are
+// enabled by default.
+func (s *sqlserverProvider) WithTransaction(enabled bool) *sqlserverProvider {
+	c := *s
+	c.transaction = enabled
+	return &c
+}
+
+// WithSchema returns a copy of the provider qualifying every table with
+// schema and introspecting schema instead of dbo. The migrations table lives
+// in schema too, which is created with it.
+func (s *sqlserverProvider) WithSchema(schema string) *sqlserverProvider {
+	c := *s
+	c.schema = schema
+	return &c
+}
+
+// schemaName returns the schema introspection queries look in
+func (s *sqlserverProvider) schemaName() string {
+	if s.schema == "" {
+		return "dbo"
+	}
+	return s.schema
+}
+
+// qualify returns the quoted table name, prefixed by the schema when set
+func (s *sqlserverProvider) qualify(tableName string) string {
+	return sqlserverQualify(s.schema, tableName)
+}
+
+func sqlserverQualify(schema, name string) string {
+	if schema == "" {
+		return sqlserverQuote(name)
+	}
+	return sqlserverQuote(schema) + "." + sqlserverQuote(name)
+}
+
+func sqlserverQuote(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+func (s *sqlserverProvider) Create(db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	return s.CreateContext(context.Background(), db, tableName, callback)
+}
+
+func (s *sqlserverProvider) CreateContext(ctx context.Context, db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	sqls, err := s.CreateSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, db, sqls)
+}
+
+func (s *sqlserverProvider) Table(db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	return s.TableContext(context.Background(), db, tableName, callback)
+}
+
+func (s *sqlserverProvider) TableContext(ctx context.Context, db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	sqls, err := s.TableSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, db, sqls)
+}
+
+// Rollback reverts what Table applies for the same callback, dropping the
+// added foreign keys, indexes and columns in reverse order. Use Drop to revert Create.
+func (s *sqlserverProvider) Rollback(db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	return s.RollbackContext(context.Background(), db, tableName, callback)
+}
+
+func (s *sqlserverProvider) RollbackContext(ctx context.Context, db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	sqls, err := s.RollbackSQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, db, sqls)
+}
+
+// CreateSQL returns the statements Create would execute, without touching the
+// database, or an error when the blueprint uses what the dialect does not support
+func (s *sqlserverProvider) CreateSQL(tableName string, callback func(MSSQLBlueprint)) ([]string, error) {
+	bp := s.newBlueprint(tableName)
+	callback(bp)
+	if err := errors.Join(bp.verifyCreate(), bp.validate()); err != nil {
+		return nil, err
+	}
+
+	sqls := []string{bp.toCreateTableSQL()}
+	sqls = append(sqls, bp.toIndexSQL()...)
+	sqls = append(sqls, bp.toForeignKeySQL()...)
+	return append(sqls, bp.toCommentSQL()...), nil
+}
+
+// TableSQL returns the statements Table would execute, without touching the database
+func (s *sqlserverProvider) TableSQL(tableName string, callback func(MSSQLBlueprint)) ([]string, error) {
+	bp := s.newBlueprint(tableName)
+	callback(bp)
+	if err := errors.Join(bp.verify(), bp.validate()); err != nil {
+		return nil, err
+	}
+	sqls := bp.toAlterSQL()
+	if err := bp.verifyAlter(sqls); err != nil {
+		return nil, err
+	}
+	return sqls, nil
+}
+
+// RollbackSQL returns the statements Rollback would execute, without touching the database
+func (s *sqlserverProvider) RollbackSQL(tableName string, callback func(MSSQLBlueprint)) ([]string, error) {
+	bp := s.newBlueprint(tableName)
+	callback(bp)
+	if err := errors.Join(bp.verify(), bp.validate()); err != nil {
+		return nil, err
+	}
+	return bp.toRollbackSQL(), nil
+}
+
+// CreateTemporary creates a #table dropped when the connection creating it
+// closes. Only that connection sees the table, see the MySQL provider.
+func (s *sqlserverProvider) CreateTemporary(db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	return s.CreateTemporaryContext(context.Background(), db, tableName, callback)
+}
+
+func (s *sqlserverProvider) CreateTemporaryContext(ctx context.Context, db DB, tableName string, callback func(MSSQLBlueprint)) error {
+	sqls, err := s.CreateTemporarySQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, db, sqls)
+}
+
+// CreateTemporarySQL returns the statements CreateTemporary would execute, without touching the database
+func (s *sqlserverProvider) CreateTemporarySQL(tableName string, callback func(MSSQLBlueprint)) ([]string, error) {
+	bp := s.newBlueprint(tableName)
+	// temporary tables live in tempdb, named with a leading #
+	bp.temporary, bp.schema = true, ""
+	callback(bp)
+	if err := errors.Join(bp.verifyCreate(), bp.validate()); err != nil {
+		return nil, err
+	}
+	return append([]string{bp.toCreateTableSQL()}, bp.toIndexSQL()...), nil
+}
+
+func (s *sqlserverProvider) exec(ctx context.Context, db DB, sqls []string) error {
+	return execStatements(ctx, db, sqls, s.transaction)
+}
+
+// copySQL allows explicit ids in the identity column, if any, for the copy
+func (s *sqlserverProvider) copySQL(tableName string, columns []ColumnInfo) (before, after []string) {
+	for _, column := range columns {
+		if column.AutoIncrement {
+			return []string{"SET IDENTITY_INSERT " + s.qualify(tableName) + " ON"},
+				[]string{"SET IDENTITY_INSERT " + s.qualify(tableName) + " OFF"}
+		}
+	}
+	return nil, nil
+}
+
+func (s *sqlserverProvider) Drop(db DB, tableName string) error {
+	return s.DropContext(context.Background(), db, tableName)
+}
+
+func (s *sqlserverProvider) DropContext(ctx context.Context, db DB, tableName string) error {
+	sql := fmt.Sprintf("DROP TABLE %s", s.qualify(tableName))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (s *sqlserverProvider) DropIfExists(db DB, tableName string) error {
+	return s.DropIfExistsContext(context.Background(), db, tableName)
+}
+
+func (s *sqlserverProvider) DropIfExistsContext(ctx context.Context, db DB, tableName string) error {
+	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s", s.qualify(tableName))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateView creates a view selecting query, such as "SELECT ... FROM orders GROUP BY ..."
+func (s *sqlserverProvider) CreateView(db DB, name, query string) error {
+	return s.CreateViewContext(context.Background(), db, name, query)
+}
+
+func (s *sqlserverProvider) CreateViewContext(ctx context.Context, db DB, name, query string) error {
+	sql := fmt.Sprintf("CREATE VIEW %s AS %s", s.qualify(name), query)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (s *sqlserverProvider) DropView(db DB, name string) error {
+	return s.DropViewContext(context.Background(), db, name)
+}
+
+func (s *sqlserverProvider) DropViewContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP VIEW %s", s.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (s *sqlserverProvider) Rename(db DB, from, to string) error {
+	return s.RenameContext(context.Background(), db, from, to)
+}
+
+func (s *sqlserverProvider) RenameContext(ctx context.Context, db DB, from, to string) error {
+	sql := fmt.Sprintf("EXEC sp_rename %s, %s", quoteString(s.qualify(from)), quoteString(to))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (s *sqlserverProvider) HasTable(db DB, tableName string) (bool, error) {
+	return s.HasTableContext(context.Background(), db, tableName)
+}
+
+func (s *sqlserverProvider) HasTableContext(ctx context.Context, db DB, tableName string) (bool, error) {
+	query := "SELECT COUNT(*) FROM sys.tables t JOIN sys.schemas s ON s.schema_id = t.schema_id WHERE s.name = @p1 AND t.name = @p2"
+	var count int
+	err := db.QueryRowContext(ctx, query, s.schemaName(), tableName).Scan(&count)
+	return count > 0, err
+}
+
+func (s *sqlserverProvider) HasColumn(db DB, tableName, columnName string) (bool, error) {
+	return s.HasColumnContext(context.Background(), db, tableName, columnName)
+}
+
+func (s *sqlserverProvider) HasColumnContext(ctx context.Context, db DB, tableName, columnName string) (bool, error) {
+	query := `SELECT COUNT(*) FROM sys.columns c
+		JOIN sys.tables t ON t.object_id = c.object_id
+		JOIN sys.schemas s ON s.schema_id = t.schema_id
+		WHERE s.name = @p1 AND t.name = @p2 AND c.name = @p3`
+	var count int
+	err := db.QueryRowContext(ctx, query, s.schemaName(), tableName, columnName).Scan(&count)
+	return count > 0, err
+}
+
+// Columns returns the columns of a table in table order
+func (s *sqlserverProvider) Columns(db DB, tableName string) ([]ColumnInfo, error) {
+	return s.ColumnsContext(context.Background(), db, tableName)
+}
+
+func (s *sqlserverProvider) ColumnsContext(ctx context.Context, db DB, tableName string) ([]ColumnInfo, error) {
+	// max_length counts bytes, two per character of the Unicode types
+	query := `SELECT c.name,
+			ty.name + CASE
+				WHEN ty.name IN ('varchar', 'char', 'varbinary', 'binary') THEN '(' + IIF(c.max_length = -1, 'max', CAST(c.max_length AS varchar(10))) + ')'
+				WHEN ty.name IN ('nvarchar', 'nchar') THEN '(' + IIF(c.max_length = -1, 'max', CAST(c.max_length / 2 AS varchar(10))) + ')'
+				WHEN ty.name IN ('decimal', 'numeric') THEN '(' + CAST(c.precision AS varchar(10)) + ',' + CAST(c.scale AS varchar(10)) + ')'
+				ELSE '' END,
+			c.is_nullable, dc.definition,
+			CAST(IIF(EXISTS (SELECT 1 FROM sys.index_columns ic JOIN sys.indexes i ON i.object_id = ic.object_id AND i.index_id = ic.index_id
+				WHERE i.is_primary_key = 1 AND ic.object_id = c.object_id AND ic.column_id = c.column_id), 1, 0) AS bit),
+			c.is_identity
+		FROM sys.columns c
+		JOIN sys.tables t ON t.object_id = c.object_id
+		JOIN sys.schemas s ON s.schema_id = t.schema_id
+		JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+		LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+		WHERE s.name = @p1 AND t.name = @p2
+		ORDER BY c.column_id`
+	return queryColumns(ctx, db, query, s.schemaName(), tableName)
+}
+
+// Indexes returns the indexes of a table in name order, including the
+// primary key. Algorithm is CLUSTERED or NONCLUSTERED.
+func (s *sqlserverProvider) Indexes(db DB, tableName string) ([]IndexInfo, error) {
+	return s.IndexesContext(context.Background(), db, tableName)
+}
+
+func (s *sqlserverProvider) IndexesContext(ctx context.Context, db DB, tableName string) ([]IndexInfo, error) {
+	query := `SELECT i.name, c.name, i.is_unique, i.is_primary_key, i.type_desc, i.filter_definition
+		FROM sys.indexes i
+		JOIN sys.tables t ON t.object_id = i.object_id
+		JOIN sys.schemas s ON s.schema_id = t.schema_id
+		JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE s.name = @p1 AND t.name = @p2 AND i.name IS NOT NULL AND ic.is_included_column = 0
+		ORDER BY i.name, ic.key_ordinal`
+	return queryIndexes(ctx, db, query, s.schemaName(), tableName)
+}
+
+// Truncate empties the table and restarts its identity. SQL Server refuses
+// to truncate tables other tables reference, see TruncateAll.
+func (s *sqlserverProvider) Truncate(db DB, tableName string) error {
+	return s.TruncateContext(context.Background(), db, tableName)
+}
+
+func (s *sqlserverProvider) TruncateContext(ctx context.Context, db DB, tableName string) error {
+	sql := fmt.Sprintf("TRUNCATE TABLE %s", s.qualify(tableName))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// Tables lists the tables of the schema, dbo by default
+func (s *sqlserverProvider) Tables(db DB) ([]string, error) {
+	return s.TablesContext(context.Background(), db)
+}
+
+func (s *sqlserverProvider) TablesContext(ctx context.Context, db DB) ([]string, error) {
+	return queryStrings(ctx, db, "SELECT t.name FROM sys.tables t JOIN sys.schemas s ON s.schema_id = t.schema_id WHERE s.name = @p1 ORDER BY t.name", s.schemaName())
+}
+
+// DropAllTables drops every table of the schema, dbo by default, including
+// the migrations table. Foreign keys are dropped first, as SQL Server has no CASCADE.
+func (s *sqlserverProvider) DropAllTables(db DB) error {
+	return s.DropAllTablesContext(context.Background(), db)
+}
+
+func (s *sqlserverProvider) DropAllTablesContext(ctx context.Context, db DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT OBJECT_NAME(fk.parent_object_id), fk.name FROM sys.foreign_keys fk
+		JOIN sys.schemas s ON s.schema_id = fk.schema_id WHERE s.name = @p1`, s.schemaName())
+	if err != nil {
+		return err
+	}
+	var sqls []string
+	for rows.Next() {
+		var table, name string
+		if err := rows.Scan(&table, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", s.qualify(table), sqlserverQuote(name)))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tables, err := s.TablesContext(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		sqls = append(sqls, fmt.Sprintf("DROP TABLE %s", s.qualify(table)))
+	}
+	return execStatements(ctx, db, sqls, false)
+}
+
+// TruncateAll empties every table of the schema but the except ones. SQL
+// Server cannot truncate referenced tables, so rows are deleted with the
+// constraints disabled, and identities keep counting.
+func (s *sqlserverProvider) TruncateAll(db DB, except ...string) error {
+	return s.TruncateAllContext(context.Background(), db, except...)
+}
+
+func (s *sqlserverProvider) TruncateAllContext(ctx context.Context, db DB, except ...string) error {
+	tables, err := s.TablesContext(ctx, db)
+	if err != nil {
+		return err
+	}
+	tables = slices.DeleteFunc(tables, func(table string) bool {
+		return slices.Contains(except, table)
+	})
+	if len(tables) == 0 {
+		return nil
+	}
+
+	var sqls []string
+	for _, table := range tables {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s NOCHECK CONSTRAINT ALL", s.qualify(table)))
+	}
+	for _, table := range tables {
+		sqls = append(sqls, fmt.Sprintf("DELETE FROM %s", s.qualify(table)))
+	}
+	for _, table := range tables {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s WITH CHECK CHECK CONSTRAINT ALL", s.qualify(table)))
+	}
+	return execStatements(ctx, db, sqls, true)
+}
+
+// CreateTrigger creates a trigger running Body once per statement, reading
+// the changed rows from the inserted and deleted tables. SQL Server has no
+// BEFORE triggers, use AFTER or INSTEAD OF.
+func (s *sqlserverProvider) CreateTrigger(db DB, trigger Trigger) error {
+	return s.CreateTriggerContext(context.Background(), db, trigger)
+}
+
+func (s *sqlserverProvider) CreateTriggerContext(ctx context.Context, db DB, trigger Trigger) error {
+	if err := trigger.validate(); err != nil {
+		return err
+	}
+	if strings.EqualFold(trigger.Timing, "BEFORE") {
+		return unsupported("SQL Server", trigger.Table, fmt.Sprintf("BEFORE trigger %s is", trigger.Name))
+	}
+	sql := fmt.Sprintf("CREATE TRIGGER %s ON %s %s %s AS %s",
+		s.qualify(trigger.Name), s.qualify(trigger.Table), strings.ToUpper(trigger.Timing), strings.ToUpper(trigger.Event), trigger.Body)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// DropTrigger drops a trigger, SQL Server names triggers per schema so table is unused
+func (s *sqlserverProvider) DropTrigger(db DB, table, name string) error {
+	return s.DropTriggerContext(context.Background(), db, table, name)
+}
+
+func (s *sqlserverProvider) DropTriggerContext(ctx context.Context, db DB, table, name string) error {
+	sql := fmt.Sprintf("DROP TRIGGER %s", s.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateFunction creates a function from its definition following the name,
+// such as "(@price DECIMAL(8,2)) RETURNS DECIMAL(8,2) AS BEGIN RETURN @price * 1.2 END"
+func (s *sqlserverProvider) CreateFunction(db DB, name, definition string) error {
+	return s.CreateFunctionContext(context.Background(), db, name, definition)
+}
+
+func (s *sqlserverProvider) CreateFunctionContext(ctx context.Context, db DB, name, definition string) error {
+	sql := fmt.Sprintf("CREATE FUNCTION %s%s", s.qualify(name), definition)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (s *sqlserverProvider) DropFunction(db DB, name string) error {
+	return s.DropFunctionContext(context.Background(), db, name)
+}
+
+func (s *sqlserverProvider) DropFunctionContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP FUNCTION %s", s.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+// CreateProcedure creates a procedure from its definition following the
+// name, such as " @days INT AS DELETE FROM sessions WHERE ..."
+func (s *sqlserverProvider) CreateProcedure(db DB, name, definition string) error {
+	return s.CreateProcedureContext(context.Background(), db, name, definition)
+}
+
+func (s *sqlserverProvider) CreateProcedureContext(ctx context.Context, db DB, name, definition string) error {
+	sql := fmt.Sprintf("CREATE PROCEDURE %s%s", s.qualify(name), definition)
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (s *sqlserverProvider) DropProcedure(db DB, name string) error {
+	return s.DropProcedureContext(context.Background(), db, name)
+}
+
+func (s *sqlserverProvider) DropProcedureContext(ctx context.Context, db DB, name string) error {
+	sql := fmt.Sprintf("DROP PROCEDURE %s", s.qualify(name))
+	_, err := db.ExecContext(ctx, sql)
+	return err
+}
+
+func (bp *sqlserverBlueprint) ID() ColumnBuilder {
+	return bp.AddColumn("id", "BIGINT IDENTITY(1,1) PRIMARY KEY")
+}
+
+func (bp *sqlserverBlueprint) String(name string, length int) ColumnBuilder {
+	return bp.AddColumn(name, fmt.Sprintf("NVARCHAR(%d)", length))
+}
+
+func (bp *sqlserverBlueprint) VarChar(name string, length int) ColumnBuilder {
+	return bp.AddColumn(name, fmt.Sprintf("VARCHAR(%d)", length))
+}
+
+func (bp *sqlserverBlueprint) Text(name string) ColumnBuilder {
+	return bp.AddColumn(name, "NVARCHAR(MAX)")
+}
+
+// Float adds a single precision column, SQL Server's FLOAT is a double precision one
+func (bp *sqlserverBlueprint) Float(name string) ColumnBuilder {
+	return bp.AddColumn(name, "REAL")
+}
+
+func (bp *sqlserverBlueprint) Double(name string) ColumnBuilder {
+	return bp.AddColumn(name, "FLOAT")
+}
+
+func (bp *sqlserverBlueprint) Boolean(name string) ColumnBuilder {
+	return bp.AddColumn(name, "BIT")
+}
+
+func (bp *sqlserverBlueprint) DateTime(name string) ColumnBuilder {
+	return bp.AddColumn(name, "DATETIME2")
+}
+
+// Timestamp adds a DATETIME2 column, SQL Server's TIMESTAMP is a row version
+func (bp *sqlserverBlueprint) Timestamp(name string) ColumnBuilder {
+	return bp.AddColumn(name, "DATETIME2")
+}
+
+func (bp *sqlserverBlueprint) DateTimeOffset(name string) ColumnBuilder {
+	return bp.AddColumn(name, "DATETIMEOFFSET")
+}
+
+// TimestampTz adds a DATETIMEOFFSET column, see DateTimeOffset
+func (bp *sqlserverBlueprint) TimestampTz(name string) ColumnBuilder {
+	return bp.DateTimeOffset(name)
+}
+
+// JSON adds an NVARCHAR(MAX) column, SQL Server stores JSON as text
+func (bp *sqlserverBlueprint) JSON(name string) ColumnBuilder {
+	return bp.AddColumn(name, "NVARCHAR(MAX)")
+}
+
+func (bp *sqlserverBlueprint) Binary(name string) ColumnBuilder {
+	return bp.AddColumn(name, "VARBINARY(MAX)")
+}
+
+func (bp *sqlserverBlueprint) UUID(name string) ColumnBuilder {
+	return bp.AddColumn(name, "UNIQUEIDENTIFIER")
+}
+
+// UUIDPrimary adds a UUID id primary key generated by the database
+func (bp *sqlserverBlueprint) UUIDPrimary() ColumnBuilder {
+	return bp.AddColumn("id", "UNIQUEIDENTIFIER DEFAULT NEWID() PRIMARY KEY")
+}
+
+func (bp *sqlserverBlueprint) Money(name string) ColumnBuilder {
+	return bp.AddColumn(name, "MONEY")
+}
+
+func (bp *sqlserverBlueprint) RowVersion(name string) ColumnBuilder {
+	return bp.AddColumn(name, "ROWVERSION")
+}
+
+func (bp *sqlserverBlueprint) Identity(name string, seed, increment int) ColumnBuilder {
+	return bp.AddColumn(name, fmt.Sprintf("BIGINT IDENTITY(%d,%d)", seed, increment))
+}
+
+func (bp *sqlserverBlueprint) Timestamps() {
+	bp.AddColumn("created_at", "DATETIME2 DEFAULT CURRENT_TIMESTAMP")
+	bp.AddColumn("updated_at", "DATETIME2 DEFAULT CURRENT_TIMESTAMP")
+}
+
+func (bp *sqlserverBlueprint) TimestampsTz() {
+	bp.AddColumn("created_at", "DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET()")
+	bp.AddColumn("updated_at", "DATETIMEOFFSET DEFAULT SYSDATETIMEOFFSET()")
+}
+
+func (bp *sqlserverBlueprint) NullableTimestamps() {
+	bp.Timestamp("created_at").Nullable()
+	bp.Timestamp("updated_at").Nullable()
+}
+
+func (bp *sqlserverBlueprint) SoftDeletes() ColumnBuilder {
+	return bp.Timestamp("deleted_at").Nullable()
+}
+
+func (bp *sqlserverBlueprint) PartialIndex(columns []string, where string) {
+	bp.indexes = append(bp.indexes, &index{
+		kind:    indexPlain,
+		name:    strings.Join(columns, "_") + "_partial_index",
+		columns: columns,
+		where:   where,
+	})
+}
+
+// table returns the quoted table name, prefixed by the schema when set, or
+// by # for temporary tables
+func (bp *sqlserverBlueprint) table() string {
+	if bp.temporary {
+		return sqlserverQuote("#" + bp.tableName)
+	}
+	return sqlserverQualify(bp.schema, bp.tableName)
+}
+
+// qualifyForeign returns the quoted referenced table, in the schema of the blueprint
+func (bp *sqlserverBlueprint) qualifyForeign(tableName string) string {
+	return sqlserverQualify(bp.schema, tableName)
+}
+
+func (bp *sqlserverBlueprint) columnList(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = sqlserverQuote(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (bp *sqlserverBlueprint) toCreateTableSQL() string {
+	var parts []string
+
+	for _, column := range bp.columns {
+		parts = append(parts, bp.columnSQL(column))
+	}
+
+	for _, index := range bp.indexes {
+		if index.kind == indexPrimary {
+			parts = append(parts, fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", sqlserverQuote(index.name), bp.columnList(index.columns)))
+		}
+	}
+
+	for _, check := range bp.checks {
+		parts = append(parts, check.clause(sqlserverQuote))
+	}
+
+	return bp.unlessTable(fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", bp.table(), strings.Join(parts, ",\n  ")))
+}
+
+func (bp *sqlserverBlueprint) toIndexSQL() []string {
+	var sqls []string
+
+	for _, index := range bp.indexes {
+		if index.kind != indexPrimary {
+			sqls = append(sqls, bp.indexSQL(index))
+		}
+	}
+
+	return sqls
+}
+
+func (bp *sqlserverBlueprint) toForeignKeySQL() []string {
+	var sqls []string
+
+	for _, foreign := range bp.completeForeigns() {
+		sqls = append(sqls, bp.unlessConstraint(foreign.name, fmt.Sprintf("ALTER TABLE %s ADD %s", bp.table(), sqlserverForeign(foreign).clause(sqlserverQuote, bp.qualifyForeign))))
+	}
+
+	return sqls
+}
+
+// sqlserverForeign returns fk with RESTRICT actions as NO ACTION, which SQL
+// Server names them and checks the same way but at the end of the statement
+func sqlserverForeign(fk *foreignKey) *foreignKey {
+	c := *fk
+	if strings.EqualFold(c.onDelete, "RESTRICT") {
+		c.onDelete = "NO ACTION"
+	}
+	if strings.EqualFold(c.onUpdate, "RESTRICT") {
+		c.onUpdate = "NO ACTION"
+	}
+	return &c
+}
+
+func (bp *sqlserverBlueprint) toAlterSQL() []string {
+	var sqls []string
+
+	for _, name := range bp.droppedForeigns {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", bp.table(), sqlserverQuote(name)))
+	}
+
+	for _, name := range bp.droppedIndexes {
+		sqls = append(sqls, fmt.Sprintf("DROP INDEX %s ON %s", sqlserverQuote(name), bp.table()))
+	}
+
+	for _, rename := range bp.renamedIndexes {
+		sqls = append(sqls, bp.renameIndexSQL(rename.from, rename.to))
+	}
+
+	for _, rename := range bp.renames {
+		sqls = append(sqls, bp.renameColumnSQL(rename.from, rename.to))
+	}
+
+	for _, name := range bp.drops {
+		// the default constraint Create named would block the drop
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", bp.table(), bp.defaultName(name)))
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", bp.table(), sqlserverQuote(name)))
+	}
+
+	for _, column := range bp.columns {
+		if column.Change {
+			sqls = append(sqls, bp.changeColumnSQL(column)...)
+			continue
+		}
+		sqls = append(sqls, bp.unlessColumn(column.Name, fmt.Sprintf("ALTER TABLE %s ADD %s", bp.table(), bp.columnSQL(column))))
+	}
+
+	for _, index := range bp.indexes {
+		if index.kind == indexPrimary {
+			sqls = append(sqls, bp.unlessIndex(index.name, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s PRIMARY KEY (%s)", bp.table(), sqlserverQuote(index.name), bp.columnList(index.columns))))
+			continue
+		}
+		sqls = append(sqls, bp.indexSQL(index))
+	}
+
+	for _, check := range bp.checks {
+		sqls = append(sqls, bp.unlessConstraint(check.name, fmt.Sprintf("ALTER TABLE %s ADD %s", bp.table(), check.clause(sqlserverQuote))))
+	}
+
+	sqls = append(sqls, bp.toForeignKeySQL()...)
+	return append(sqls, bp.toCommentSQL()...)
+}
+
+// toRollbackSQL reverses toAlterSQL: check constraints, foreign keys, then indexes, then added columns are
+// dropped and renamed columns and indexes get their old name back. Dropped columns, indexes and
+// foreign keys and changed columns cannot be restored as their previous definition is unknown.
+func (bp *sqlserverBlueprint) toRollbackSQL() []string {
+	var sqls []string
+
+	for i := len(bp.checks) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", bp.table(), sqlserverQuote(bp.checks[i].name)))
+	}
+
+	foreigns := bp.completeForeigns()
+	for i := len(foreigns) - 1; i >= 0; i-- {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", bp.table(), sqlserverQuote(foreigns[i].name)))
+	}
+
+	for i := len(bp.indexes) - 1; i >= 0; i-- {
+		index := bp.indexes[i]
+		if index.kind == indexPrimary {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", bp.table(), sqlserverQuote(index.name)))
+			continue
+		}
+		sqls = append(sqls, fmt.Sprintf("DROP INDEX %s ON %s", sqlserverQuote(index.name), bp.table()))
+	}
+
+	for i := len(bp.columns) - 1; i >= 0; i-- {
+		column := bp.columns[i]
+		if column.Change {
+			continue
+		}
+		if bp.hasDefault(column) {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", bp.table(), bp.defaultName(column.Name)))
+		}
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", bp.table(), sqlserverQuote(column.Name)))
+	}
+
+	for i := len(bp.renames) - 1; i >= 0; i-- {
+		rename := bp.renames[i]
+		sqls = append(sqls, bp.renameColumnSQL(rename.to, rename.from))
+	}
+
+	for i := len(bp.renamedIndexes) - 1; i >= 0; i-- {
+		rename := bp.renamedIndexes[i]
+		sqls = append(sqls, bp.renameIndexSQL(rename.to, rename.from))
+	}
+
+	return sqls
+}
+
+func (bp *sqlserverBlueprint) renameColumnSQL(from, to string) string {
+	return fmt.Sprintf("EXEC sp_rename %s, %s, 'COLUMN'", quoteString(bp.table()+"."+sqlserverQuote(from)), quoteString(to))
+}
+
+func (bp *sqlserverBlueprint) renameIndexSQL(from, to string) string {
+	return fmt.Sprintf("EXEC sp_rename %s, %s, 'INDEX'", quoteString(bp.table()+"."+sqlserverQuote(from)), quoteString(to))
+}
+
+// defaultName returns the name of the default constraint of a column. Naming
+// it lets later migrations change or drop it, SQL Server generates random
+// names otherwise.
+func (bp *sqlserverBlueprint) defaultName(column string) string {
+	return sqlserverQuote(fmt.Sprintf("df_%s_%s", bp.tableName, column))
+}
+
+func (bp *sqlserverBlueprint) hasDefault(column *Column) bool {
+	return column.Default != nil && column.Generated == "" || strings.Contains(column.Type, " DEFAULT ")
+}
+
+// changeColumnSQL alters the type and nullability of an existing column, and
+// replaces its default, which SQL Server keeps in a separate constraint
+func (bp *sqlserverBlueprint) changeColumnSQL(column *Column) []string {
+	columnType := column.Type
+	if column.Collation != "" {
+		columnType += " COLLATE " + column.Collation
+	}
+	null := " NOT NULL"
+	if column.Nullable {
+		null = " NULL"
+	}
+
+	sqls := []string{
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", bp.table(), bp.defaultName(column.Name)),
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s%s", bp.table(), sqlserverQuote(column.Name), columnType, null),
+	}
+	if column.Default != nil {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s DEFAULT %s FOR %s",
+			bp.table(), bp.defaultName(column.Name), bp.formatDefaultValue(column.Default), sqlserverQuote(column.Name)))
+	}
+	return sqls
+}
+
+func (bp *sqlserverBlueprint) columnSQL(column *Column) string {
+	name := sqlserverQuote(column.Name)
+	// computed columns take their type from the expression
+	if column.Generated != "" {
+		columnSQL := fmt.Sprintf("%s AS (%s)", name, column.Generated)
+		if column.Stored {
+			columnSQL += " PERSISTED"
+		}
+		return columnSQL
+	}
+
+	// name the defaults declared within the type, such as those of Timestamps
+	columnType := strings.Replace(column.Type, " DEFAULT ", " CONSTRAINT "+bp.defaultName(column.Name)+" DEFAULT ", 1)
+	columnSQL := fmt.Sprintf("%s %s", name, columnType)
+
+	if column.Collation != "" {
+		columnSQL += " COLLATE " + column.Collation
+	}
+
+	if column.AutoIncrement {
+		columnSQL += " IDENTITY(1,1)"
+	}
+
+	if column.Nullable {
+		columnSQL += " NULL"
+	} else {
+		columnSQL += " NOT NULL"
+	}
+
+	if column.Default != nil {
+		columnSQL += fmt.Sprintf(" CONSTRAINT %s DEFAULT %s", bp.defaultName(column.Name), bp.formatDefaultValue(column.Default))
+	}
+
+	if column.Unique {
+		columnSQL += " UNIQUE"
+	}
+
+	if column.Primary {
+		columnSQL += " PRIMARY KEY"
+	}
+
+	return columnSQL
+}
+
+func (bp *sqlserverBlueprint) formatDefaultValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "N" + quoteString(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int, int64, float64:
+		return fmt.Sprintf("%v", v)
+	default:
+		return quoteString(fmt.Sprint(v))
+	}
+}
+
+func (bp *sqlserverBlueprint) indexSQL(index *index) string {
+	unique := ""
+	if index.kind == indexUnique {
+		unique = "UNIQUE "
+	}
+	sql := fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, sqlserverQuote(index.name), bp.table(), bp.columnList(index.columns))
+	if index.where != "" {
+		sql += " WHERE " + index.where
+	}
+	return bp.unlessIndex(index.name, sql)
+}
+
+// toCommentSQL stores the table and column comments as MS_Description
+// extended properties, which SQL Server Management Studio shows
+func (bp *sqlserverBlueprint) toCommentSQL() []string {
+	if bp.temporary {
+		return nil
+	}
+	var sqls []string
+	if bp.comment != "" {
+		sqls = append(sqls, bp.descriptionSQL(bp.comment, ""))
+	}
+	for _, column := range bp.columns {
+		if column.Comment != "" {
+			sqls = append(sqls, bp.descriptionSQL(column.Comment, column.Name))
+		}
+	}
+	return sqls
+}
+
+// descriptionSQL sets the description of the table, or of its column when
+// column is not empty, updating an existing one
+func (bp *sqlserverBlueprint) descriptionSQL(text, column string) string {
+	schema := bp.schema
+	if schema == "" {
+		schema = "dbo"
+	}
+	target := fmt.Sprintf("N'SCHEMA', %s, N'TABLE', %s", quoteString(schema), quoteString(bp.tableName))
+	minor := "0"
+	if column != "" {
+		target += ", N'COLUMN', " + quoteString(column)
+		minor = fmt.Sprintf("COLUMNPROPERTY(OBJECT_ID(%s), %s, 'ColumnId')", quoteString(bp.table()), quoteString(column))
+	}
+	return fmt.Sprintf("IF EXISTS (SELECT 1 FROM sys.extended_properties WHERE major_id = OBJECT_ID(%s) AND minor_id = %s AND name = N'MS_Description') "+
+		"EXEC sp_updateextendedproperty N'MS_Description', %s, %s ELSE EXEC sp_addextendedproperty N'MS_Description', %s, %s",
+		quoteString(bp.table()), minor, "N"+quoteString(text), target, "N"+quoteString(text), target)
+}
+
+func (s *sqlserverProvider) placeholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
+
+// maxParams is the most parameters a SQL Server request can pass
+func (s *sqlserverProvider) maxParams() int {
+	return 2100
+}
+
+// maxInsertRows is the most row value expressions an INSERT VALUES list takes
+func (s *sqlserverProvider) maxInsertRows() int {
+	return 1000
+}
+
+// qualifiedTable returns the migrations table for the queries of the Runner,
+// prefixed by the schema when set
+func (s *sqlserverProvider) qualifiedTable(tableName string) string {
+	if s.schema == "" {
+		return tableName
+	}
+	return s.qualify(tableName)
+}
+
+func (s *sqlserverProvider) quoteTable(tableName string) string {
+	return s.qualify(tableName)
+}
+
+func (s *sqlserverProvider) quote(name string) string {
+	return sqlserverQuote(name)
+}
+
+func (s *sqlserverProvider) createMigrationsTable(ctx context.Context, db DB, tableName string) error {
+	if s.schema != "" {
+		// CREATE SCHEMA must be the only statement of its batch
+		sql := fmt.Sprintf("IF SCHEMA_ID(%s) IS NULL EXEC(%s)", quoteString(s.schema), quoteString("CREATE SCHEMA "+sqlserverQuote(s.schema)))
+		if _, err := db.ExecContext(ctx, sql); err != nil {
+			return err
+		}
+	}
+	return s.CreateContext(ctx, db, tableName, func(table MSSQLBlueprint) {
+		table.ID()
+		table.String("migration", 255)
+		table.Integer("batch")
+		table.Timestamp("applied_at")
+	})
+}
+
+// locker returns the application lock Runner holds while migrating
+func (s *sqlserverProvider) locker(db lock.Pool) lock.Locker {
+	return lock.SQLServer(db)
+}
+
+func (s *sqlserverProvider) describe(tableName string, callback func(Blueprint)) *blueprint {
+	bp := s.newBlueprint(tableName)
+	callback(bp)
+	return bp.blueprint
+}
+
+func (s *sqlserverProvider) createSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.CreateSQL(tableName, func(bp MSSQLBlueprint) { callback(bp) })
+}
+
+func (s *sqlserverProvider) createTemporarySQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.CreateTemporarySQL(tableName, func(bp MSSQLBlueprint) { callback(bp) })
+}
+
+func (s *sqlserverProvider) tableSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.TableSQL(tableName, func(bp MSSQLBlueprint) { callback(bp) })
+}
+
+func (s *sqlserverProvider) rollbackSQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return s.RollbackSQL(tableName, func(bp MSSQLBlueprint) { callback(bp) })
+}
@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CreateTemporary creates a table dropped when the connection creating it
+// closes, for ETL style migrations staging rows. Only that connection sees
+// the table, so run the statements filling and reading it on the same
+// *sql.Conn, or on a *sql.DB limited to a single open connection.
+func (m *mysqlProvider) CreateTemporary(db DB, tableName string, callback func(MySQLBlueprint)) error {
+	return m.CreateTemporaryContext(context.Background(), db, tableName, callback)
+}
+
+func (m *mysqlProvider) CreateTemporaryContext(ctx context.Context, db DB, tableName string, callback func(MySQLBlueprint)) error {
+	sqls, err := m.CreateTemporarySQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return m.exec(ctx, db, sqls)
+}
+
+// CreateTemporarySQL returns the statements CreateTemporary would execute, without touching the database
+func (m *mysqlProvider) CreateTemporarySQL(tableName string, callback func(MySQLBlueprint)) ([]string, error) {
+	bp := m.newBlueprint(tableName)
+	bp.temporary = true
+	callback(bp)
+	if err := errors.Join(bp.verifyCreate(), bp.validate()); err != nil {
+		return nil, err
+	}
+	return []string{bp.toCreateSQL()}, nil
+}
+
+func (m *mysqlProvider) createTemporarySQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return m.CreateTemporarySQL(tableName, func(bp MySQLBlueprint) { callback(bp) })
+}
+
+// CreateTemporary creates a table dropped at the end of the session creating
+// it, in the temporary schema of the session whatever WithSchema sets. Only
+// that session sees the table, see the MySQL provider.
+func (p *postgresqlProvider) CreateTemporary(db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	return p.CreateTemporaryContext(context.Background(), db, tableName, callback)
+}
+
+func (p *postgresqlProvider) CreateTemporaryContext(ctx context.Context, db DB, tableName string, callback func(PostgreSQLBlueprint)) error {
+	sqls, err := p.CreateTemporarySQL(tableName, callback)
+	if err != nil {
+		return err
+	}
+	return p.exec(ctx, db, sqls)
+}
+
+// CreateTemporarySQL returns the statements CreateTemporary would execute, without touching the database
+func (p *postgresqlProvider) CreateTemporarySQL(tableName string, callback func(PostgreSQLBlueprint)) ([]string, error) {
+	bp := p.newBlueprint(tableName)
+	// temporary tables cannot be created in a regular schema
+	bp.temporary, bp.schema = true, ""
+	callback(bp)
+	if err := errors.Join(bp.verifyCreate(), bp.validate()); err != nil {
+		return nil, err
+	}
+
+	sqls := []string{bp.toCreateTableSQL()}
+	sqls = append(sqls, bp.toPartitionTablesSQL()...)
+	sqls = append(sqls, bp.toIndexSQL()...)
+	sqls = append(sqls, bp.toTriggerSQL()...)
+	return append(sqls, bp.toCommentSQL()...), nil
+}
+
+func (p *postgresqlProvider) createTemporarySQL(tableName string, callback func(Blueprint)) ([]string, error) {
+	return p.CreateTemporarySQL(tableName, func(bp PostgreSQLBlueprint) { callback(bp) })
+}
+
+// Unlogged skips the write-ahead log for the table, making writes much faster
+// for staging and cache tables. Unlogged tables are emptied after a crash and
+// not replicated. Table switches an existing table, Rollback switches it back.
+func (bp *postgresqlBlueprint) Unlogged() {
+	bp.unlogged = true
+}
+
+// createTable returns the CREATE TABLE keywords for the kind of table
+func (b *blueprint) createTable() string {
+	create := "CREATE TABLE"
+	if b.temporary {
+		create = "CREATE TEMPORARY TABLE"
+	}
+	if b.skipExisting {
+		create += " IF NOT EXISTS"
+	}
+	return create
+}
+
+func (bp *postgresqlBlueprint) createTable() string {
+	switch {
+	case bp.unlogged:
+		return "CREATE UNLOGGED TABLE" + bp.ifNotExists()
+	case bp.temporary:
+		return "CREATE TEMPORARY TABLE" + bp.ifNotExists()
+	}
+	return "CREATE TABLE" + bp.ifNotExists()
+}
+
+func (bp *postgresqlBlueprint) toLoggedSQL(logged bool) []string {
+	if !bp.unlogged {
+		return nil
+	}
+	if logged {
+		return []string{fmt.Sprintf("ALTER TABLE %s SET LOGGED", bp.table())}
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s SET UNLOGGED", bp.table())}
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidBlueprint is wrapped by the errors of blueprints that would
+// produce broken or no SQL, returned before any statement runs
+var ErrInvalidBlueprint = errors.New("invalid blueprint")
+
+func invalid(table, what string) error {
+	return fmt.Errorf("migrations: %s: %s: %w", table, what, ErrInvalidBlueprint)
+}
+
+// foreignKeyActions are the referential actions of ON DELETE and ON UPDATE
+var foreignKeyActions = map[string]bool{"CASCADE": true, "SET NULL": true, "SET DEFAULT": true, "RESTRICT": true, "NO ACTION": true}
+
+// verify rejects foreign keys missing On or References, unknown referential
+// actions and columns added twice
+func (b *blueprint) verify() error {
+	var errs []error
+	for _, fk := range b.foreigns {
+		if fk.foreignTable == "" {
+			errs = append(errs, invalid(b.tableName, fmt.Sprintf("foreign key %s has no referenced table, call On", fk.name)))
+		}
+		if fk.foreignColumn == "" {
+			errs = append(errs, invalid(b.tableName, fmt.Sprintf("foreign key %s has no referenced column, call References", fk.name)))
+		}
+		for _, action := range []string{fk.onDelete, fk.onUpdate} {
+			if action != "" && !foreignKeyActions[strings.ToUpper(action)] {
+				errs = append(errs, invalid(b.tableName, fmt.Sprintf("foreign key %s has unknown action %q", fk.name, action)))
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, column := range b.columns {
+		if seen[column.Name] {
+			errs = append(errs, invalid(b.tableName, fmt.Sprintf("column %s is added twice", column.Name)))
+		}
+		seen[column.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// verifyCreate also rejects tables without columns
+func (b *blueprint) verifyCreate() error {
+	if len(b.columns) == 0 {
+		return errors.Join(b.verify(), invalid(b.tableName, "the table has no columns"))
+	}
+	return b.verify()
+}
+
+// verifyAlter rejects blueprints changing nothing, given the statements they produce
+func (b *blueprint) verifyAlter(sqls []string) error {
+	if len(sqls) == 0 {
+		return invalid(b.tableName, "the blueprint changes nothing")
+	}
+	return nil
+}
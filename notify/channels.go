@@ -0,0 +1,289 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/go-bold/bold/migrations"
+)
+
+// Mail is the mail channel payload
+type Mail struct {
+	Subject string
+	Body    string
+	HTML    bool
+}
+
+// MailNotification is a notification deliverable over the mail channel
+type MailNotification interface {
+	ToMail(to Notifiable) Mail
+}
+
+// MailSender sends an email
+type MailSender interface {
+	SendMail(ctx context.Context, to string, mail Mail) error
+}
+
+// MailChannel delivers notifications by email
+type MailChannel struct {
+	Sender MailSender
+}
+
+func (c MailChannel) Send(ctx context.Context, to Notifiable, n Notification) error {
+	mn, ok := n.(MailNotification)
+	if !ok {
+		return fmt.Errorf("%T does not implement MailNotification", n)
+	}
+	return c.Sender.SendMail(ctx, to.NotificationRoute("mail"), mn.ToMail(to))
+}
+
+// SMTPSender sends mail through an SMTP server
+type SMTPSender struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// SendMail sends mail to the address to. The address and subject are encoded
+// so neither can inject headers, and the SMTP conversation is abandoned when
+// ctx is done.
+func (s SMTPSender) SendMail(ctx context.Context, to string, mail Mail) error {
+	from, err := netmail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("notify: invalid sender %q: %w", s.From, err)
+	}
+	rcpt, err := netmail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("notify: invalid recipient %q: %w", to, err)
+	}
+
+	contentType := "text/plain"
+	if mail.HTML {
+		contentType = "text/html"
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", rcpt)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", mail.Subject))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s; charset=UTF-8\r\n\r\n", contentType)
+	msg.WriteString(mail.Body)
+
+	return s.send(ctx, from.Address, rcpt.Address, []byte(msg.String()))
+}
+
+// send runs the SMTP conversation of smtp.SendMail on a connection closed
+// when ctx is done
+func (s SMTPSender) send(ctx context.Context, from, to string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return contextErr(ctx, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return contextErr(ctx, err)
+		}
+	}
+	if s.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(s.Auth); err != nil {
+				return contextErr(ctx, err)
+			}
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return contextErr(ctx, err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return contextErr(ctx, err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return contextErr(ctx, err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return contextErr(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return contextErr(ctx, err)
+	}
+	return contextErr(ctx, c.Quit())
+}
+
+// contextErr returns the error of ctx when it ended the conversation, err otherwise
+func contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// SMS is the sms channel payload
+type SMS struct {
+	Body string
+}
+
+// SMSNotification is a notification deliverable over the sms channel
+type SMSNotification interface {
+	ToSMS(to Notifiable) SMS
+}
+
+// SMSSender sends a text message through a gateway such as Twilio or SNS
+type SMSSender interface {
+	SendSMS(ctx context.Context, to string, sms SMS) error
+}
+
+// SMSChannel delivers notifications by text message
+type SMSChannel struct {
+	Sender SMSSender
+}
+
+func (c SMSChannel) Send(ctx context.Context, to Notifiable, n Notification) error {
+	sn, ok := n.(SMSNotification)
+	if !ok {
+		return fmt.Errorf("%T does not implement SMSNotification", n)
+	}
+	return c.Sender.SendSMS(ctx, to.NotificationRoute("sms"), sn.ToSMS(to))
+}
+
+// WebhookNotification is a notification deliverable over the webhook channel
+type WebhookNotification interface {
+	// ToWebhook returns the value posted as the JSON request body
+	ToWebhook(to Notifiable) any
+}
+
+// WebhookChannel delivers notifications by posting JSON to the recipient URL
+type WebhookChannel struct {
+	Client *http.Client
+}
+
+func (c WebhookChannel) Send(ctx context.Context, to Notifiable, n Notification) error {
+	wn, ok := n.(WebhookNotification)
+	if !ok {
+		return fmt.Errorf("%T does not implement WebhookNotification", n)
+	}
+
+	body, err := json.Marshal(wn.ToWebhook(to))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, to.NotificationRoute("webhook"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// DatabaseNotification is a notification stored for in-app display
+type DatabaseNotification interface {
+	ToDatabase(to Notifiable) map[string]any
+}
+
+// DatabaseChannel stores notifications in a table created with Blueprint
+type DatabaseChannel struct {
+	DB    *sql.DB
+	Table string
+	// PostgreSQL selects $n placeholders instead of ?
+	PostgreSQL bool
+}
+
+func (c DatabaseChannel) Send(ctx context.Context, to Notifiable, n Notification) error {
+	dn, ok := n.(DatabaseNotification)
+	if !ok {
+		return fmt.Errorf("%T does not implement DatabaseNotification", n)
+	}
+
+	data, err := json.Marshal(dn.ToDatabase(to))
+	if err != nil {
+		return err
+	}
+
+	table := c.Table
+	if table == "" {
+		table = "notifications"
+	}
+	query := fmt.Sprintf("INSERT INTO %s (type, notifiable, data, created_at, updated_at) VALUES (?, ?, ?, ?, ?)", table)
+	if c.PostgreSQL {
+		query = fmt.Sprintf("INSERT INTO %s (type, notifiable, data, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)", table)
+	}
+
+	now := time.Now()
+	_, err = c.DB.ExecContext(ctx, query, fmt.Sprintf("%T", n), to.NotificationRoute("database"), string(data), now, now)
+	return err
+}
+
+// MarkRead marks a stored notification as read
+func (c DatabaseChannel) MarkRead(ctx context.Context, id int64) error {
+	table := c.Table
+	if table == "" {
+		table = "notifications"
+	}
+	query := fmt.Sprintf("UPDATE %s SET read_at = ? WHERE id = ?", table)
+	if c.PostgreSQL {
+		query = fmt.Sprintf("UPDATE %s SET read_at = $1 WHERE id = $2", table)
+	}
+
+	res, err := c.DB.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("notify: notification not found")
+	}
+	return nil
+}
+
+// Blueprint declares the notifications table columns, for use inside a
+// migrations Create callback of either dialect
+func Blueprint(t migrations.Blueprint) {
+	t.ID()
+	t.String("type", 255)
+	t.String("notifiable", 255).Index()
+	t.Text("data")
+	t.Timestamp("read_at").Nullable()
+	t.Timestamps()
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Notifiable is a recipient of notifications, such as a user
+type Notifiable interface {
+	// NotificationRoute returns the address used by a channel: an email
+	// address for "mail", a phone number for "sms", a URL for "webhook" and
+	// the recipient key for "database"
+	NotificationRoute(channel string) string
+}
+
+// Notification is a message delivered over the channels it declares
+type Notification interface {
+	Via(to Notifiable) []string
+}
+
+// Channel delivers notifications over one medium
+type Channel interface {
+	Send(ctx context.Context, to Notifiable, n Notification) error
+}
+
+// Dispatcher runs deliveries asynchronously, e.g. by pushing them to a job queue
+type Dispatcher interface {
+	Dispatch(ctx context.Context, job func(ctx context.Context) error) error
+}
+
+// Notifier routes notifications to their channels
+type Notifier struct {
+	channels   map[string]Channel
+	dispatcher Dispatcher
+	// OnError is called with failures of asynchronous deliveries
+	OnError func(err error)
+}
+
+func New() *Notifier {
+	return &Notifier{channels: map[string]Channel{}}
+}
+
+// Channel registers a channel under name
+func (n *Notifier) Channel(name string, ch Channel) *Notifier {
+	n.channels[name] = ch
+	return n
+}
+
+// UseDispatcher sets the dispatcher used by SendAsync
+func (n *Notifier) UseDispatcher(d Dispatcher) *Notifier {
+	n.dispatcher = d
+	return n
+}
+
+// Send delivers the notification to every recipient over each channel it declares
+func (n *Notifier) Send(ctx context.Context, notification Notification, to ...Notifiable) error {
+	var errs []error
+	for _, recipient := range to {
+		for _, name := range notification.Via(recipient) {
+			ch, ok := n.channels[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("notify: unknown channel %q", name))
+				continue
+			}
+			if err := ch.Send(ctx, recipient, notification); err != nil {
+				errs = append(errs, fmt.Errorf("notify: %s channel: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendAsync hands the delivery to the dispatcher, or to a goroutine when none is set
+func (n *Notifier) SendAsync(ctx context.Context, notification Notification, to ...Notifiable) error {
+	job := func(ctx context.Context) error {
+		return n.Send(ctx, notification, to...)
+	}
+
+	if n.dispatcher != nil {
+		return n.dispatcher.Dispatch(ctx, job)
+	}
+
+	go func() {
+		if err := job(context.WithoutCancel(ctx)); err != nil && n.OnError != nil {
+			n.OnError(err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-bold/bold/routing"
+)
+
+// ErrInvalidState is returned when the callback state does not match the one issued at redirect
+var ErrInvalidState = errors.New("oauth: invalid state")
+
+// LoginFunc is called with the resolved identity once the provider callback
+// succeeded; it is where the identity is mapped to a local user and signed in
+type LoginFunc func(w http.ResponseWriter, r *http.Request, identity *Identity)
+
+// ErrorFunc is called when the provider callback fails
+type ErrorFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+const flowCookieMaxAge = 10 * time.Minute
+
+// Redirect returns a handler starting the login flow: it stores the state and
+// PKCE verifier in a short-lived cookie and redirects to the provider
+func (p *Provider) Redirect() routing.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := randomString()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		verifier, err := randomString()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     p.cookieName(),
+			Value:    state + "." + verifier,
+			Path:     "/",
+			MaxAge:   int(flowCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			Secure:   !p.InsecureCookie,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, p.AuthCodeURL(state, verifier), http.StatusFound)
+	}
+}
+
+// Callback returns the handler for the provider redirect URL. It checks the
+// state, exchanges the code, fetches the identity and hands it to login.
+func (p *Provider) Callback(login LoginFunc, fail ErrorFunc) routing.HandlerFunc {
+	if fail == nil {
+		fail = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(p.cookieName())
+		http.SetCookie(w, &http.Cookie{Name: p.cookieName(), Path: "/", MaxAge: -1})
+		if err != nil {
+			fail(w, r, ErrInvalidState)
+			return
+		}
+
+		state, verifier, _ := strings.Cut(cookie.Value, ".")
+		if state == "" || r.URL.Query().Get("state") != state {
+			fail(w, r, ErrInvalidState)
+			return
+		}
+		if msg := r.URL.Query().Get("error"); msg != "" {
+			fail(w, r, errors.New("oauth: provider returned error: "+msg))
+			return
+		}
+
+		token, err := p.Exchange(r.Context(), r.URL.Query().Get("code"), verifier)
+		if err != nil {
+			fail(w, r, err)
+			return
+		}
+		identity, err := p.Identity(r.Context(), token)
+		if err != nil {
+			fail(w, r, err)
+			return
+		}
+		login(w, r, identity)
+	}
+}
+
+func (p *Provider) cookieName() string {
+	return "bold_oauth_" + p.Name
+}
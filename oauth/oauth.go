@@ -0,0 +1,278 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// Provider describes an OAuth2 / OpenID Connect identity provider
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+	// MapIdentity converts the user info response into an Identity, the
+	// standard OIDC claims are used when nil
+	MapIdentity func(claims map[string]any) Identity
+	// Client performs the discovery, token and user info requests, http.DefaultClient when nil
+	Client *http.Client
+	// InsecureCookie lets the flow cookie travel over plain HTTP, for local
+	// development. It is Secure otherwise, even behind a TLS terminating proxy.
+	InsecureCookie bool
+	// Clock tells when tokens expire, clock.Default() when nil
+	Clock clock.Clock
+}
+
+// Token is the token endpoint response
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	ExpiresIn    int       `json:"expires_in,omitempty"`
+	Expiry       time.Time `json:"-"`
+}
+
+// Identity is the provider-independent user identity resolved after login
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	AvatarURL     string
+	Claims        map[string]any
+	Token         *Token
+}
+
+// Google returns a provider configured for Google sign-in
+func Google(clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Name:         "google",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// GitHub returns a provider configured for GitHub sign-in
+func GitHub(clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Name:         "github",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		UserInfoURL:  "https://api.github.com/user",
+		Scopes:       []string{"read:user", "user:email"},
+		MapIdentity: func(claims map[string]any) Identity {
+			return Identity{
+				Subject:   claimString(claims, "id"),
+				Email:     claimString(claims, "email"),
+				Name:      firstNonEmpty(claimString(claims, "name"), claimString(claims, "login")),
+				AvatarURL: claimString(claims, "avatar_url"),
+			}
+		},
+	}
+}
+
+// Discover returns a generic OIDC provider configured from the issuer's
+// /.well-known/openid-configuration document, fetched with
+// http.DefaultClient. Use Provider.Discover to fetch it with a Client.
+func Discover(ctx context.Context, name, issuer, clientID, clientSecret, redirectURL string) (*Provider, error) {
+	p := &Provider{
+		Name:         name,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+	if err := p.Discover(ctx, issuer); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Discover sets the endpoints of p from the issuer's
+// /.well-known/openid-configuration document, fetched with p's Client
+func (p *Provider) Discover(ctx context.Context, issuer string) error {
+	endpoint := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	var doc struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := doJSON(p.client(), req, &doc); err != nil {
+		return fmt.Errorf("oauth: discovering %s: %w", issuer, err)
+	}
+
+	p.AuthURL, p.TokenURL, p.UserInfoURL = doc.AuthorizationEndpoint, doc.TokenEndpoint, doc.UserinfoEndpoint
+	return nil
+}
+
+// AuthCodeURL returns the provider authorization URL for the given state and PKCE verifier
+func (p *Provider) AuthCodeURL(state, verifier string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("scope", strings.Join(p.Scopes, " "))
+	params.Set("state", state)
+	params.Set("code_challenge", challenge(verifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.AuthURL + sep + params.Encode()
+}
+
+// Exchange trades an authorization code for a token
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token Token
+	if err := doJSON(p.client(), req, &token); err != nil {
+		return nil, fmt.Errorf("oauth: exchanging code with %s: %w", p.Name, err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("oauth: %s returned no access token", p.Name)
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = clock.Or(p.Clock).Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
+
+// Identity fetches the user info for token and maps it to an Identity
+func (p *Provider) Identity(ctx context.Context, token *Token) (*Identity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	claims := map[string]any{}
+	if err := doJSON(p.client(), req, &claims); err != nil {
+		return nil, fmt.Errorf("oauth: fetching %s user info: %w", p.Name, err)
+	}
+
+	var identity Identity
+	if p.MapIdentity != nil {
+		identity = p.MapIdentity(claims)
+	} else {
+		identity = Identity{
+			Subject:       claimString(claims, "sub"),
+			Email:         claimString(claims, "email"),
+			EmailVerified: claims["email_verified"] == true,
+			Name:          claimString(claims, "name"),
+			AvatarURL:     claimString(claims, "picture"),
+		}
+	}
+	identity.Provider = p.Name
+	identity.Claims = claims
+	identity.Token = token
+
+	if identity.Subject == "" {
+		return nil, errors.New("oauth: user info has no subject")
+	}
+	return &identity, nil
+}
+
+func (p *Provider) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return http.DefaultClient
+}
+
+// randomString returns a URL-safe random string suitable for state and PKCE verifiers
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// challenge derives the S256 PKCE challenge of a verifier
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func claimString(claims map[string]any, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
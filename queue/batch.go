@@ -0,0 +1,304 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/migrations"
+)
+
+// ErrBatchNotFound is returned when a batch does not exist
+var ErrBatchNotFound = errors.New("queue: batch not found")
+
+// BatchStatus is the progress of a batch of jobs
+type BatchStatus struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Total      int        `json:"total"`
+	Pending    int        `json:"pending"`
+	Failed     int        `json:"failed"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	OnComplete *Envelope  `json:"-"`
+	OnFailure  *Envelope  `json:"-"`
+}
+
+// Progress returns the share of jobs that finished, between 0 and 1
+func (b *BatchStatus) Progress() float64 {
+	if b.Total == 0 {
+		return 1
+	}
+	return float64(b.Total-b.Pending) / float64(b.Total)
+}
+
+// BatchStore tracks the progress of batches
+type BatchStore interface {
+	Create(ctx context.Context, batch *BatchStatus) error
+	Find(ctx context.Context, id string) (*BatchStatus, error)
+	// Record counts a finished job of the batch. It reports whether the caller
+	// must dispatch the failure callback (first failure) and the completion
+	// callback (last job), so each is dispatched exactly once across workers.
+	Record(ctx context.Context, id string, failed bool) (status *BatchStatus, notifyFailure, complete bool, err error)
+}
+
+// BatchBuilder dispatches a set of jobs whose overall progress is tracked
+type BatchBuilder struct {
+	name       string
+	jobs       []Job
+	onComplete Job
+	onFailure  Job
+}
+
+// Batch returns a builder for a batch of jobs
+func Batch(jobs ...Job) *BatchBuilder {
+	return &BatchBuilder{jobs: jobs}
+}
+
+// Name labels the batch for inspection
+func (b *BatchBuilder) Name(name string) *BatchBuilder {
+	b.name = name
+	return b
+}
+
+// OnComplete sets a job dispatched once every job of the batch finished,
+// whether it succeeded or not
+func (b *BatchBuilder) OnComplete(job Job) *BatchBuilder {
+	b.onComplete = job
+	return b
+}
+
+// OnFailure sets a job dispatched when the first job of the batch fails
+func (b *BatchBuilder) OnFailure(job Job) *BatchBuilder {
+	b.onFailure = job
+	return b
+}
+
+// Dispatch records the batch in store and pushes its jobs. Workers handling
+// them must be configured with the same store.
+func (b *BatchBuilder) Dispatch(ctx context.Context, q *Queue, store BatchStore, opts ...Option) (*BatchStatus, error) {
+	status := &BatchStatus{
+		ID:        newID(),
+		Name:      b.name,
+		Total:     len(b.jobs),
+		Pending:   len(b.jobs),
+		CreatedAt: time.Now(),
+	}
+
+	var err error
+	if b.onComplete != nil {
+		if status.OnComplete, err = NewEnvelope(b.onComplete, opts...); err != nil {
+			return nil, err
+		}
+	}
+	if b.onFailure != nil {
+		if status.OnFailure, err = NewEnvelope(b.onFailure, opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	envs := make([]*Envelope, len(b.jobs))
+	for i, job := range b.jobs {
+		if envs[i], err = NewEnvelope(job, opts...); err != nil {
+			return nil, err
+		}
+		if envs[i].UniqueKey != "" {
+			return nil, errors.New("queue: batches cannot be Unique")
+		}
+		envs[i].BatchID = status.ID
+	}
+
+	if err := store.Create(ctx, status); err != nil {
+		return nil, err
+	}
+	for _, env := range envs {
+		if err := q.Backend().Push(ctx, env); err != nil {
+			return status, err
+		}
+	}
+	return status, nil
+}
+
+// MemoryBatchStore tracks batches in process memory
+type MemoryBatchStore struct {
+	mu       sync.Mutex
+	batches  map[string]*BatchStatus
+	notified map[string]bool
+}
+
+func NewMemoryBatchStore() *MemoryBatchStore {
+	return &MemoryBatchStore{batches: map[string]*BatchStatus{}, notified: map[string]bool{}}
+}
+
+func (s *MemoryBatchStore) Create(ctx context.Context, batch *BatchStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *batch
+	s.batches[batch.ID] = &copied
+	return nil
+}
+
+func (s *MemoryBatchStore) Find(ctx context.Context, id string) (*BatchStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch, ok := s.batches[id]
+	if !ok {
+		return nil, ErrBatchNotFound
+	}
+	copied := *batch
+	return &copied, nil
+}
+
+func (s *MemoryBatchStore) Record(ctx context.Context, id string, failed bool) (*BatchStatus, bool, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch, ok := s.batches[id]
+	if !ok {
+		return nil, false, false, ErrBatchNotFound
+	}
+
+	batch.Pending--
+	notifyFailure := false
+	if failed {
+		batch.Failed++
+		notifyFailure = !s.notified[id]
+		s.notified[id] = true
+	}
+
+	complete := batch.Pending == 0 && batch.FinishedAt == nil
+	if complete {
+		now := time.Now()
+		batch.FinishedAt = &now
+	}
+
+	copied := *batch
+	return &copied, notifyFailure, complete, nil
+}
+
+// DatabaseBatchStore tracks batches in a table created with JobBatchesBlueprint
+type DatabaseBatchStore struct {
+	DB    *sql.DB
+	Table string
+	// PostgreSQL selects $n placeholders instead of ?
+	PostgreSQL bool
+}
+
+// JobBatchesBlueprint declares the job batches table columns, for use inside
+// a migrations Create callback of either dialect
+func JobBatchesBlueprint(t migrations.Blueprint) {
+	t.ID()
+	t.String("uuid", 64).Unique()
+	t.String("name", 255)
+	t.Integer("total")
+	t.Integer("pending")
+	t.Integer("failed")
+	t.Integer("failure_notified").Default(0)
+	t.Text("on_complete").Nullable()
+	t.Text("on_failure").Nullable()
+	t.Timestamp("created_at")
+	t.Timestamp("finished_at").Nullable()
+}
+
+func (s DatabaseBatchStore) Create(ctx context.Context, batch *BatchStatus) error {
+	onComplete, err := encodeCallback(batch.OnComplete)
+	if err != nil {
+		return err
+	}
+	onFailure, err := encodeCallback(batch.OnFailure)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.ExecContext(ctx,
+		s.bind("INSERT INTO %s (uuid, name, total, pending, failed, failure_notified, on_complete, on_failure, created_at) VALUES (?, ?, ?, ?, 0, 0, ?, ?, ?)"),
+		batch.ID, batch.Name, batch.Total, batch.Pending, onComplete, onFailure, batch.CreatedAt)
+	return err
+}
+
+func (s DatabaseBatchStore) Find(ctx context.Context, id string) (*BatchStatus, error) {
+	var batch BatchStatus
+	var onComplete, onFailure sql.NullString
+	var finishedAt sql.NullTime
+
+	err := s.DB.QueryRowContext(ctx,
+		s.bind("SELECT uuid, name, total, pending, failed, on_complete, on_failure, created_at, finished_at FROM %s WHERE uuid = ?"), id).
+		Scan(&batch.ID, &batch.Name, &batch.Total, &batch.Pending, &batch.Failed, &onComplete, &onFailure, &batch.CreatedAt, &finishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if finishedAt.Valid {
+		batch.FinishedAt = &finishedAt.Time
+	}
+	if batch.OnComplete, err = decodeCallback(onComplete); err != nil {
+		return nil, err
+	}
+	if batch.OnFailure, err = decodeCallback(onFailure); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (s DatabaseBatchStore) Record(ctx context.Context, id string, failed bool) (*BatchStatus, bool, bool, error) {
+	increment := 0
+	if failed {
+		increment = 1
+	}
+	if _, err := s.DB.ExecContext(ctx, s.bind("UPDATE %s SET pending = pending - 1, failed = failed + ? WHERE uuid = ?"), increment, id); err != nil {
+		return nil, false, false, err
+	}
+
+	notifyFailure := false
+	if failed {
+		res, err := s.DB.ExecContext(ctx, s.bind("UPDATE %s SET failure_notified = 1 WHERE uuid = ? AND failure_notified = 0"), id)
+		if err != nil {
+			return nil, false, false, err
+		}
+		n, _ := res.RowsAffected()
+		notifyFailure = n == 1
+	}
+
+	res, err := s.DB.ExecContext(ctx, s.bind("UPDATE %s SET finished_at = ? WHERE uuid = ? AND pending <= 0 AND finished_at IS NULL"), time.Now(), id)
+	if err != nil {
+		return nil, false, false, err
+	}
+	n, _ := res.RowsAffected()
+
+	batch, err := s.Find(ctx, id)
+	return batch, notifyFailure, n == 1, err
+}
+
+func (s DatabaseBatchStore) bind(query string) string {
+	table := s.Table
+	if table == "" {
+		table = "job_batches"
+	}
+	return rebind(fmt.Sprintf(query, table), s.PostgreSQL)
+}
+
+func encodeCallback(env *Envelope) (sql.NullString, error) {
+	if env == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(env)
+	return sql.NullString{String: string(b), Valid: true}, err
+}
+
+func decodeCallback(value sql.NullString) (*Envelope, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	var env Envelope
+	err := json.Unmarshal([]byte(value.String), &env)
+	return &env, err
+}
@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// ChainBuilder dispatches jobs that run one after another, each only once the previous one succeeded
+type ChainBuilder struct {
+	jobs      []Job
+	onFailure Job
+}
+
+// Chain returns a builder for a chain of jobs
+func Chain(jobs ...Job) *ChainBuilder {
+	return &ChainBuilder{jobs: jobs}
+}
+
+// OnFailure sets a job dispatched when a job of the chain exhausted its tries.
+// Callbacks are jobs rather than funcs so they survive being queued.
+func (c *ChainBuilder) OnFailure(job Job) *ChainBuilder {
+	c.onFailure = job
+	return c
+}
+
+// Dispatch pushes the first job, carrying the rest of the chain along with it
+func (c *ChainBuilder) Dispatch(ctx context.Context, q *Queue, opts ...Option) error {
+	if len(c.jobs) == 0 {
+		return errors.New("queue: empty chain")
+	}
+
+	envs := make([]*Envelope, len(c.jobs))
+	for i, job := range c.jobs {
+		env, err := NewEnvelope(job, opts...)
+		if err != nil {
+			return err
+		}
+		envs[i] = env
+	}
+
+	first := envs[0]
+	first.Chain = envs[1:]
+	if c.onFailure != nil {
+		env, err := NewEnvelope(c.onFailure, opts...)
+		if err != nil {
+			return err
+		}
+		env.UniqueKey = ""
+		first.OnFailure = env
+	}
+
+	// the chain holds one claim, released once its last job settled
+	if err := q.claimUnique(ctx, first); err != nil {
+		return err
+	}
+	for _, env := range first.Chain {
+		env.UniqueToken = first.UniqueToken
+	}
+	return q.Backend().Push(ctx, first)
+}
+
+// next returns the envelope following env in its chain, or nil at the end of the chain
+func (env *Envelope) next() *Envelope {
+	if len(env.Chain) == 0 {
+		return nil
+	}
+	next := env.Chain[0]
+	next.Chain = env.Chain[1:]
+	next.OnFailure = env.OnFailure
+	return next
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-bold/bold/config"
+	"github.com/go-bold/bold/console"
+)
+
+// FailedCommands returns the queue:failed, queue:retry and queue:flush
+// commands managing the failed jobs of store, retried jobs being pushed to
+// backend
+func FailedCommands(store FailedStore, backend Backend) []*console.Command {
+	return []*console.Command{
+		{
+			Name:        "queue:failed",
+			Description: "List the failed jobs",
+			Run: func(ctx context.Context, in *console.Input) error {
+				jobs, err := store.List(ctx)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(in.Out, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tQUEUE\tTYPE\tATTEMPTS\tFAILED AT\tERROR")
+				for _, job := range jobs {
+					env := job.Envelope
+					message, _, _ := strings.Cut(job.Error, "\n")
+					fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", env.ID, env.Queue, env.Type, env.Attempts, job.FailedAt.Format("2006-01-02 15:04:05"), message)
+				}
+				return w.Flush()
+			},
+		},
+		{
+			Name:        "queue:retry",
+			Description: "Push failed jobs back onto their queue, e.g. queue:retry ID... or queue:retry --all",
+			Flags: func(fs *flag.FlagSet) {
+				fs.Bool("all", false, "retry every failed job")
+			},
+			Run: func(ctx context.Context, in *console.Input) error {
+				ids := in.Args
+				if in.Bool("all") {
+					jobs, err := store.List(ctx)
+					if err != nil {
+						return err
+					}
+					ids = nil
+					for _, job := range jobs {
+						ids = append(ids, job.Envelope.ID)
+					}
+				}
+				if len(ids) == 0 {
+					return errors.New("usage: queue:retry ID... | queue:retry --all")
+				}
+				for _, id := range ids {
+					if err := Retry(ctx, store, backend, id); err != nil {
+						return fmt.Errorf("queue: retry %s: %w", id, err)
+					}
+					fmt.Fprintf(in.Out, "Retried %s\n", id)
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "queue:flush",
+			Description: "Delete every failed job",
+			Flags: func(fs *flag.FlagSet) {
+				fs.Bool("force", false, "skip the confirmation in production or with no environment set")
+			},
+			Run: func(ctx context.Context, in *console.Input) error {
+				if !in.Bool("force") && config.IsProtected() && !in.Confirm("The environment is production or not set, delete every failed job anyway?") {
+					fmt.Fprintln(in.Out, "Cancelled")
+					return nil
+				}
+				if err := store.Purge(ctx); err != nil {
+					return err
+				}
+				fmt.Fprintln(in.Out, "Deleted the failed jobs")
+				return nil
+			},
+		},
+	}
+}
@@ -0,0 +1,220 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/migrations"
+)
+
+// ErrFailedJobNotFound is returned when a failed job does not exist
+var ErrFailedJobNotFound = errors.New("queue: failed job not found")
+
+// FailedJob is a job that exhausted its tries
+type FailedJob struct {
+	Envelope *Envelope `json:"envelope"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// FailedStore is the dead-letter store jobs are moved to once they exhausted their tries
+type FailedStore interface {
+	Store(ctx context.Context, job FailedJob) error
+	List(ctx context.Context) ([]FailedJob, error)
+	Find(ctx context.Context, id string) (*FailedJob, error)
+	Delete(ctx context.Context, id string) error
+	Purge(ctx context.Context) error
+}
+
+// Retry pushes a failed job back onto its queue with a fresh set of tries and removes it from the store
+func Retry(ctx context.Context, store FailedStore, backend Backend, id string) error {
+	job, err := store.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	env := *job.Envelope
+	env.Attempts = 0
+	env.AvailableAt = time.Now()
+	if err := backend.Push(ctx, &env); err != nil {
+		return err
+	}
+	return store.Delete(ctx, id)
+}
+
+// MemoryFailedStore keeps failed jobs in process memory
+type MemoryFailedStore struct {
+	mu   sync.Mutex
+	jobs []FailedJob
+}
+
+func NewMemoryFailedStore() *MemoryFailedStore {
+	return &MemoryFailedStore{}
+}
+
+func (s *MemoryFailedStore) Store(ctx context.Context, job FailedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func (s *MemoryFailedStore) List(ctx context.Context) ([]FailedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := slices.Clone(s.jobs)
+	slices.Reverse(jobs)
+	return jobs, nil
+}
+
+func (s *MemoryFailedStore) Find(ctx context.Context, id string) (*FailedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Envelope.ID == id {
+			return &job, nil
+		}
+	}
+	return nil, ErrFailedJobNotFound
+}
+
+func (s *MemoryFailedStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = slices.DeleteFunc(s.jobs, func(job FailedJob) bool {
+		return job.Envelope.ID == id
+	})
+	return nil
+}
+
+func (s *MemoryFailedStore) Purge(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = nil
+	return nil
+}
+
+// DatabaseFailedStore keeps failed jobs in a table created with FailedJobsBlueprint
+type DatabaseFailedStore struct {
+	DB    *sql.DB
+	Table string
+	// PostgreSQL selects $n placeholders instead of ?
+	PostgreSQL bool
+}
+
+// FailedJobsBlueprint declares the failed jobs table columns, for use inside a
+// migrations Create callback of either dialect. The envelope column holds the
+// whole job as JSON, chain and batch included, the others are kept for
+// querying the table.
+func FailedJobsBlueprint(t migrations.Blueprint) {
+	t.ID()
+	t.String("uuid", 64).Unique()
+	t.String("queue", 255)
+	t.String("type", 255)
+	t.Text("payload")
+	t.Integer("attempts")
+	t.Text("error")
+	t.Timestamp("failed_at")
+	t.Text("envelope")
+}
+
+func (s DatabaseFailedStore) Store(ctx context.Context, job FailedJob) error {
+	env := job.Envelope
+	envelope, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	query := s.bind("INSERT INTO %s (uuid, queue, type, payload, attempts, error, failed_at, envelope) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	_, err = s.DB.ExecContext(ctx, query, env.ID, env.Queue, env.Type, string(env.Payload), env.Attempts, job.Error, job.FailedAt, string(envelope))
+	return err
+}
+
+func (s DatabaseFailedStore) List(ctx context.Context) ([]FailedJob, error) {
+	rows, err := s.DB.QueryContext(ctx, s.bind("SELECT envelope, error, failed_at FROM %s ORDER BY id DESC"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []FailedJob
+	for rows.Next() {
+		job, err := scanFailedJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s DatabaseFailedStore) Find(ctx context.Context, id string) (*FailedJob, error) {
+	row := s.DB.QueryRowContext(ctx, s.bind("SELECT envelope, error, failed_at FROM %s WHERE uuid = ?"), id)
+	job, err := scanFailedJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFailedJobNotFound
+	}
+	return job, err
+}
+
+func (s DatabaseFailedStore) Delete(ctx context.Context, id string) error {
+	_, err := s.DB.ExecContext(ctx, s.bind("DELETE FROM %s WHERE uuid = ?"), id)
+	return err
+}
+
+func (s DatabaseFailedStore) Purge(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, s.bind("DELETE FROM %s"))
+	return err
+}
+
+// bind fills in the table name and rewrites placeholders for PostgreSQL
+func (s DatabaseFailedStore) bind(query string) string {
+	table := s.Table
+	if table == "" {
+		table = "failed_jobs"
+	}
+	return rebind(fmt.Sprintf(query, table), s.PostgreSQL)
+}
+
+// rebind rewrites ? placeholders to PostgreSQL's $n form when postgres is set
+func rebind(query string, postgres bool) string {
+	if !postgres {
+		return query
+	}
+
+	var out []byte
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			out = fmt.Appendf(out, "$%d", n)
+			continue
+		}
+		out = append(out, query[i])
+	}
+	return string(out)
+}
+
+func scanFailedJob(row interface{ Scan(...any) error }) (*FailedJob, error) {
+	var job FailedJob
+	var envelope string
+	if err := row.Scan(&envelope, &job.Error, &job.FailedAt); err != nil {
+		return nil, err
+	}
+	var env Envelope
+	if err := json.Unmarshal([]byte(envelope), &env); err != nil {
+		return nil, fmt.Errorf("queue: failed job: %w", err)
+	}
+	job.Envelope = &env
+	return &job, nil
+}
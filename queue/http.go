@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-bold/bold/routing"
+)
+
+// FailedRoutes returns a route group exposing the failed jobs of store:
+//
+//	GET    {prefix}            lists failed jobs
+//	POST   {prefix}/{id}/retry pushes a failed job back onto its queue
+//	DELETE {prefix}/{id}       forgets a failed job
+//	DELETE {prefix}            purges all failed jobs
+//
+// The group carries no authentication; pass the middleware protecting it.
+func FailedRoutes(prefix string, store FailedStore, backend Backend, middlewares ...routing.MiddlewareFunc) *routing.RouteGroup {
+	route := routing.NewRoute()
+
+	return route.Group(prefix,
+		middlewares,
+		route.GET("", func(w http.ResponseWriter, r *http.Request) {
+			jobs, err := store.List(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if jobs == nil {
+				jobs = []FailedJob{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(jobs)
+		}),
+		route.POST("/{id}/retry", func(w http.ResponseWriter, r *http.Request) {
+			err := Retry(r.Context(), store, backend, r.PathValue("id"))
+			writeFailedResult(w, err)
+		}),
+		route.DELETE("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			writeFailedResult(w, store.Delete(r.Context(), r.PathValue("id")))
+		}),
+		route.DELETE("", func(w http.ResponseWriter, r *http.Request) {
+			writeFailedResult(w, store.Purge(r.Context()))
+		}),
+	)
+}
+
+func writeFailedResult(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrFailedJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// ErrDuplicate is returned by Dispatch when a Unique job with the same key is already queued or running
+var ErrDuplicate = errors.New("queue: duplicate unique job")
+
+// Unique dedupes the job: while a job with the same key is queued or running,
+// dispatching another one fails with ErrDuplicate. The claim expires after ttl
+// in case the job is lost, which must be positive. It requires a locker on both
+// the Queue and the Worker. A unique chain holds its claim until its last job
+// settled, batches cannot be unique.
+func Unique(key string, ttl time.Duration) Option {
+	if ttl <= 0 {
+		panic("queue: Unique requires a positive ttl")
+	}
+	return func(env *Envelope) {
+		env.UniqueKey = key
+		env.UniqueTTL = ttl
+	}
+}
+
+// RateLimited makes workers run at most n jobs sharing key per period, e.g.
+// to respect a third-party API limit. Jobs over the limit are put back on the
+// queue until the next period without counting as an attempt. It requires a KV
+// on the Worker.
+func RateLimited(key string, n int, per time.Duration) Option {
+	return func(env *Envelope) {
+		env.RateKey = key
+		env.RateLimit = n
+		env.RatePer = per
+	}
+}
+
+// claimUnique takes the uniqueness lock of a Unique job before it is pushed
+func (q *Queue) claimUnique(ctx context.Context, env *Envelope) error {
+	if env.UniqueKey == "" {
+		return nil
+	}
+	if q.locker == nil {
+		return errors.New("queue: Unique jobs require a locker, see Queue.UseLocker")
+	}
+
+	token, ok, err := q.locker.Lock(ctx, uniqueLockKey(env.UniqueKey), env.UniqueTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDuplicate
+	}
+	env.UniqueToken = token
+	return nil
+}
+
+// releaseUnique frees the uniqueness lock once a Unique job is settled for good
+func (w *Worker) releaseUnique(ctx context.Context, env *Envelope) {
+	if env.UniqueToken == "" || w.Locker == nil {
+		return
+	}
+	w.Locker.Unlock(ctx, uniqueLockKey(env.UniqueKey), env.UniqueToken)
+}
+
+// throttled reports whether a RateLimited job is over its limit, returning
+// the delay until the next period starts
+func (w *Worker) throttled(ctx context.Context, env *Envelope) (time.Duration, bool) {
+	if env.RateKey == "" || env.RatePer <= 0 || w.KV == nil {
+		return 0, false
+	}
+
+	now := clock.Or(w.Clock).Now()
+	window := now.Truncate(env.RatePer)
+	key := fmt.Sprintf("queue:rate:%s:%d", env.RateKey, window.Unix())
+
+	count, err := w.KV.Incr(ctx, key, env.RatePer)
+	if err != nil || count <= int64(env.RateLimit) {
+		return 0, false
+	}
+	return window.Add(env.RatePer).Sub(now), true
+}
+
+func uniqueLockKey(key string) string {
+	return "queue:unique:" + key
+}
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process Backend, useful for tests and single instance apps
+type Memory struct {
+	mu       sync.Mutex
+	pending  map[string][]*Envelope
+	reserved map[string]*Envelope
+}
+
+func NewMemory() *Memory {
+	return &Memory{pending: map[string][]*Envelope{}, reserved: map[string]*Envelope{}}
+}
+
+func (m *Memory) Push(ctx context.Context, env *Envelope) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending[env.Queue] = append(m.pending[env.Queue], env)
+	return nil
+}
+
+func (m *Memory) Reserve(ctx context.Context, queue string) (*Envelope, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for i, env := range m.pending[queue] {
+		if env.AvailableAt.After(now) {
+			continue
+		}
+		m.pending[queue] = append(m.pending[queue][:i], m.pending[queue][i+1:]...)
+		m.reserved[env.ID] = env
+		return env, nil
+	}
+	return nil, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, env *Envelope) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.reserved, env.ID)
+	return nil
+}
+
+func (m *Memory) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.reserved, env.ID)
+	env.AvailableAt = time.Now().Add(delay)
+	m.pending[env.Queue] = append(m.pending[env.Queue], env)
+	return nil
+}
+
+// Size returns the number of pending jobs on the queue
+func (m *Memory) Size(queue string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.pending[queue])
+}
@@ -0,0 +1,182 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/driver"
+	"github.com/go-bold/bold/idgen"
+)
+
+// Job is a unit of background work. Jobs are serialized to JSON when dispatched,
+// so their exported fields must carry everything Handle needs.
+type Job interface {
+	Handle(ctx context.Context) error
+}
+
+// Envelope is a dispatched job as stored by a Backend
+type Envelope struct {
+	ID          string          `json:"id"`
+	Queue       string          `json:"queue"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxTries    int             `json:"max_tries"`
+	AvailableAt time.Time       `json:"available_at"`
+	// Chain holds the jobs dispatched one after another once this one succeeds
+	Chain []*Envelope `json:"chain,omitempty"`
+	// OnFailure is dispatched if this job or a later one of its chain fails
+	OnFailure *Envelope `json:"on_failure,omitempty"`
+	// BatchID is the batch the job belongs to
+	BatchID string `json:"batch_id,omitempty"`
+	// UniqueKey and UniqueTTL dedupe dispatches of the same job, see Unique
+	UniqueKey   string        `json:"unique_key,omitempty"`
+	UniqueTTL   time.Duration `json:"unique_ttl,omitempty"`
+	UniqueToken string        `json:"unique_token,omitempty"`
+	// RateKey, RateLimit and RatePer throttle how often workers run jobs sharing the key, see RateLimited
+	RateKey   string        `json:"rate_key,omitempty"`
+	RateLimit int           `json:"rate_limit,omitempty"`
+	RatePer   time.Duration `json:"rate_per,omitempty"`
+}
+
+// Backend stores jobs until a worker claims them
+type Backend interface {
+	Push(ctx context.Context, env *Envelope) error
+	// Reserve claims the next available job of the queue, returning nil when there is none
+	Reserve(ctx context.Context, queue string) (*Envelope, error)
+	// Delete removes a reserved job once it has been handled
+	Delete(ctx context.Context, env *Envelope) error
+	// Release makes a reserved job available again after delay
+	Release(ctx context.Context, env *Envelope, delay time.Duration) error
+}
+
+// Option configures a dispatched job
+type Option func(*Envelope)
+
+// OnQueue dispatches the job to the named queue instead of "default"
+func OnQueue(name string) Option {
+	return func(env *Envelope) {
+		env.Queue = name
+	}
+}
+
+// Delay makes the job available only after d
+func Delay(d time.Duration) Option {
+	return func(env *Envelope) {
+		env.AvailableAt = env.AvailableAt.Add(d)
+	}
+}
+
+// Tries sets how many times the job is attempted before it is considered failed
+func Tries(n int) Option {
+	return func(env *Envelope) {
+		env.MaxTries = n
+	}
+}
+
+// Queue dispatches jobs to a backend
+type Queue struct {
+	backend Backend
+	locker  driver.Locker
+}
+
+func New(backend Backend) *Queue {
+	return &Queue{backend: backend}
+}
+
+// UseLocker sets the locker that enforces Unique jobs; workers must be given the same one
+func (q *Queue) UseLocker(l driver.Locker) *Queue {
+	q.locker = l
+	return q
+}
+
+// Backend returns the backend jobs are dispatched to
+func (q *Queue) Backend() Backend {
+	return q.backend
+}
+
+// Dispatch serializes the job and pushes it to the backend
+func (q *Queue) Dispatch(ctx context.Context, job Job, opts ...Option) error {
+	env, err := NewEnvelope(job, opts...)
+	if err != nil {
+		return err
+	}
+	if err := q.claimUnique(ctx, env); err != nil {
+		return err
+	}
+	return q.backend.Push(ctx, env)
+}
+
+// NewEnvelope serializes job into an envelope ready to be pushed to a backend
+func NewEnvelope(job Job, opts ...Option) (*Envelope, error) {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("queue: encoding %T: %w", job, err)
+	}
+
+	Register(job)
+	env := &Envelope{
+		ID:          newID(),
+		Queue:       "default",
+		Type:        typeName(job),
+		Payload:     payload,
+		MaxTries:    1,
+		AvailableAt: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(env)
+	}
+	return env, nil
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]reflect.Type{}
+)
+
+// Register makes job types known to workers so their envelopes can be decoded.
+// Dispatching registers the type automatically; worker processes that never
+// dispatch a given job must register it themselves.
+func Register(jobs ...Job) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, job := range jobs {
+		registry[typeName(job)] = reflect.TypeOf(job)
+	}
+}
+
+// Decode rebuilds the job carried by an envelope
+func Decode(env *Envelope) (Job, error) {
+	registryMu.RLock()
+	t, ok := registry[env.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("queue: job type %s is not registered", env.Type)
+	}
+
+	var v reflect.Value
+	if t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem())
+	} else {
+		v = reflect.New(t)
+	}
+	if err := json.Unmarshal(env.Payload, v.Interface()); err != nil {
+		return nil, fmt.Errorf("queue: decoding %s: %w", env.Type, err)
+	}
+	if t.Kind() != reflect.Pointer {
+		v = v.Elem()
+	}
+	return v.Interface().(Job), nil
+}
+
+func typeName(job Job) string {
+	return reflect.TypeOf(job).String()
+}
+
+func newID() string {
+	return idgen.New()
+}
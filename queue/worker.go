@@ -0,0 +1,305 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+	"github.com/go-bold/bold/driver"
+	"github.com/go-bold/bold/errors"
+	"github.com/go-bold/bold/metrics"
+)
+
+// ErrShutdown is the cause of the job context cancellation when a worker gives up waiting for in-flight jobs
+var ErrShutdown = errors.New("queue: worker shut down")
+
+var (
+	jobsProcessed = metrics.NewCounter("bold_queue_jobs_total", "Jobs handled by queue workers by outcome", "queue", "job", "status")
+	jobDuration   = metrics.NewHistogram("bold_queue_job_duration_seconds", "Time spent handling jobs", nil, "queue", "job")
+)
+
+// Worker claims jobs from a backend and handles them
+type Worker struct {
+	Backend Backend
+	// Queues are polled in order, "default" when empty
+	Queues []string
+	// Concurrency is the number of jobs handled at once, 1 when zero
+	Concurrency int
+	// PollInterval is the wait between polls of empty queues, 1 second when zero
+	PollInterval time.Duration
+	// ShutdownTimeout bounds how long in-flight jobs may finish once the worker stops, 30 seconds when zero
+	ShutdownTimeout time.Duration
+	// Backoff returns the delay before a failed job is retried, attempts seconds when nil
+	Backoff func(attempts int) time.Duration
+	// Locker releases the claims of Unique jobs, the one given to Queue.UseLocker
+	Locker driver.Locker
+	// KV counts RateLimited jobs
+	KV driver.KV
+	// Batches tracks the progress of batched jobs, required when jobs are dispatched with Batch
+	Batches BatchStore
+	// Failed is the dead-letter store jobs are moved to once they exhausted their tries
+	Failed FailedStore
+	// OnFailed is called when a job exhausted its tries
+	OnFailed func(env *Envelope, err error)
+	// Clock tells the time of rate limit windows, clock.Default() when nil
+	Clock clock.Clock
+
+	mu       sync.Mutex
+	stopping chan struct{}
+	stopped  chan struct{}
+}
+
+// Run claims and handles jobs until ctx is done, Shutdown is called or the
+// process receives SIGINT or SIGTERM. It then stops claiming jobs, waits up to
+// ShutdownTimeout for in-flight ones, and re-queues those that did not finish.
+// Jobs still running ShutdownTimeout after their context was cancelled are
+// abandoned, Run returning ErrShutdown. The worker can be run again once Run
+// returned.
+func (w *Worker) Run(ctx context.Context) error {
+	w.mu.Lock()
+	if w.stopping != nil {
+		w.mu.Unlock()
+		return errors.New("queue: worker already running")
+	}
+	w.stopping = make(chan struct{})
+	w.stopped = make(chan struct{})
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		close(w.stopped)
+		w.stopping, w.stopped = nil, nil
+		w.mu.Unlock()
+	}()
+
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// job contexts outlive the worker context so in-flight jobs can drain
+	jobCtx, cancelJobs := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer cancelJobs(nil)
+
+	concurrency := max(w.Concurrency, 1)
+	slots := make(chan struct{}, concurrency)
+	var inflight sync.WaitGroup
+
+claim:
+	for {
+		select {
+		case <-sigCtx.Done():
+			break claim
+		case <-w.stopping:
+			break claim
+		case slots <- struct{}{}:
+		}
+
+		env, err := w.reserve(jobCtx)
+		if err != nil || env == nil {
+			<-slots
+			if !w.sleep(sigCtx) {
+				break claim
+			}
+			continue
+		}
+
+		inflight.Add(1)
+		go func() {
+			defer inflight.Done()
+			defer func() { <-slots }()
+			w.handle(jobCtx, env)
+		}()
+	}
+
+	timeout := w.ShutdownTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	drained := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(timeout):
+		cancelJobs(ErrShutdown)
+	}
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("%w: in-flight jobs ignored the cancellation of their context", ErrShutdown)
+	}
+}
+
+// Shutdown stops the worker from claiming jobs and waits until in-flight jobs
+// are drained or ctx is done. It is meant to be called from the app's shutdown.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	stopping, stopped := w.stopping, w.stopped
+	if stopping == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	select {
+	case <-stopping:
+	default:
+		close(stopping)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve claims the next job from the first queue that has one
+func (w *Worker) reserve(ctx context.Context) (*Envelope, error) {
+	queues := w.Queues
+	if len(queues) == 0 {
+		queues = []string{"default"}
+	}
+	for _, q := range queues {
+		env, err := w.Backend.Reserve(ctx, q)
+		if err != nil || env != nil {
+			return env, err
+		}
+	}
+	return nil, nil
+}
+
+// sleep waits for the poll interval, reporting false if the worker is stopping
+func (w *Worker) sleep(ctx context.Context) bool {
+	interval := w.PollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-w.stopping:
+		return false
+	}
+}
+
+// handle runs a reserved job and settles it with the backend
+func (w *Worker) handle(ctx context.Context, env *Envelope) {
+	if delay, ok := w.throttled(ctx, env); ok {
+		w.Backend.Release(context.WithoutCancel(ctx), env, delay)
+		return
+	}
+
+	env.Attempts++
+	started := time.Now()
+	stack, err := w.perform(ctx, env)
+	jobDuration.Observe(time.Since(started).Seconds(), env.Queue, env.Type)
+
+	// settle with the backend even when jobs were cancelled by shutdown
+	settleCtx := context.WithoutCancel(ctx)
+
+	if err != nil && errors.Is(context.Cause(ctx), ErrShutdown) {
+		// the job was interrupted, so it does not count as an attempt
+		env.Attempts--
+		w.Backend.Release(settleCtx, env, 0)
+		jobsProcessed.Inc(env.Queue, env.Type, "interrupted")
+		return
+	}
+
+	if err == nil {
+		w.Backend.Delete(settleCtx, env)
+		if next := env.next(); next != nil {
+			next.AvailableAt = time.Now()
+			w.Backend.Push(settleCtx, next)
+		} else {
+			w.releaseUnique(settleCtx, env)
+		}
+		w.recordBatch(settleCtx, env, false)
+		jobsProcessed.Inc(env.Queue, env.Type, "processed")
+		return
+	}
+
+	if env.Attempts < env.MaxTries {
+		w.Backend.Release(settleCtx, env, w.backoff(env.Attempts))
+		jobsProcessed.Inc(env.Queue, env.Type, "retried")
+		return
+	}
+
+	jobsProcessed.Inc(env.Queue, env.Type, "failed")
+
+	w.Backend.Delete(settleCtx, env)
+	w.releaseUnique(settleCtx, env)
+	if w.Failed != nil {
+		w.Failed.Store(settleCtx, FailedJob{Envelope: env, Error: err.Error(), FailedAt: time.Now()})
+	}
+	if env.OnFailure != nil {
+		env.OnFailure.AvailableAt = time.Now()
+		w.Backend.Push(settleCtx, env.OnFailure)
+	}
+	w.recordBatch(settleCtx, env, true)
+	opts := []errors.Option{errors.WithTag("queue", env.Queue), errors.WithTag("job", env.Type), errors.WithTag("job_id", env.ID)}
+	if p, ok := err.(*errors.PanicError); ok {
+		opts = append(opts, errors.WithPanic(p, stack))
+	}
+	errors.Report(settleCtx, err, opts...)
+	if w.OnFailed != nil {
+		w.OnFailed(env, err)
+	}
+}
+
+// recordBatch updates the progress of the job's batch and dispatches its callbacks when due
+func (w *Worker) recordBatch(ctx context.Context, env *Envelope, failed bool) {
+	if env.BatchID == "" || w.Batches == nil {
+		return
+	}
+
+	batch, notifyFailure, complete, err := w.Batches.Record(ctx, env.BatchID, failed)
+	if err != nil {
+		return
+	}
+	if notifyFailure && batch.OnFailure != nil {
+		batch.OnFailure.AvailableAt = time.Now()
+		w.Backend.Push(ctx, batch.OnFailure)
+	}
+	if complete && batch.OnComplete != nil {
+		batch.OnComplete.AvailableAt = time.Now()
+		w.Backend.Push(ctx, batch.OnComplete)
+	}
+}
+
+// perform decodes and handles the job, turning a panic into an
+// errors.PanicError returned with the stack it was raised on
+func (w *Worker) perform(ctx context.Context, env *Envelope) (stack []byte, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			stack, err = debug.Stack(), &errors.PanicError{Value: v}
+		}
+	}()
+
+	job, err := Decode(env)
+	if err != nil {
+		return nil, err
+	}
+	return nil, job.Handle(ctx)
+}
+
+func (w *Worker) backoff(attempts int) time.Duration {
+	if w.Backoff != nil {
+		return w.Backoff(attempts)
+	}
+	return time.Duration(attempts) * time.Second
+}
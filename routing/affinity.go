@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"net/http"
+	"os"
+)
+
+// AffinityCookie names the cookie Affinity pins clients to an instance with
+const AffinityCookie = "bold_affinity"
+
+// Affinity returns middleware pinning each client to the instance serving it,
+// by setting AffinityCookie to instance, the host name when empty. Load
+// balancers configured to route on the cookie, such as HAProxy's cookie
+// directive or application cookie stickiness on AWS load balancers, then
+// send the reconnects of long polls and event streams back to the instance
+// holding their state. A client whose instance went away is pinned to the
+// one it reaches next.
+func Affinity(instance string) MiddlewareFunc {
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if cookie, err := r.Cookie(AffinityCookie); err != nil || cookie.Value != instance {
+				http.SetCookie(w, &http.Cookie{
+					Name:     AffinityCookie,
+					Value:    instance,
+					Path:     "/",
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			next(w, r)
+		}
+	}
+}
+
+// LastEventID returns the ID of the last event a reconnecting client
+// received, "" on a first connection, so a stream resumes after it instead of
+// starting over. Browsers send it in the Last-Event-ID header when an
+// EventSource reconnects, and clients that cannot set headers pass it in the
+// lastEventId query parameter. Events are replayed from a store shared by the
+// instances, as the client may reconnect to another one.
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
@@ -0,0 +1,124 @@
+package routing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// CaptchaVerifier checks a CAPTCHA response token with its provider (reCAPTCHA, hCaptcha, Turnstile, ...)
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token string, remoteIP string) (bool, error)
+}
+
+// Honeypot returns middleware rejecting form submissions that fill in the
+// given field. The field should be rendered hidden so humans leave it empty.
+func Honeypot(field string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if isSubmission(r) && r.PostFormValue(field) != "" {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// FormTimestamp returns a signed timestamp to embed in a form as a hidden field checked by MinSubmitTime
+func FormTimestamp(key []byte) string {
+	ts := strconv.FormatInt(clock.Now().Unix(), 10)
+	return ts + "." + signTimestamp(key, ts)
+}
+
+// MaxFormAge is how long a FormTimestamp stays valid, so a timestamp captured
+// once cannot be replayed forever
+var MaxFormAge = 2 * time.Hour
+
+// MinSubmitTime returns middleware rejecting form submissions made less than
+// min after the form was rendered, as read from the FormTimestamp value in the
+// given field. Missing, tampered or future timestamps and those older than
+// MaxFormAge are rejected as well.
+func MinSubmitTime(field string, min time.Duration, key []byte) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if isSubmission(r) && !submittedAfter(r.PostFormValue(field), min, key) {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// RequireCaptcha returns middleware verifying the CAPTCHA token posted in the given field
+func RequireCaptcha(verifier CaptchaVerifier, field string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !isSubmission(r) {
+				next(w, r)
+				return
+			}
+
+			token := r.PostFormValue(field)
+			if token == "" {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+
+			remoteIP := ""
+			if ip := ClientIP(r); ip.IsValid() {
+				remoteIP = ip.String()
+			}
+
+			ok, err := verifier.Verify(r.Context(), token, remoteIP)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			if !ok {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// isSubmission reports whether the request carries a form body
+func isSubmission(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// submittedAfter reports whether a signed form timestamp is valid, at least
+// min old and at most MaxFormAge old
+func submittedAfter(value string, min time.Duration, key []byte) bool {
+	ts, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(signTimestamp(key, ts))) {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := clock.Now().Sub(time.Unix(unix, 0))
+	return age >= 0 && age >= min && age <= MaxFormAge
+}
+
+func signTimestamp(key []byte, ts string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ts))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,107 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// MaxBulkBody is the largest bulk request body Bulk decodes, larger ones get a 413
+var MaxBulkBody int64 = 10 << 20
+
+// BulkResult is the outcome of processing a single item of a bulk request
+type BulkResult struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	Data   any    `json:"data,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkFunc processes a single item of a bulk request
+type BulkFunc[T any] func(ctx context.Context, item T) (any, error)
+
+// Bulk returns a handler for batch endpoints. The request body must be a JSON
+// array; each item is processed by fn with at most limit items in flight, and
+// the response is a 207 Multi-Status body mapping item indices to results.
+//
+// Failed items are reported with status 422 unless the error provides its own
+// status through a StatusCode() int method, anywhere in its chain. Items whose fn panics are reported
+// as errors.PanicError and get a 500, the other items carrying on.
+func Bulk[T any](limit int, fn BulkFunc[T]) HandlerFunc {
+	if limit < 1 {
+		limit = 1
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var items []T
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBulkBody)).Decode(&items); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "request body is too large"})
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "request body must be a JSON array"})
+			return
+		}
+
+		ctx := r.Context()
+		results := make([]BulkResult, len(items))
+		sem := make(chan struct{}, limit)
+		var wg sync.WaitGroup
+
+		for i, item := range items {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i] = bulkFailure(i, ctx.Err())
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, item T) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				defer func() {
+					if v := recover(); v != nil {
+						results[i] = bulkPanic(r, i, &errors.PanicError{Value: v}, debug.Stack())
+					}
+				}()
+
+				data, err := fn(ctx, item)
+				if err != nil {
+					results[i] = bulkFailure(i, err)
+					return
+				}
+				results[i] = BulkResult{Index: i, Status: http.StatusOK, Data: data}
+			}(i, item)
+		}
+		wg.Wait()
+
+		writeJSON(w, http.StatusMultiStatus, map[string]any{"results": results})
+	}
+}
+
+// bulkPanic reports the panic of an item and builds its result, the panic
+// value only exposed where error details are, see debugErrors
+func bulkPanic(r *http.Request, index int, err *errors.PanicError, stack []byte) BulkResult {
+	errors.Report(r.Context(), err, errors.WithRequest(r), errors.WithPanic(err, stack), errors.WithExtra("bulk_index", index))
+	result := BulkResult{Index: index, Status: http.StatusInternalServerError, Error: http.StatusText(http.StatusInternalServerError)}
+	if debugErrors() {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// bulkFailure builds the result for an item that could not be processed
+func bulkFailure(index int, err error) BulkResult {
+	status := http.StatusUnprocessableEntity
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) {
+		status = sc.StatusCode()
+	}
+	return BulkResult{Index: index, Status: status, Error: err.Error()}
+}
@@ -0,0 +1,210 @@
+package routing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed, tampered with
+// or signed for other sort keys
+var ErrInvalidCursor = errors.New("routing: invalid cursor")
+
+// SortKey is a column keyset pagination orders rows by
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Keyset paginates by the sort key values of the last row of a page rather
+// than an offset, so pages stay stable as rows are added and deep pages are as
+// fast as the first. The last sort key must be unique, usually the primary key:
+//
+//	users := routing.Keyset{Key: key, Sort: []routing.SortKey{{Column: "created_at", Desc: true}, {Column: "id", Desc: true}}}
+//	cursor, err := users.Cursor(r)
+//	if err != nil {
+//		routing.WriteProblem(w, routing.Problem{Status: http.StatusBadRequest, Detail: err.Error()})
+//		return
+//	}
+//	where, args := cursor.Where(func(int) string { return "?" })
+//	// SELECT ... WHERE <where> ORDER BY <cursor.OrderBy()> LIMIT <cursor.Limit+1>
+//	page := routing.NewPage(r, cursor, rows, func(u User) []any { return []any{u.CreatedAt, u.ID} })
+//
+// Cursors are signed with Key, so clients cannot forge positions. They carry
+// the values as JSON, so times come back as RFC 3339 strings and numbers as
+// json.Number, which databases compare with the column values as expected.
+type Keyset struct {
+	Key  []byte
+	Sort []SortKey
+	// PerPage is the page size when the request sets no per_page, 25 by default
+	PerPage int
+	// MaxPerPage caps per_page, 100 by default
+	MaxPerPage int
+}
+
+// Cursor is the page a request asks for
+type Cursor struct {
+	keyset Keyset
+	// After holds the sort key values of the last row of the previous page, nil for the first page
+	After []any
+	// Limit is the page size, query one more row so NewPage knows whether another page follows
+	Limit int
+}
+
+// Cursor reads the page a request asks for from its cursor and per_page query parameters
+func (k Keyset) Cursor(r *http.Request) (Cursor, error) {
+	c := Cursor{keyset: k, Limit: k.PerPage}
+	if c.Limit <= 0 {
+		c.Limit = 25
+	}
+	max := k.MaxPerPage
+	if max <= 0 {
+		max = 100
+	}
+
+	query := r.URL.Query()
+	if perPage := query.Get("per_page"); perPage != "" {
+		n, err := strconv.Atoi(perPage)
+		if err != nil || n < 1 {
+			return Cursor{}, errors.New("routing: per_page must be a positive integer")
+		}
+		c.Limit = min(n, max)
+	}
+	if token := query.Get("cursor"); token != "" {
+		after, err := k.decode(token)
+		if err != nil {
+			return Cursor{}, err
+		}
+		c.After = after
+	}
+	return c, nil
+}
+
+// Encode returns the opaque cursor of the page following the row with the
+// given sort key values
+func (k Keyset) Encode(values []any) string {
+	payload, _ := json.Marshal(values)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + k.sign(encoded)
+}
+
+func (k Keyset) decode(token string) ([]any, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(k.sign(encoded))) {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var values []any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil || len(values) != len(k.Sort) {
+		return nil, ErrInvalidCursor
+	}
+	return values, nil
+}
+
+// sign covers the sort keys too, so a cursor of one ordering is refused by another
+func (k Keyset) sign(encoded string) string {
+	mac := hmac.New(sha256.New, k.Key)
+	for _, key := range k.Sort {
+		mac.Write([]byte(key.Column))
+		if key.Desc {
+			mac.Write([]byte(" DESC"))
+		}
+		mac.Write([]byte{0})
+	}
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Where returns the condition selecting the rows after the cursor and its
+// arguments, numbering placeholders from 1, or "" and nil on the first page.
+// Mixed directions are supported, which row value comparisons cannot do.
+func (c Cursor) Where(placeholder func(n int) string) (string, []any) {
+	if c.After == nil {
+		return "", nil
+	}
+
+	var terms []string
+	var args []any
+	for i, key := range c.keyset.Sort {
+		var parts []string
+		for j, prior := range c.keyset.Sort[:i] {
+			args = append(args, c.After[j])
+			parts = append(parts, prior.Column+" = "+placeholder(len(args)))
+		}
+		op := " > "
+		if key.Desc {
+			op = " < "
+		}
+		args = append(args, c.After[i])
+		parts = append(parts, key.Column+op+placeholder(len(args)))
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// OrderBy returns the ORDER BY list of the sort keys, such as "created_at DESC, id DESC"
+func (c Cursor) OrderBy() string {
+	columns := make([]string, len(c.keyset.Sort))
+	for i, key := range c.keyset.Sort {
+		columns[i] = key.Column
+		if key.Desc {
+			columns[i] += " DESC"
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// Page is the response envelope of a paginated list
+type Page[T any] struct {
+	Data  []T       `json:"data"`
+	Meta  PageMeta  `json:"meta"`
+	Links PageLinks `json:"links"`
+}
+
+// PageMeta describes a page
+type PageMeta struct {
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PageLinks holds the URLs of a page and of the next one, absent on the last page
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+}
+
+// NewPage builds the page of rows queried for c, at most c.Limit+1 of them.
+// key returns the sort key values of a row, in the order of the sort keys.
+func NewPage[T any](r *http.Request, c Cursor, rows []T, key func(T) []any) Page[T] {
+	page := Page[T]{
+		Data:  rows,
+		Meta:  PageMeta{PerPage: c.Limit},
+		Links: PageLinks{Self: r.URL.RequestURI()},
+	}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if len(rows) > c.Limit {
+		page.Data = rows[:c.Limit]
+		page.Meta.NextCursor = c.keyset.Encode(key(rows[c.Limit-1]))
+
+		next := *r.URL
+		query := next.Query()
+		query.Set("cursor", page.Meta.NextCursor)
+		next.RawQuery = query.Encode()
+		page.Links.Next = next.RequestURI()
+	}
+	return page
+}
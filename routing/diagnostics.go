@@ -0,0 +1,129 @@
+package routing
+
+import (
+	"expvar"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"time"
+)
+
+// Diagnostics mounts runtime diagnostics under prefix: pprof profiles at
+// /pprof/, expvar at /vars, memory and GC statistics at /gc, build info at
+// /build and a full goroutine dump at /goroutines. The middlewares guard
+// every endpoint and at least one is required, Diagnostics panics without
+// any rather than exposing the endpoints.
+func (app *NetHTTPApp) Diagnostics(prefix string, middlewares ...MiddlewareFunc) {
+	if len(middlewares) == 0 {
+		panic("routing: Diagnostics requires a middleware guarding its endpoints")
+	}
+
+	r := NewRoute()
+	app.Routes(r.Group(prefix,
+		middlewares,
+		r.GET("/pprof/{$}", pprofIndex(prefix)),
+		r.GET("/pprof/cmdline", pprof.Cmdline),
+		r.GET("/pprof/profile", pprof.Profile),
+		r.GET("/pprof/symbol", pprof.Symbol),
+		r.POST("/pprof/symbol", pprof.Symbol),
+		r.GET("/pprof/trace", pprof.Trace),
+		r.GET("/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+			pprof.Handler(r.PathValue("profile")).ServeHTTP(w, r)
+		}),
+		r.GET("/vars", expvar.Handler().ServeHTTP),
+		r.GET("/gc", gcStats),
+		r.GET("/build", buildInfo),
+		r.GET("/goroutines", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			rpprof.Lookup("goroutine").WriteTo(w, 2)
+		}),
+	))
+}
+
+// LocalOnly rejects requests from clients other than the loopback interface
+// with 403. Behind a reverse proxy on the same host every request comes from
+// loopback, so it is no substitute for authentication there.
+func LocalOnly() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !ClientIP(r).IsLoopback() {
+				WriteProblem(w, Problem{Status: http.StatusForbidden})
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+var pprofIndexPage = template.Must(template.New("pprof").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Profiles</title></head>
+<body>
+<h1>Profiles</h1>
+<ul>
+{{range .Profiles}}<li><a href="{{$.Prefix}}/pprof/{{.Name}}?debug=1">{{.Name}}</a> ({{.Count}})</li>
+{{end}}<li><a href="{{.Prefix}}/pprof/profile?seconds=30">profile</a> (30s CPU profile)</li>
+<li><a href="{{.Prefix}}/pprof/trace?seconds=5">trace</a> (5s execution trace)</li>
+</ul>
+</body>
+</html>
+`))
+
+// pprofIndex lists the available profiles, as pprof.Index only works under /debug/pprof/
+func pprofIndex(prefix string) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		pprofIndexPage.Execute(w, map[string]any{"Prefix": prefix, "Profiles": rpprof.Profiles()})
+	}
+}
+
+func gcStats(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"goroutines":      runtime.NumGoroutine(),
+		"heap_alloc":      mem.HeapAlloc,
+		"heap_sys":        mem.HeapSys,
+		"heap_objects":    mem.HeapObjects,
+		"total_alloc":     mem.TotalAlloc,
+		"sys":             mem.Sys,
+		"num_gc":          gc.NumGC,
+		"last_gc":         gc.LastGC.Format(time.RFC3339Nano),
+		"pause_total":     gc.PauseTotal.String(),
+		"gc_cpu_fraction": mem.GCCPUFraction,
+		"next_gc":         mem.NextGC,
+	})
+}
+
+func buildInfo(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		WriteProblem(w, Problem{Status: http.StatusNotFound, Detail: "binary built without module support"})
+		return
+	}
+
+	settings := map[string]string{}
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	deps := map[string]string{}
+	for _, d := range info.Deps {
+		deps[d.Path] = d.Version
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"go_version": info.GoVersion,
+		"path":       info.Path,
+		"main":       fmt.Sprintf("%s@%s", info.Main.Path, info.Main.Version),
+		"settings":   settings,
+		"deps":       deps,
+	})
+}
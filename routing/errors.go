@@ -0,0 +1,154 @@
+package routing
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-bold/bold/config"
+	"github.com/go-bold/bold/errors"
+)
+
+// ErrorPageData is passed to error page templates
+type ErrorPageData struct {
+	Status  int
+	Title   string
+	Detail  string
+	Stack   string
+	Request *http.Request
+	// Debug is set in development, where Detail and Stack are filled in, see debugErrors
+	Debug bool
+}
+
+// ErrorPage registers the template rendering HTML error pages for status,
+// or for every status without its own template when status is 0
+func (app *NetHTTPApp) ErrorPage(status int, tmpl *template.Template) {
+	if app.errorPages == nil {
+		app.errorPages = map[int]*template.Template{}
+	}
+	app.errorPages[status] = tmpl
+}
+
+// RenderError writes an error response for err. Clients accepting HTML get an
+// error page, those preferring XML problem+xml and others problem+json. The
+// error message and stack are only exposed when the environment is explicitly
+// set to development, responses are generic when it is unset or anything else.
+func (app *NetHTTPApp) RenderError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	app.renderError(w, r, status, err, "")
+}
+
+func (app *NetHTTPApp) renderError(w http.ResponseWriter, r *http.Request, status int, err error, stack string) {
+	data := ErrorPageData{
+		Status:  status,
+		Title:   http.StatusText(status),
+		Request: r,
+		Debug:   debugErrors(),
+	}
+	if data.Debug {
+		if err != nil {
+			data.Detail = err.Error()
+		}
+		data.Stack = stack
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		p := Problem{Status: status, Detail: data.Detail}
+		if data.Stack != "" {
+			p.Extra = map[string]any{"stack": strings.Split(strings.TrimSpace(data.Stack), "\n")}
+		}
+		if prefersXML(r.Header.Get("Accept")) {
+			writeProblemXML(w, p)
+			return
+		}
+		WriteProblem(w, p)
+		return
+	}
+
+	tmpl := app.errorPages[status]
+	if tmpl == nil {
+		tmpl = app.errorPages[0]
+	}
+	if tmpl == nil {
+		tmpl = defaultErrorPage
+	}
+
+	// render to a buffer first so a failing template cannot leave a half written page
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		buf.Reset()
+		defaultErrorPage.Execute(&buf, data)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// recoverer reports panics in next and turns them into 500 error responses,
+// unless the handler already started its response. The panic value is reported
+// as an *errors.PanicError, tagged with the kind of value and the route
+// pattern, with the stack starting at the panicking frame.
+func (app *NetHTTPApp) recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := newResponseWriter(w)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			err := &errors.PanicError{Value: v}
+			stack := debug.Stack()
+			opts := []errors.Option{errors.WithRequest(r), errors.WithPanic(err, stack)}
+			if r.Pattern != "" {
+				opts = append(opts, errors.WithTag("route", r.Pattern))
+			}
+			errors.Report(r.Context(), err, opts...)
+			if rw.status == 0 {
+				app.renderError(rw, r, http.StatusInternalServerError, err, string(errors.FilterStack(stack)))
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+var defaultErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 4rem auto; max-width: 56rem; padding: 0 1rem; color: #222; }
+pre { background: #f5f5f5; padding: 1rem; overflow-x: auto; font-size: 0.85rem; }
+</style>
+</head>
+<body>
+<h1>{{.Status}} {{.Title}}</h1>
+{{if .Debug}}
+{{with .Detail}}<p>{{.}}</p>{{end}}
+<p><code>{{.Request.Method}} {{.Request.URL}}</code></p>
+{{with .Stack}}<pre>{{.}}</pre>{{end}}
+{{else}}
+<p>Something went wrong on our side. Please try again later.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// debugErrors reports whether error responses expose details. Unlike
+// config.IsDevelopment it fails closed: an unset environment, which Env
+// takes for development, keeps the details hidden.
+func debugErrors() bool {
+	for _, name := range []string{config.EnvVar, "APP_ENV"} {
+		if env, ok := os.LookupEnv(name); ok && env != "" {
+			return env == config.Development
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package routing
+
+import (
+	"context"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+)
+
+// Variant is a named arm of an experiment with a relative weight
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+// Experiment describes an A/B test requests are assigned to
+type Experiment struct {
+	Name     string
+	Variants []Variant
+	// MaxAge is the lifetime of the assignment cookie in seconds, 30 days when zero
+	MaxAge int
+	// OnExposure is called for every request served under an assigned variant
+	OnExposure func(r *http.Request, experiment, variant string)
+}
+
+type experimentsKey struct{}
+
+// Experiments returns middleware assigning each request to a weighted variant
+// of every given experiment. Assignments are persisted in a cookie per
+// experiment so a client keeps seeing the same variant across requests.
+func Experiments(experiments ...Experiment) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assigned := map[string]string{}
+			if parent, ok := r.Context().Value(experimentsKey{}).(map[string]string); ok {
+				maps.Copy(assigned, parent)
+			}
+
+			for _, e := range experiments {
+				if len(e.Variants) == 0 {
+					continue
+				}
+				variant := e.current(r)
+				if variant == "" {
+					variant = e.pick()
+					maxAge := e.MaxAge
+					if maxAge == 0 {
+						maxAge = 30 * 24 * 60 * 60
+					}
+					http.SetCookie(w, &http.Cookie{
+						Name:     e.cookieName(),
+						Value:    variant,
+						Path:     "/",
+						MaxAge:   maxAge,
+						HttpOnly: true,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+				assigned[e.Name] = variant
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), experimentsKey{}, assigned))
+
+			for _, e := range experiments {
+				if e.OnExposure != nil && assigned[e.Name] != "" {
+					e.OnExposure(r, e.Name, assigned[e.Name])
+				}
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// ExperimentVariant returns the variant the request was assigned for the named experiment
+func ExperimentVariant(r *http.Request, experiment string) string {
+	assigned, _ := r.Context().Value(experimentsKey{}).(map[string]string)
+	return assigned[experiment]
+}
+
+// ExperimentVariants returns all experiment assignments of the request, suitable for template data
+func ExperimentVariants(r *http.Request) map[string]string {
+	assigned, _ := r.Context().Value(experimentsKey{}).(map[string]string)
+	return maps.Clone(assigned)
+}
+
+func (e Experiment) cookieName() string {
+	return "bold_exp_" + e.Name
+}
+
+// current returns the variant persisted in the request cookie if it is still part of the experiment
+func (e Experiment) current(r *http.Request) string {
+	cookie, err := r.Cookie(e.cookieName())
+	if err != nil {
+		return ""
+	}
+	for _, v := range e.Variants {
+		if v.Name == cookie.Value {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+// pick chooses a variant at random according to the variant weights
+func (e Experiment) pick() string {
+	total := 0
+	for _, v := range e.Variants {
+		total += max(v.Weight, 0)
+	}
+	if total == 0 {
+		return e.Variants[rand.IntN(len(e.Variants))].Name
+	}
+
+	n := rand.IntN(total)
+	for _, v := range e.Variants {
+		n -= max(v.Weight, 0)
+		if n < 0 {
+			return v.Name
+		}
+	}
+	return e.Variants[len(e.Variants)-1].Name
+}
@@ -0,0 +1,175 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// ExportQuery runs the query of an export endpoint, usually with
+// db.QueryContext(r.Context(), ...) so a client going away cancels it
+type ExportQuery func(r *http.Request) (*sql.Rows, error)
+
+// ExportWriter writes the rows of an export in a file format
+type ExportWriter interface {
+	WriteHeader(columns []string) error
+	// WriteRow writes the values of a row as the driver returned them
+	WriteRow(values []any) error
+	// Flush writes out buffered rows, Export calls it periodically and at the end
+	Flush() error
+}
+
+// ExportFormat is a file format Export can stream, see RegisterExportFormat
+type ExportFormat struct {
+	ContentType string
+	// Extension is appended to the file name of the download, such as ".csv"
+	Extension string
+	New       func(w io.Writer) ExportWriter
+}
+
+var (
+	exportMu      sync.RWMutex
+	exportFormats = map[string]ExportFormat{
+		"csv": {ContentType: "text/csv; charset=utf-8", Extension: ".csv", New: newCSVExport},
+	}
+)
+
+// RegisterExportFormat adds a format export endpoints serve for ?format=name,
+// such as an XLSX writer backed by a spreadsheet library. CSV is built in.
+func RegisterExportFormat(name string, format ExportFormat) {
+	exportMu.Lock()
+	defer exportMu.Unlock()
+	exportFormats[name] = format
+}
+
+// exportFlushRows is how many rows are written between flushes to the client
+const exportFlushRows = 500
+
+// Export returns a handler streaming the rows of query as a download named
+// filename plus the extension of the format, CSV unless the request asks for
+// another registered one with ?format=. Rows are read as the client accepts
+// them, so a slow client slows the query down instead of filling memory.
+// Errors after the first row abort the response, so a truncated download is
+// not mistaken for a complete one.
+func Export(filename string, query ExportQuery) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("format")
+		if name == "" {
+			name = "csv"
+		}
+		exportMu.RLock()
+		format, ok := exportFormats[name]
+		exportMu.RUnlock()
+		if !ok {
+			WriteProblem(w, Problem{Status: http.StatusBadRequest, Detail: fmt.Sprintf("unsupported export format %q", name)})
+			return
+		}
+
+		rows, err := query(r)
+		if err != nil {
+			errors.Report(r.Context(), err, errors.WithRequest(r))
+			WriteProblem(w, Problem{Status: http.StatusInternalServerError})
+			return
+		}
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		if err != nil {
+			errors.Report(r.Context(), err, errors.WithRequest(r))
+			WriteProblem(w, Problem{Status: http.StatusInternalServerError})
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + format.Extension}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+
+		if err := streamExport(w, r, rows, columns, format.New(w)); err != nil {
+			if r.Context().Err() == nil {
+				errors.Report(r.Context(), err, errors.WithRequest(r))
+			}
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+func streamExport(w http.ResponseWriter, r *http.Request, rows *sql.Rows, columns []string, out ExportWriter) error {
+	rc := http.NewResponseController(w)
+	if err := out.WriteHeader(columns); err != nil {
+		return err
+	}
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for n := 1; rows.Next(); n++ {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		if err := out.WriteRow(values); err != nil {
+			return err
+		}
+		if n%exportFlushRows == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			rc.Flush()
+			if err := r.Context().Err(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	rc.Flush()
+	return nil
+}
+
+type csvExport struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVExport(w io.Writer) ExportWriter {
+	return &csvExport{w: csv.NewWriter(w)}
+}
+
+func (c *csvExport) WriteHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvExport) WriteRow(values []any) error {
+	c.record = c.record[:0]
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			c.record = append(c.record, "")
+		case []byte:
+			c.record = append(c.record, string(v))
+		case time.Time:
+			c.record = append(c.record, v.Format(time.RFC3339))
+		default:
+			c.record = append(c.record, fmt.Sprint(v))
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvExport) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
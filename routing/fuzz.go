@@ -0,0 +1,307 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RequestGenerator turns fuzzer input into requests for the routes of an
+// application, so handlers can be fuzzed with Go's native fuzzing:
+//
+//	func FuzzAPI(f *testing.F) {
+//		g := routing.NewRequestGenerator(app())
+//		for _, seed := range g.Seeds() {
+//			f.Add(seed)
+//		}
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			g.Serve(data)
+//		})
+//	}
+//
+// The first bytes of the input pick a route and whether the request is valid
+// or not, the rest fill in its path parameters, query and body. Valid requests
+// carry a JSON body of the type declared with Route.Accepts, invalid ones a
+// malformed, mistyped or truncated body. The same input always generates the
+// same request, so the fuzzer can minimize and replay failures.
+type RequestGenerator struct {
+	app    *NetHTTPApp
+	routes []*Route
+}
+
+// NewRequestGenerator returns a RequestGenerator for the routes registered on app
+func NewRequestGenerator(app *NetHTTPApp) *RequestGenerator {
+	return &RequestGenerator{app: app, routes: app.allRoutes()}
+}
+
+// Seeds returns a valid and an invalid input for every route, for the seed corpus
+func (g *RequestGenerator) Seeds() [][]byte {
+	var seeds [][]byte
+	for i := range g.routes {
+		for _, mode := range []byte{0, 1} {
+			seed := []byte{byte(i), byte(i >> 8), mode}
+			for j := 0; j < 32; j++ {
+				seed = append(seed, byte('a'+(i+j)%26))
+			}
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
+
+// Request generates the request for data, nil when the application has no routes
+func (g *RequestGenerator) Request(data []byte) *http.Request {
+	if len(g.routes) == 0 {
+		return nil
+	}
+	in := &fuzzInput{data: data}
+	index := int(in.byte())
+	index |= int(in.byte()) << 8
+	route := g.routes[index%len(g.routes)]
+	valid := in.byte()%2 == 0
+
+	target := generatePath(route.pattern, in, valid)
+	if query := generateQuery(in, valid); query != "" {
+		target += "?" + query
+	}
+
+	var body []byte
+	if route.method == http.MethodPost || route.method == http.MethodPut || route.method == http.MethodPatch {
+		body = generateBody(route.body, in, valid)
+	}
+	r := httptest.NewRequest(route.method, "http://example.com/", bytes.NewReader(body))
+	r.URL, _ = url.Parse(target)
+	if r.URL == nil {
+		r.URL = &url.URL{Path: "/"}
+	}
+	r.RequestURI = r.URL.RequestURI()
+	if body != nil {
+		contentType := "application/json"
+		if !valid && in.byte()%4 == 0 {
+			contentType = "text/plain"
+		}
+		r.Header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+// Serve generates the request for data and serves it without panic recovery,
+// so a panicking handler fails the fuzz test with its stack
+func (g *RequestGenerator) Serve(data []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := g.Request(data)
+	if r == nil {
+		return w
+	}
+	g.app.mux().ServeHTTP(w, r)
+	return w
+}
+
+// fuzzInput consumes fuzzer input, yielding zero values once it runs out
+type fuzzInput struct {
+	data []byte
+}
+
+func (in *fuzzInput) byte() byte {
+	if len(in.data) == 0 {
+		return 0
+	}
+	b := in.data[0]
+	in.data = in.data[1:]
+	return b
+}
+
+func (in *fuzzInput) int() int64 {
+	var n int64
+	for i := 0; i < 8; i++ {
+		n = n<<8 | int64(in.byte())
+	}
+	return n
+}
+
+// string returns up to 32 bytes of input, restricted to letters and digits when alnum is set
+func (in *fuzzInput) string(alnum bool) string {
+	n := int(in.byte() % 33)
+	if n > len(in.data) {
+		n = len(in.data)
+	}
+	s := in.data[:n]
+	in.data = in.data[n:]
+	if !alnum {
+		return string(s)
+	}
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, len(s))
+	for i, c := range s {
+		b[i] = chars[int(c)%len(chars)]
+	}
+	return string(b)
+}
+
+// generatePath fills in the wildcards of pattern, with numbers or words when
+// valid and arbitrary escaped bytes otherwise
+func generatePath(pattern string, in *fuzzInput, valid bool) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(pattern, "{")
+		if start < 0 {
+			b.WriteString(pattern)
+			return b.String()
+		}
+		end := strings.Index(pattern[start:], "}")
+		if end < 0 {
+			b.WriteString(pattern)
+			return b.String()
+		}
+		b.WriteString(pattern[:start])
+		name := pattern[start+1 : start+end]
+		pattern = pattern[start+end+1:]
+
+		switch {
+		case name == "$":
+		case !valid:
+			b.WriteString(url.PathEscape(in.string(false)))
+		case in.byte()%2 == 0:
+			b.WriteString(strconv.FormatInt(int64(in.byte())+1, 10))
+		default:
+			segment := in.string(true)
+			if segment == "" {
+				segment = "x"
+			}
+			b.WriteString(segment)
+		}
+	}
+}
+
+func generateQuery(in *fuzzInput, valid bool) string {
+	query := url.Values{}
+	for n := in.byte() % 4; n > 0; n-- {
+		query.Add(in.string(valid), in.string(valid))
+	}
+	return query.Encode()
+}
+
+// generateBody returns a JSON body of type t, or a malformed one when not valid
+func generateBody(t reflect.Type, in *fuzzInput, valid bool) []byte {
+	if t == nil {
+		if valid {
+			return []byte("{}")
+		}
+		return in.data
+	}
+	v := reflect.New(t).Elem()
+	fillValue(v, in, 0)
+	body, err := json.Marshal(v.Interface())
+	if err != nil || valid {
+		return body
+	}
+
+	switch in.byte() % 4 {
+	case 0:
+		return body[:int(in.byte())%len(body)]
+	case 1:
+		// the right fields with the wrong types
+		return mistype(body)
+	case 2:
+		return append([]byte("["), append(body, ']')...)
+	default:
+		return []byte(in.string(false))
+	}
+}
+
+// mistype swaps the JSON types of the values in body, e.g. numbers become strings
+func mistype(body []byte) []byte {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	var swap func(any) any
+	swap = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, value := range v {
+				v[k] = swap(value)
+			}
+			return v
+		case []any:
+			return map[string]any{"0": v}
+		case string:
+			return len(v)
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return []any{v}
+		case nil:
+			return false
+		}
+		return v
+	}
+	swapped, _ := json.Marshal(swap(v))
+	return swapped
+}
+
+// fillValue sets v, and the fields and elements it contains, from input
+func fillValue(v reflect.Value, in *fuzzInput, depth int) {
+	if depth > 4 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(in.string(false))
+	case reflect.Bool:
+		v.SetBool(in.byte()%2 == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := in.int()
+		if v.OverflowInt(n) {
+			n = int64(int8(n))
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := uint64(in.int())
+		if v.OverflowUint(n) {
+			n = uint64(uint8(n))
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(in.int()) / 256)
+	case reflect.Pointer:
+		if in.byte()%4 == 0 {
+			return
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		fillValue(v.Elem(), in, depth+1)
+	case reflect.Slice:
+		n := int(in.byte() % 4)
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := 0; i < n; i++ {
+			fillValue(v.Index(i), in, depth+1)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fillValue(v.Index(i), in, depth+1)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		v.Set(reflect.MakeMap(v.Type()))
+		for n := in.byte() % 4; n > 0; n-- {
+			key := reflect.New(v.Type().Key()).Elem()
+			key.SetString(in.string(true))
+			value := reflect.New(v.Type().Elem()).Elem()
+			fillValue(value, in, depth+1)
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fillValue(v.Field(i), in, depth+1)
+			}
+		}
+	}
+}
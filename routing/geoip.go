@@ -0,0 +1,80 @@
+package routing
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// GeoLocation is the geographic information resolved for a client address
+type GeoLocation struct {
+	CountryCode string
+	Country     string
+	Region      string
+	City        string
+}
+
+// GeoResolver resolves the location of an IP address, e.g. from a MaxMind database
+type GeoResolver interface {
+	Resolve(ip netip.Addr) (*GeoLocation, error)
+}
+
+type geoKey struct{}
+
+// GeoIP returns middleware attaching the client location to the request context.
+// Requests whose address cannot be resolved are served without a location.
+func GeoIP(resolver GeoResolver) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if ip := ClientIP(r); ip.IsValid() {
+				if loc, err := resolver.Resolve(ip); err == nil && loc != nil {
+					r = r.WithContext(context.WithValue(r.Context(), geoKey{}, loc))
+				}
+			}
+			next(w, r)
+		}
+	}
+}
+
+// Geo returns the location attached to the request by GeoIP, or nil if unknown
+func Geo(r *http.Request) *GeoLocation {
+	loc, _ := r.Context().Value(geoKey{}).(*GeoLocation)
+	return loc
+}
+
+// BlockCountries returns middleware rejecting clients located in any of the
+// given ISO country codes with 451 Unavailable For Legal Reasons. It must run
+// after GeoIP; requests without a resolved location are let through.
+func BlockCountries(codes ...string) MiddlewareFunc {
+	blocked := make([]string, len(codes))
+	for i, code := range codes {
+		blocked[i] = strings.ToUpper(code)
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if loc := Geo(r); loc != nil && slices.Contains(blocked, strings.ToUpper(loc.CountryCode)) {
+				http.Error(w, http.StatusText(http.StatusUnavailableForLegalReasons), http.StatusUnavailableForLegalReasons)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// ClientIP returns the address of the client connected to the server. Proxy
+// headers are not consulted since they can be forged by the client.
+func ClientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
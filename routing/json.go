@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// writeJSON encodes v as the JSON response body with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Problem is an RFC 9457 problem details body
+type Problem struct {
+	Type   string         `json:"type,omitempty"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Extra  map[string]any `json:"-"`
+}
+
+// MarshalJSON inlines the extension members next to the standard ones
+func (p Problem) MarshalJSON() ([]byte, error) {
+	body := map[string]any{}
+	for k, v := range p.Extra {
+		body[k] = v
+	}
+	if p.Type != "" {
+		body["type"] = p.Type
+	}
+	body["title"] = p.Title
+	body["status"] = p.Status
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	return json.Marshal(body)
+}
+
+// WriteProblem writes p as an application/problem+json response
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,172 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// DefaultHookTimeout bounds hooks registered without their own Timeout
+var DefaultHookTimeout = 30 * time.Second
+
+// ShutdownTimeout bounds how long Listen waits for in-flight requests on shutdown
+var ShutdownTimeout = 30 * time.Second
+
+// HookFunc is a lifecycle callback run by OnBoot and OnShutdown
+type HookFunc func(ctx context.Context) error
+
+// Hook is a registered lifecycle callback
+type Hook struct {
+	fn      HookFunc
+	timeout time.Duration
+}
+
+// Timeout sets how long the hook may run before it is abandoned
+func (h *Hook) Timeout(d time.Duration) *Hook {
+	h.timeout = d
+	return h
+}
+
+// run calls the hook, returning once it finishes or its timeout expires
+func (h *Hook) run(ctx context.Context) error {
+	timeout := h.timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- h.fn(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// OnBoot registers a hook run before the server starts accepting requests.
+// Boot hooks run in registration order and a failing hook aborts startup.
+func (app *NetHTTPApp) OnBoot(fn HookFunc) *Hook {
+	h := &Hook{fn: fn}
+	app.bootHooks = append(app.bootHooks, h)
+	return h
+}
+
+// OnShutdown registers a hook run after the server stopped serving requests.
+// Shutdown hooks run in reverse registration order, like deferred calls.
+func (app *NetHTTPApp) OnShutdown(fn HookFunc) *Hook {
+	h := &Hook{fn: fn}
+	app.shutdownHooks = append(app.shutdownHooks, h)
+	return h
+}
+
+// Boot runs the boot hooks, stopping at the first failure
+func (app *NetHTTPApp) Boot(ctx context.Context) error {
+	for i, h := range app.bootHooks {
+		if err := h.run(ctx); err != nil {
+			return fmt.Errorf("routing: boot hook %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Close runs every shutdown hook and returns their joined errors
+func (app *NetHTTPApp) Close(ctx context.Context) error {
+	var errs []error
+	for i := len(app.shutdownHooks) - 1; i >= 0; i-- {
+		if err := app.shutdownHooks[i].run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("routing: shutdown hook %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Listen boots the application and serves addr until SIGINT, SIGTERM or
+// Shutdown, then drains in-flight requests and runs the shutdown hooks.
+// When the ListenFDEnv environment variable is set, the inherited socket it
+// names is served instead of addr. SIGUSR2 hands the socket to a new release
+// of the binary, see Upgrade.
+func (app *NetHTTPApp) Listen(addr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app.mu.Lock()
+	app.stop = stop
+	app.mu.Unlock()
+
+	if err := app.Boot(ctx); err != nil {
+		return errors.Join(err, app.Close(context.Background()))
+	}
+
+	ln, err := listen(addr)
+	if err != nil {
+		return errors.Join(err, app.Close(context.Background()))
+	}
+
+	app.mu.Lock()
+	app.listener = ln
+	app.mu.Unlock()
+	defer func() {
+		app.mu.Lock()
+		app.listener = nil
+		app.mu.Unlock()
+	}()
+
+	server := &http.Server{Addr: addr, Handler: app.Handler()}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(ln) }()
+
+	notifyUpgradeParent()
+	upgradeOnSignal(ctx, ln, func(err error) {
+		errors.Report(ctx, err, errors.WithTag("routing", "upgrade"))
+	})
+
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		drainCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		err = server.Shutdown(drainCtx)
+		cancel()
+	}
+
+	return errors.Join(err, app.Close(context.Background()))
+}
+
+// ListenFDEnv names the environment variable holding the file descriptor of
+// an inherited listening socket, set by serve --watch for the processes it restarts
+const ListenFDEnv = "BOLD_LISTEN_FD"
+
+// listen returns the inherited listener named by ListenFDEnv, or a new one on addr
+func listen(addr string) (net.Listener, error) {
+	value := os.Getenv(ListenFDEnv)
+	if value == "" {
+		return listenTCP(addr)
+	}
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("routing: invalid %s %q", ListenFDEnv, value)
+	}
+	f := os.NewFile(uintptr(fd), "listener")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// Shutdown stops a running Listen as if the process received SIGTERM
+func (app *NetHTTPApp) Shutdown() {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	if app.stop != nil {
+		app.stop()
+	}
+}
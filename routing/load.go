@@ -0,0 +1,130 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+	"github.com/go-bold/bold/errors"
+)
+
+// maxLoadSamples bounds the latencies Load keeps, the most recent ones win
+const maxLoadSamples = 10000
+
+// Load tracks the requests an instance is serving, as signals for autoscalers
+// reading JSON over HTTP, such as the KEDA metrics-api scaler or a custom
+// metrics adapter of the Kubernetes HPA, without a metrics backend. Mount
+// Middleware globally and Handler on a route of its own.
+type Load struct {
+	// Window is how far back the latency percentile looks, a minute when zero
+	Window time.Duration
+	// QueueDepth reports the work waiting to be served, such as the size of a
+	// job queue, left out of the signals when nil
+	QueueDepth func(ctx context.Context) (int, error)
+	// Clock tells the time of requests, clock.Default() when nil
+	Clock clock.Clock
+
+	inFlight atomic.Int64
+	mu       sync.Mutex
+	samples  []loadSample
+}
+
+type loadSample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// LoadSignals are the signals Load.Handler responds with
+type LoadSignals struct {
+	// InFlight is the number of requests being served
+	InFlight int64 `json:"in_flight"`
+	// Requests is the number of requests completed within the window
+	Requests int `json:"requests"`
+	// P99Seconds is the 99th percentile latency of those requests, 0 without any
+	P99Seconds float64 `json:"p99_seconds"`
+	QueueDepth *int    `json:"queue_depth,omitempty"`
+}
+
+// Middleware returns the middleware counting the requests in flight and
+// recording their latency
+func (l *Load) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			l.inFlight.Add(1)
+			started := clock.Or(l.Clock).Now()
+			defer func() {
+				l.inFlight.Add(-1)
+				l.record(started)
+			}()
+			next(w, r)
+		}
+	}
+}
+
+// Signals returns the current signals
+func (l *Load) Signals(ctx context.Context) (LoadSignals, error) {
+	signals := LoadSignals{InFlight: l.inFlight.Load()}
+
+	l.mu.Lock()
+	l.prune(clock.Or(l.Clock).Now())
+	durations := make([]time.Duration, len(l.samples))
+	for i, sample := range l.samples {
+		durations[i] = sample.duration
+	}
+	l.mu.Unlock()
+
+	signals.Requests = len(durations)
+	if len(durations) > 0 {
+		slices.Sort(durations)
+		signals.P99Seconds = durations[(len(durations)*99-1)/100].Seconds()
+	}
+
+	if l.QueueDepth != nil {
+		depth, err := l.QueueDepth(ctx)
+		if err != nil {
+			return signals, err
+		}
+		signals.QueueDepth = &depth
+	}
+	return signals, nil
+}
+
+// Handler returns the handler responding with the signals as JSON. A failing
+// QueueDepth is reported and its signal left out, so scaling on the others goes on.
+func (l *Load) Handler() HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		signals, err := l.Signals(r.Context())
+		if err != nil {
+			errors.Report(r.Context(), err, errors.WithRequest(r))
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, signals)
+	}
+}
+
+func (l *Load) record(started time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := clock.Or(l.Clock).Now()
+	l.samples = append(l.samples, loadSample{at: now, duration: now.Sub(started)})
+	l.prune(now)
+}
+
+// prune drops the samples older than the window and the oldest beyond maxLoadSamples
+func (l *Load) prune(now time.Time) {
+	window := l.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+	i, _ := slices.BinarySearchFunc(l.samples, now.Add(-window), func(sample loadSample, t time.Time) int {
+		return sample.at.Compare(t)
+	})
+	i = max(i, len(l.samples)-maxLoadSamples)
+	if i > 0 {
+		l.samples = l.samples[i:]
+	}
+}
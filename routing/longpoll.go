@@ -0,0 +1,81 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// PollFunc reports whether the awaited condition holds, returning the payload to respond with when it does
+type PollFunc func(ctx context.Context) (data any, ready bool, err error)
+
+// LongPollInterval is how often LongPoll re-checks its condition between wake-ups
+var LongPollInterval = 250 * time.Millisecond
+
+var (
+	pollMu   sync.Mutex
+	pollWake = make(chan struct{})
+)
+
+// NotifyPollers wakes every pending LongPoll so it re-checks its condition
+// immediately instead of waiting for the next interval. Event publishers
+// should call it after state that pollers may be waiting on changes.
+func NotifyPollers() {
+	pollMu.Lock()
+	close(pollWake)
+	pollWake = make(chan struct{})
+	pollMu.Unlock()
+}
+
+// pollWakeup returns the channel closed by the next NotifyPollers call
+func pollWakeup() <-chan struct{} {
+	pollMu.Lock()
+	defer pollMu.Unlock()
+	return pollWake
+}
+
+// LongPoll blocks for up to wait until check reports ready, then writes its
+// payload as JSON. If the wait elapses first it responds 204 No Content so the
+// client can simply poll again, as it does when check fails because the wait
+// ran out under it. Nothing is written once the client has gone away.
+func LongPoll(w http.ResponseWriter, r *http.Request, wait time.Duration, check PollFunc) {
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	ticker := time.NewTicker(LongPollInterval)
+	defer ticker.Stop()
+
+	for {
+		wake := pollWakeup()
+
+		data, ready, err := check(ctx)
+		if err != nil && r.Context().Err() != nil {
+			return
+		}
+		if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if ready {
+			writeJSON(w, http.StatusOK, data)
+			return
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+		case <-ctx.Done():
+			if r.Context().Err() == nil {
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"text/template"
+
+	"github.com/go-bold/bold/console"
+)
+
+var identifier = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// MakeHandlerCommand returns the make:handler NAME command, which writes a
+// HandlerFunc skeleton named NAME to dir, or to the directory given by --dir
+func MakeHandlerCommand(dir string) *console.Command {
+	return makeCommand("make:handler", "Create a handler file, e.g. make:handler ShowUser", dir, handlerStub)
+}
+
+// MakeMiddlewareCommand returns the make:middleware NAME command, which writes
+// a MiddlewareFunc skeleton named NAME to dir, or to the directory given by --dir
+func MakeMiddlewareCommand(dir string) *console.Command {
+	return makeCommand("make:middleware", "Create a middleware file, e.g. make:middleware RequireAdmin", dir, middlewareStub)
+}
+
+func makeCommand(name, description, dir string, stub *template.Template) *console.Command {
+	return &console.Command{
+		Name:        name,
+		Description: description,
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("dir", dir, "directory of the generated file")
+		},
+		Run: func(ctx context.Context, in *console.Input) error {
+			if len(in.Args) != 1 {
+				return errors.New("usage: " + name + " [--dir DIR] NAME")
+			}
+			ident := in.Args[0]
+			if !identifier.MatchString(ident) {
+				return fmt.Errorf("invalid name %q, use an exported Go identifier such as ShowUser", ident)
+			}
+
+			dir := in.String("dir")
+			data := struct{ Package, Name string }{filepath.Base(dir), ident}
+			return console.Generate(in.Out, filepath.Join(dir, console.SnakeCase(ident)+".go"), stub, data)
+		},
+	}
+}
+
+var handlerStub = template.Must(template.New("handler").Parse(`package {{.Package}}
+
+import (
+	"net/http"
+
+	"github.com/go-bold/bold/routing"
+)
+
+var _ routing.HandlerFunc = {{.Name}}
+
+// {{.Name}} is a routing.HandlerFunc, register it with routing.NewRoute().GET and friends
+func {{.Name}}(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	_ = ctx
+
+	routing.WriteProblem(w, routing.Problem{Status: http.StatusNotImplemented})
+}
+`))
+
+var middlewareStub = template.Must(template.New("middleware").Parse(`package {{.Package}}
+
+import (
+	"net/http"
+
+	"github.com/go-bold/bold/routing"
+)
+
+// {{.Name}} is a routing.MiddlewareFunc, apply it with app.Use or in a route group
+func {{.Name}}(next routing.HandlerFunc) routing.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r)
+	}
+}
+`))
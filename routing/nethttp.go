@@ -1,6 +1,13 @@
 package routing
 
-import "net/http"
+import (
+	"context"
+	"html/template"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+)
 
 // HandlerFunc is the signature for route handlers
 type HandlerFunc func(w http.ResponseWriter, r *http.Request)
@@ -12,8 +19,23 @@ type MiddlewareFunc func(HandlerFunc) HandlerFunc
 type Route struct {
 	method      string
 	pattern     string
+	name        string
 	handler     HandlerFunc
 	middlewares []MiddlewareFunc
+	body        reflect.Type
+}
+
+// Name names the route for introspection tools such as route:list
+func (r *Route) Name(name string) *Route {
+	r.name = name
+	return r
+}
+
+// Accepts declares the JSON body the route decodes, given as a value of its
+// type, so request generators can produce well-formed bodies for it
+func (r *Route) Accepts(body any) *Route {
+	r.body = reflect.TypeOf(body)
+	return r
 }
 
 // handle returns the final handler with all middlewares applied
@@ -46,8 +68,10 @@ func (g *RouteGroup) flatten(parentPrefix string, parentMiddlewares []Middleware
 		r := &Route{
 			method:      route.method,
 			pattern:     fullPrefix + route.pattern,
+			name:        route.name,
 			handler:     route.handler,
 			middlewares: append(allMiddlewares, route.middlewares...),
+			body:        route.body,
 		}
 		result = append(result, r)
 	}
@@ -117,6 +141,14 @@ type NetHTTPApp struct {
 	routes      []*Route
 	groups      []*RouteGroup
 	middlewares []MiddlewareFunc
+
+	bootHooks     []*Hook
+	shutdownHooks []*Hook
+	errorPages    map[int]*template.Template
+
+	mu       sync.Mutex
+	stop     context.CancelFunc
+	listener net.Listener
 }
 
 // Routes configures the application routes
@@ -131,11 +163,13 @@ func (app *NetHTTPApp) Routes(items ...any) {
 	}
 }
 
-// Handler returns an http.Handler for the application
-func (app *NetHTTPApp) Handler() http.Handler {
-	mux := http.NewServeMux()
+// Use adds middlewares applied to every route
+func (app *NetHTTPApp) Use(middlewares ...MiddlewareFunc) {
+	app.middlewares = append(app.middlewares, middlewares...)
+}
 
-	// Collect all routes
+// allRoutes returns the direct routes followed by the flattened group routes
+func (app *NetHTTPApp) allRoutes() []*Route {
 	allRoutes := make([]*Route, 0)
 
 	// Add direct routes
@@ -146,8 +180,20 @@ func (app *NetHTTPApp) Handler() http.Handler {
 		allRoutes = append(allRoutes, group.flatten("", nil)...)
 	}
 
+	return allRoutes
+}
+
+// Handler returns an http.Handler for the application
+func (app *NetHTTPApp) Handler() http.Handler {
+	return app.recoverer(app.mux())
+}
+
+// mux returns the router with every route and middleware registered, without panic recovery
+func (app *NetHTTPApp) mux() http.Handler {
+	mux := http.NewServeMux()
+
 	// Register routes with mux
-	for _, route := range allRoutes {
+	for _, route := range app.allRoutes() {
 		pattern := route.method + " " + route.pattern
 		handler := route.handle()
 
@@ -161,8 +207,3 @@ func (app *NetHTTPApp) Handler() http.Handler {
 
 	return mux
 }
-
-// Listen starts the HTTP server
-func (app *NetHTTPApp) Listen(addr string) error {
-	return http.ListenAndServe(addr, app.Handler())
-}
@@ -0,0 +1,211 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+	"github.com/go-bold/bold/driver"
+)
+
+// QuotaLimits are the requests a principal may make per UTC day and month, zero meaning unlimited
+type QuotaLimits struct {
+	Daily   int64
+	Monthly int64
+}
+
+// Quota counts the requests of each API principal against daily and monthly
+// limits kept in a KV store shared by all instances, for APIs sold by volume.
+// Every response carries the state of each limited period:
+//
+//	X-Quota-Daily-Limit: 1000
+//	X-Quota-Daily-Remaining: 998
+//	X-Quota-Daily-Reset: 3600
+//
+// Reset is the number of seconds until the period ends. Monthly headers follow
+// the same pattern.
+type Quota struct {
+	KV driver.KV
+	// Principal identifies who the request is counted against, such as the ID
+	// of its API token. Requests without a principal are not counted.
+	Principal func(r *http.Request) string
+	// Limits returns the limits of a principal, such as those of its plan, Default when nil
+	Limits func(ctx context.Context, principal string) (QuotaLimits, error)
+	// Default applies to principals when Limits is nil
+	Default QuotaLimits
+	// Soft serves requests over quota too, only reporting them through the
+	// headers and OnExceeded, for plans billing overage
+	Soft bool
+	// OnExceeded is called for each request over quota
+	OnExceeded func(r *http.Request, principal string, usage QuotaUsage)
+	// Clock tells the periods of requests, clock.Default() when nil
+	Clock clock.Clock
+}
+
+// QuotaUsage is the consumption of a principal in the current periods
+type QuotaUsage struct {
+	Principal string      `json:"principal"`
+	Daily     QuotaPeriod `json:"daily"`
+	Monthly   QuotaPeriod `json:"monthly"`
+}
+
+// QuotaPeriod is the consumption of a principal in a period
+type QuotaPeriod struct {
+	Used int64 `json:"used"`
+	// Limit is zero for unlimited periods, whose Remaining is zero as well
+	Limit     int64     `json:"limit"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+func (p QuotaPeriod) exceeded() bool {
+	return p.Limit > 0 && p.Used > p.Limit
+}
+
+// Middleware returns the middleware counting requests. Requests over a hard
+// quota are rejected with 429 and a Retry-After header, and still counted.
+// The store being unavailable lets requests through uncounted.
+func (q *Quota) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal := ""
+			if q.Principal != nil {
+				principal = q.Principal(r)
+			}
+			if principal == "" {
+				next(w, r)
+				return
+			}
+
+			usage, err := q.count(r.Context(), principal)
+			if err != nil {
+				next(w, r)
+				return
+			}
+			now := clock.Or(q.Clock).Now()
+			q.setHeaders(w, "Daily", usage.Daily, now)
+			q.setHeaders(w, "Monthly", usage.Monthly, now)
+
+			if usage.Daily.exceeded() || usage.Monthly.exceeded() {
+				if q.OnExceeded != nil {
+					q.OnExceeded(r, principal, usage)
+				}
+				if !q.Soft {
+					reset := usage.Daily.Reset
+					if usage.Monthly.exceeded() {
+						reset = usage.Monthly.Reset
+					}
+					w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+					WriteProblem(w, Problem{Status: http.StatusTooManyRequests, Detail: "the API quota is exhausted"})
+					return
+				}
+			}
+			next(w, r)
+		}
+	}
+}
+
+// Usage returns the consumption of principal without counting a request, for
+// dashboards and billing
+func (q *Quota) Usage(ctx context.Context, principal string) (QuotaUsage, error) {
+	limits, err := q.limits(ctx, principal)
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+
+	usage := QuotaUsage{Principal: principal}
+	now := clock.Or(q.Clock).Now().UTC()
+	for _, period := range q.periods(&usage, limits, now) {
+		value, ok, err := q.KV.Get(ctx, period.key)
+		if err != nil {
+			return QuotaUsage{}, err
+		}
+		var used int64
+		if ok {
+			used, _ = strconv.ParseInt(string(value), 10, 64)
+		}
+		period.set(used)
+	}
+	return usage, nil
+}
+
+// UsageHandler returns a handler answering the consumption of the request principal as JSON
+func (q *Quota) UsageHandler() HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := ""
+		if q.Principal != nil {
+			principal = q.Principal(r)
+		}
+		if principal == "" {
+			WriteProblem(w, Problem{Status: http.StatusUnauthorized, Detail: "authentication is required"})
+			return
+		}
+		usage, err := q.Usage(r.Context(), principal)
+		if err != nil {
+			WriteProblem(w, Problem{Status: http.StatusServiceUnavailable})
+			return
+		}
+		writeJSON(w, http.StatusOK, usage)
+	}
+}
+
+// count records a request of principal in both periods, limited or not, so Usage reports it for billing
+func (q *Quota) count(ctx context.Context, principal string) (QuotaUsage, error) {
+	limits, err := q.limits(ctx, principal)
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+
+	usage := QuotaUsage{Principal: principal}
+	now := clock.Or(q.Clock).Now().UTC()
+	for _, period := range q.periods(&usage, limits, now) {
+		used, err := q.KV.Incr(ctx, period.key, period.reset.Sub(now))
+		if err != nil {
+			return QuotaUsage{}, err
+		}
+		period.set(used)
+	}
+	return usage, nil
+}
+
+func (q *Quota) limits(ctx context.Context, principal string) (QuotaLimits, error) {
+	if q.Limits == nil {
+		return q.Default, nil
+	}
+	return q.Limits(ctx, principal)
+}
+
+// quotaPeriod ties a period of a QuotaUsage to its counter
+type quotaPeriod struct {
+	target *QuotaPeriod
+	key    string
+	limit  int64
+	reset  time.Time
+}
+
+func (p quotaPeriod) set(used int64) {
+	*p.target = QuotaPeriod{Used: used, Limit: p.limit, Reset: p.reset}
+	if p.limit > 0 {
+		p.target.Remaining = max(p.limit-used, 0)
+	}
+}
+
+func (q *Quota) periods(usage *QuotaUsage, limits QuotaLimits, now time.Time) []quotaPeriod {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return []quotaPeriod{
+		{&usage.Daily, "quota:daily:" + usage.Principal + ":" + day.Format("20060102"), limits.Daily, day.AddDate(0, 0, 1)},
+		{&usage.Monthly, "quota:monthly:" + usage.Principal + ":" + month.Format("200601"), limits.Monthly, month.AddDate(0, 1, 0)},
+	}
+}
+
+func (q *Quota) setHeaders(w http.ResponseWriter, name string, period QuotaPeriod, now time.Time) {
+	if period.Limit == 0 {
+		return
+	}
+	w.Header().Set("X-Quota-"+name+"-Limit", strconv.FormatInt(period.Limit, 10))
+	w.Header().Set("X-Quota-"+name+"-Remaining", strconv.FormatInt(period.Remaining, 10))
+	w.Header().Set("X-Quota-"+name+"-Reset", strconv.Itoa(int(period.Reset.Sub(now).Seconds())))
+}
@@ -0,0 +1,281 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// Redacted replaces the values hidden by a Recorder
+const Redacted = "[REDACTED]"
+
+// Recording is a request and response pair captured by a Recorder
+type Recording struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// LoadRecording reads a recording written by a Recorder, e.g. as a golden fixture
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("routing: recording %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+// NewRequest returns the recorded request, for replaying it against a handler
+func (rec *Recording) NewRequest() *http.Request {
+	r, _ := http.NewRequest(rec.Request.Method, rec.Request.URL, strings.NewReader(rec.Request.Body))
+	if r == nil {
+		return nil
+	}
+	r.Header = rec.Request.Header.Clone()
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	return r
+}
+
+// Recorder captures request and response pairs into JSON files under Dir,
+// one file per request, for use as fixtures in handler and contract tests.
+// Credentials are redacted before anything is written.
+type Recorder struct {
+	Dir string
+	// SampleRate is the fraction of requests recorded, all of them when zero
+	SampleRate float64
+	// RedactHeaders are headers whose values are redacted, in addition to
+	// Authorization, Cookie, Set-Cookie and Proxy-Authorization
+	RedactHeaders []string
+	// RedactFields are query parameters, form fields and JSON body fields, at
+	// any depth, whose values are redacted, such as password or token, matched
+	// case-insensitively. Bodies that cannot be parsed are redacted whole.
+	RedactFields []string
+	// MaxBodySize truncates recorded bodies, 64KB when zero. A truncated body
+	// is redacted whole when RedactFields is set, as it cannot be parsed.
+	MaxBodySize int64
+	// Skip excludes requests from recording, such as health checks
+	Skip func(r *http.Request) bool
+
+	seq atomic.Uint64
+}
+
+var defaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// Middleware returns the recording middleware
+func (rec *Recorder) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if (rec.Skip != nil && rec.Skip(r)) || (rec.SampleRate > 0 && rand.Float64() >= rec.SampleRate) {
+				next(w, r)
+				return
+			}
+
+			var reqBody []byte
+			var reqTruncated bool
+			if r.Body != nil && r.Body != http.NoBody {
+				// one byte past the limit tells a truncated body from one of the limit size
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, rec.maxBodySize()+1))
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+				if reqTruncated = int64(len(reqBody)) > rec.maxBodySize(); reqTruncated {
+					reqBody = reqBody[:rec.maxBodySize()]
+				}
+			}
+
+			rw := &recordingWriter{responseWriter: newResponseWriter(w), limit: rec.maxBodySize()}
+			next(rw, r)
+
+			recording := Recording{
+				Request: RecordedRequest{
+					Method: r.Method,
+					URL:    rec.redactURL(r.URL),
+					Header: rec.redactHeader(r.Header),
+					Body:   rec.redactBody(reqBody, r.Header.Get("Content-Type"), reqTruncated),
+				},
+				Response: RecordedResponse{
+					Status: rw.Status(),
+					Header: rec.redactHeader(rw.Header()),
+					Body:   rec.redactBody(rw.body.Bytes(), rw.Header().Get("Content-Type"), rw.truncated),
+				},
+				RecordedAt: time.Now().UTC(),
+			}
+			if err := rec.write(r, recording); err != nil {
+				errors.Report(r.Context(), err, errors.WithRequest(r))
+			}
+		}
+	}
+}
+
+func (rec *Recorder) maxBodySize() int64 {
+	if rec.MaxBodySize > 0 {
+		return rec.MaxBodySize
+	}
+	return 64 << 10
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// write stores recording as <time>_<seq>_<method>_<path>.json
+func (rec *Recorder) write(r *http.Request, recording Recording) error {
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(recording); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(rec.Dir, 0o755); err != nil {
+		return err
+	}
+
+	path := strings.Trim(unsafePathChars.ReplaceAllString(r.URL.Path, "_"), "_")
+	if path == "" {
+		path = "root"
+	}
+	name := fmt.Sprintf("%s_%d_%s_%s.json", recording.RecordedAt.Format("20060102T150405"), rec.seq.Add(1), strings.ToLower(r.Method), path)
+	return os.WriteFile(filepath.Join(rec.Dir, name), data.Bytes(), 0o644)
+}
+
+func (rec *Recorder) redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range slices.Concat(defaultRedactedHeaders, rec.RedactHeaders) {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, Redacted)
+		}
+	}
+	return h
+}
+
+func (rec *Recorder) redactURL(u *url.URL) string {
+	if u.RawQuery == "" || len(rec.RedactFields) == 0 {
+		return u.RequestURI()
+	}
+	// Query drops the pairs that do not parse, so none are written unredacted
+	redacted := *u
+	redacted.RawQuery = rec.redactValues(u.Query()).Encode()
+	return redacted.RequestURI()
+}
+
+// redactValues redacts RedactFields in query parameters and form fields
+func (rec *Recorder) redactValues(values url.Values) url.Values {
+	for key, value := range values {
+		if rec.redactsField(key) {
+			for i := range value {
+				value[i] = Redacted
+			}
+		}
+	}
+	return values
+}
+
+// redactBody redacts RedactFields in form and JSON bodies. Truncated bodies
+// and bodies that do not parse are redacted whole, the fields they hold
+// cannot be found.
+func (rec *Recorder) redactBody(body []byte, contentType string, truncated bool) string {
+	if len(rec.RedactFields) == 0 || len(body) == 0 {
+		return string(body)
+	}
+	if truncated {
+		return Redacted
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return Redacted
+		}
+		return rec.redactValues(values).Encode()
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return Redacted
+	}
+	redacted, err := json.Marshal(rec.redactValue(v))
+	if err != nil {
+		return Redacted
+	}
+	return string(redacted)
+}
+
+func (rec *Recorder) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if rec.redactsField(key) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = rec.redactValue(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = rec.redactValue(value)
+		}
+	}
+	return v
+}
+
+func (rec *Recorder) redactsField(name string) bool {
+	for _, field := range rec.RedactFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingWriter keeps a copy of the first limit bytes of the response body
+type recordingWriter struct {
+	*responseWriter
+	body      bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	room := rw.limit - int64(rw.body.Len())
+	if room > 0 {
+		rw.body.Write(b[:min(int64(len(b)), room)])
+	}
+	if int64(len(b)) > room {
+		rw.truncated = true
+	}
+	return rw.responseWriter.Write(b)
+}
+
+// readCloser reads the replayed request body while closing the original one
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
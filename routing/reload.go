@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"net/http"
+
+	"github.com/go-bold/bold/config"
+	"github.com/go-bold/bold/errors"
+)
+
+// ConfigReload mounts a POST endpoint at path calling config.Reload, guarded
+// by the middlewares. At least one is required, ConfigReload panics without
+// any rather than letting anyone reload the configuration.
+func (app *NetHTTPApp) ConfigReload(path string, middlewares ...MiddlewareFunc) {
+	if len(middlewares) == 0 {
+		panic("routing: ConfigReload requires a middleware guarding its endpoint")
+	}
+
+	r := NewRoute()
+	app.Routes(r.Group("", middlewares, r.POST(path, func(w http.ResponseWriter, r *http.Request) {
+		if err := config.Reload(); err != nil {
+			errors.Report(r.Context(), err, errors.WithRequest(r))
+			WriteProblem(w, Problem{Status: http.StatusInternalServerError, Detail: "the configuration could not be reloaded"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"reloaded": true, "env": config.Env()})
+	})))
+}
+
+// Maintenance answers 503 while config.Maintenance reports true, letting
+// requests whose path is in except through. The switch is read on every
+// request, so toggling app.maintenance and reloading takes effect at once.
+func Maintenance(except ...string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !config.Maintenance() {
+				next(w, r)
+				return
+			}
+			for _, path := range except {
+				if r.URL.Path == path {
+					next(w, r)
+					return
+				}
+			}
+			w.Header().Set("Retry-After", config.String("app.maintenance_retry_after", "60"))
+			WriteProblem(w, Problem{Status: http.StatusServiceUnavailable, Detail: "the application is down for maintenance"})
+		}
+	}
+}
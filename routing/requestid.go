@@ -0,0 +1,39 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/go-bold/bold/idgen"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID returns middleware giving every request an ID, kept from the
+// X-Request-ID header when a proxy set a well formed one and otherwise taken
+// from idgen.Default(). The ID is echoed in the response header and available
+// to handlers through RequestIDFrom.
+func RequestID() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(id) {
+				id = idgen.New()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		}
+	}
+}
+
+// RequestIDFrom returns the ID RequestID gave the request of ctx, or ""
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
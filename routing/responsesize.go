@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// ErrResponseTooLarge is returned by the writes of a handler beyond the limit of MaxResponseSize
+var ErrResponseTooLarge = errors.New("routing: response too large")
+
+// MaxResponseSize returns middleware failing the responses of handlers
+// writing more than limit bytes, such as a listing missing its pagination.
+// Writes beyond the limit return ErrResponseTooLarge and the response is
+// reported. When nothing was sent yet the client gets a 500 error response,
+// otherwise the connection is aborted, so the truncated body cannot pass for
+// the whole response.
+func MaxResponseSize(limit int64) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			lw := &limitWriter{ResponseWriter: w, limit: limit}
+			next(lw, r)
+			if !lw.exceeded {
+				return
+			}
+
+			errors.Report(r.Context(), fmt.Errorf("%w: over %d bytes", ErrResponseTooLarge, limit), errors.WithRequest(r))
+			if lw.started {
+				panic(http.ErrAbortHandler)
+			}
+			w.Header().Del("Content-Length")
+			WriteProblem(w, Problem{Status: http.StatusInternalServerError})
+		}
+	}
+}
+
+// limitWriter passes writes through up to limit bytes
+type limitWriter struct {
+	http.ResponseWriter
+	limit   int64
+	written int64
+	// started is set once the status or part of the body reached the client
+	started  bool
+	exceeded bool
+}
+
+func (w *limitWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *limitWriter) Write(b []byte) (int, error) {
+	if w.exceeded || w.written+int64(len(b)) > w.limit {
+		w.exceeded = true
+		return 0, ErrResponseTooLarge
+	}
+	w.started = true
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *limitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,118 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-bold/bold/console"
+)
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Name    string `json:"name,omitempty"`
+	Handler string `json:"handler"`
+	// Middlewares are the function names of the middlewares, outermost first
+	Middlewares []string `json:"middlewares"`
+}
+
+// RouteList returns the registered routes in registration order
+func (app *NetHTTPApp) RouteList() []RouteInfo {
+	var infos []RouteInfo
+	for _, route := range app.allRoutes() {
+		info := RouteInfo{
+			Method:      route.method,
+			Pattern:     route.pattern,
+			Name:        route.name,
+			Handler:     funcName(route.handler),
+			Middlewares: []string{},
+		}
+		for _, mw := range append(append([]MiddlewareFunc{}, app.middlewares...), route.middlewares...) {
+			info.Middlewares = append(info.Middlewares, funcName(mw))
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+|-fm)+$`)
+
+// funcName returns the package qualified name of fn, naming closures after the function that created them
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := closureSuffix.ReplaceAllString(f.Name(), "")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// RouteListCommand returns the route:list console command listing the routes
+// of app as a table or, with --json, as JSON. The --method, --name, --path
+// and --middleware flags keep the routes matching them, the last three by substring.
+func RouteListCommand(app *NetHTTPApp) *console.Command {
+	return &console.Command{
+		Name:        "route:list",
+		Description: "List the registered routes",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("method", "", "only routes with this HTTP method")
+			fs.String("name", "", "only routes whose name contains this")
+			fs.String("path", "", "only routes whose pattern contains this")
+			fs.String("middleware", "", "only routes with a middleware whose name contains this")
+			fs.Bool("json", false, "print JSON instead of a table")
+		},
+		Run: func(ctx context.Context, in *console.Input) error {
+			routes := []RouteInfo{}
+			for _, route := range app.RouteList() {
+				if matchRoute(route, in) {
+					routes = append(routes, route)
+				}
+			}
+
+			if in.Bool("json") {
+				enc := json.NewEncoder(in.Out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(routes)
+			}
+
+			w := tabwriter.NewWriter(in.Out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARE")
+			for _, r := range routes {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Method, r.Pattern, r.Name, r.Handler, strings.Join(r.Middlewares, ", "))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func matchRoute(route RouteInfo, in *console.Input) bool {
+	if method := in.String("method"); method != "" && !strings.EqualFold(route.Method, method) {
+		return false
+	}
+	if name := in.String("name"); name != "" && !strings.Contains(route.Name, name) {
+		return false
+	}
+	if path := in.String("path"); path != "" && !strings.Contains(route.Pattern, path) {
+		return false
+	}
+	if mw := in.String("middleware"); mw != "" {
+		for _, m := range route.Middlewares {
+			if strings.Contains(m, mw) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
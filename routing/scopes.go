@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type scopesKey struct{}
+
+// WithScopes returns a copy of ctx carrying the scopes granted to the
+// authenticated principal. Token authentication middleware calls it once the
+// JWT or API token has been verified.
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// Scopes returns the scopes granted to the request principal and whether a principal was authenticated
+func Scopes(r *http.Request) ([]string, bool) {
+	scopes, ok := r.Context().Value(scopesKey{}).([]string)
+	return scopes, ok
+}
+
+// RequireScopes returns middleware rejecting requests whose principal lacks
+// any of the required scopes. Granted scopes may use wildcards: "orders:*"
+// covers "orders:write" and "*" covers everything.
+func RequireScopes(required ...string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			granted, ok := Scopes(r)
+			if !ok {
+				WriteProblem(w, Problem{Status: http.StatusUnauthorized, Detail: "authentication is required"})
+				return
+			}
+
+			var missing []string
+			for _, scope := range required {
+				if !scopeGranted(granted, scope) {
+					missing = append(missing, scope)
+				}
+			}
+			if len(missing) > 0 {
+				WriteProblem(w, Problem{
+					Status: http.StatusForbidden,
+					Detail: "the token is missing required scopes",
+					Extra:  map[string]any{"missing_scopes": missing},
+				})
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// scopeGranted reports whether any granted scope covers the required one
+func scopeGranted(granted []string, required string) bool {
+	for _, g := range granted {
+		if g == required || g == "*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(g, "*"); ok && strings.HasPrefix(required, prefix) {
+			return true
+		}
+	}
+	return false
+}
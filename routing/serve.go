@@ -0,0 +1,186 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-bold/bold/console"
+)
+
+// WatchInterval is how often serve --watch polls the Go files for changes
+var WatchInterval = 500 * time.Millisecond
+
+// ServeCommand returns the serve command, which runs app.Listen on --addr,
+// addr by default. With --watch it is a development supervisor instead: it
+// builds --package, runs the binary's serve command and rebuilds and restarts
+// it whenever a Go file under the working directory changes. The supervisor
+// owns the listening socket and hands it to each process through ListenFDEnv,
+// so requests made during a restart wait for the new process instead of
+// failing. Socket handoff requires a Unix system.
+func ServeCommand(app *NetHTTPApp, addr string) *console.Command {
+	return &console.Command{
+		Name:        "serve",
+		Description: "Serve the application",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("addr", addr, "address to listen on")
+			fs.Bool("watch", false, "rebuild and restart on Go file changes")
+			fs.String("package", ".", "package of the main function, for --watch")
+		},
+		Run: func(ctx context.Context, in *console.Input) error {
+			if !in.Bool("watch") {
+				return app.Listen(in.String("addr"))
+			}
+			w := &watcher{addr: in.String("addr"), pkg: in.String("package"), out: in.Out}
+			return w.run(ctx)
+		},
+	}
+}
+
+// watcher rebuilds and restarts the application for serve --watch
+type watcher struct {
+	addr string
+	pkg  string
+	out  io.Writer
+
+	bin   string
+	child *exec.Cmd
+	exit  chan struct{}
+}
+
+func (w *watcher) run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", w.addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	socket, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		return err
+	}
+	defer socket.Close()
+
+	dir, err := os.MkdirTemp("", "bold-serve")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	w.bin = filepath.Join(dir, "app")
+
+	fmt.Fprintf(w.out, "Watching for changes, serving on %s\n", w.addr)
+	w.restart(ctx, socket)
+	defer w.terminate()
+
+	last, _ := snapshot(".")
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := snapshot(".")
+		if err != nil || current == last {
+			continue
+		}
+		last = current
+		fmt.Fprintln(w.out, "Change detected, rebuilding")
+		w.restart(ctx, socket)
+	}
+}
+
+// restart builds the application and, when the build succeeds, replaces the
+// running process. A failed build keeps the previous process serving.
+func (w *watcher) restart(ctx context.Context, socket *os.File) {
+	build := exec.CommandContext(ctx, "go", "build", "-o", w.bin, w.pkg)
+	build.Stdout, build.Stderr = w.out, w.out
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(w.out, "Build failed: %v\n", err)
+		return
+	}
+
+	w.terminate()
+
+	child := exec.Command(w.bin, "serve", "--addr", w.addr)
+	child.Stdout, child.Stderr = os.Stdout, os.Stderr
+	// ExtraFiles[0] becomes file descriptor 3 in the child
+	child.ExtraFiles = []*os.File{socket}
+	child.Env = append(os.Environ(), ListenFDEnv+"=3")
+	if err := child.Start(); err != nil {
+		fmt.Fprintf(w.out, "Start failed: %v\n", err)
+		return
+	}
+
+	exit := make(chan struct{})
+	go func() {
+		child.Wait()
+		close(exit)
+	}()
+	w.child, w.exit = child, exit
+}
+
+// terminate stops the running process gracefully, killing it if it has not
+// drained within ShutdownTimeout
+func (w *watcher) terminate() {
+	if w.child == nil {
+		return
+	}
+	child, exit := w.child, w.exit
+	w.child, w.exit = nil, nil
+
+	if err := child.Process.Signal(syscall.SIGTERM); err != nil {
+		child.Process.Kill()
+	}
+	select {
+	case <-exit:
+	case <-time.After(ShutdownTimeout):
+		child.Process.Kill()
+		<-exit
+	}
+}
+
+// snapshot fingerprints the Go files under root by name, size and modification time
+func snapshot(root string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
@@ -0,0 +1,212 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// Querier runs statements, it is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ErrUnknownTenant is returned by Connect for tenants that do not exist,
+// which Tenancy.Middleware rejects with 404
+var ErrUnknownTenant = errors.New("routing: unknown tenant")
+
+type tenantKey struct{}
+
+type tenantValue struct {
+	name string
+	db   Querier
+}
+
+// Tenancy resolves the tenant of each request and hands its handlers the
+// database of that tenant through TenantDB, either a database of its own
+// opened by Connect, or a connection of DB switched to the PostgreSQL schema
+// Schema names. On MySQL, where schemas are databases, use Connect.
+type Tenancy struct {
+	// Resolve returns the tenant of a request, "" when unknown, see
+	// TenantFromSubdomain and TenantFromHeader
+	Resolve func(r *http.Request) string
+	// Connect opens the database of a tenant, returning ErrUnknownTenant
+	// for tenants that do not exist. Handles that answer a ping are kept
+	// open for the following requests until Close.
+	Connect func(ctx context.Context, tenant string) (*sql.DB, error)
+	// DB and Schema switch a connection of DB to the schema of the tenant
+	// with SET search_path for the duration of the request, when Connect is
+	// nil. Tenants without a schema are unknown.
+	DB     *sql.DB
+	Schema func(tenant string) string
+
+	mu  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+// Middleware returns the middleware resolving the tenant. Requests of unknown
+// tenants, see ErrUnknownTenant, are rejected with 404, and with 503 when
+// their database is unavailable.
+func (t *Tenancy) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tenant := t.Resolve(r)
+			if tenant == "" {
+				WriteProblem(w, Problem{Status: http.StatusNotFound, Detail: "unknown tenant"})
+				return
+			}
+
+			if t.Connect != nil {
+				db, err := t.open(r.Context(), tenant)
+				if err != nil {
+					tenantError(w, r, tenant, err)
+					return
+				}
+				next(w, r.WithContext(WithTenant(r.Context(), tenant, db)))
+				return
+			}
+
+			conn, err := t.switchSchema(r.Context(), tenant)
+			if err != nil {
+				tenantError(w, r, tenant, err)
+				return
+			}
+			defer releaseSchema(conn)
+			next(w, r.WithContext(WithTenant(r.Context(), tenant, conn)))
+		}
+	}
+}
+
+// tenantError rejects a request whose tenant database could not be had,
+// with 404 for unknown tenants and 503 otherwise
+func tenantError(w http.ResponseWriter, r *http.Request, tenant string, err error) {
+	if errors.Is(err, ErrUnknownTenant) {
+		WriteProblem(w, Problem{Status: http.StatusNotFound, Detail: "unknown tenant"})
+		return
+	}
+	errors.Report(r.Context(), err, errors.WithRequest(r), errors.WithTag("tenant", tenant))
+	WriteProblem(w, Problem{Status: http.StatusServiceUnavailable})
+}
+
+// Close closes the databases Connect opened
+func (t *Tenancy) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var errs []error
+	for tenant, db := range t.dbs {
+		errs = append(errs, db.Close())
+		delete(t.dbs, tenant)
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Tenancy) open(ctx context.Context, tenant string) (*sql.DB, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if db, ok := t.dbs[tenant]; ok {
+		return db, nil
+	}
+	db, err := t.Connect(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	// sql.Open does not connect, a handle is only kept once it reached its database
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if t.dbs == nil {
+		t.dbs = map[string]*sql.DB{}
+	}
+	t.dbs[tenant] = db
+	return db, nil
+}
+
+// switchSchema pins a connection of DB to the request, as search_path is set per session
+func (t *Tenancy) switchSchema(ctx context.Context, tenant string) (*sql.Conn, error) {
+	schema := tenant
+	if t.Schema != nil {
+		schema = t.Schema(tenant)
+	}
+
+	conn, err := t.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", schema).Scan(&exists); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !exists {
+		conn.Close()
+		return nil, ErrUnknownTenant
+	}
+	quoted := `"` + strings.ReplaceAll(schema, `"`, `""`) + `"`
+	if _, err := conn.ExecContext(ctx, "SET search_path TO "+quoted); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// releaseSchema returns the connection to the pool with the default search
+// path, or discards it when that fails so no later request sees the tenant
+func releaseSchema(conn *sql.Conn) {
+	if _, err := conn.ExecContext(context.Background(), "RESET search_path"); err != nil {
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	conn.Close()
+}
+
+// WithTenant returns a copy of ctx carrying the tenant and its database, for
+// jobs and commands running outside of Tenancy.Middleware
+func WithTenant(ctx context.Context, tenant string, db Querier) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantValue{name: tenant, db: db})
+}
+
+// TenantFrom returns the tenant of ctx and whether one was resolved
+func TenantFrom(ctx context.Context) (string, bool) {
+	value, ok := ctx.Value(tenantKey{}).(tenantValue)
+	return value.name, ok
+}
+
+// TenantDB returns the database of the request tenant, nil outside of Tenancy.Middleware
+func TenantDB(ctx context.Context) Querier {
+	value, _ := ctx.Value(tenantKey{}).(tenantValue)
+	return value.db
+}
+
+// TenantFromSubdomain resolves the tenant from the leftmost label of hosts
+// under domain, such as acme for acme.example.com under example.com
+func TenantFromSubdomain(domain string) func(r *http.Request) string {
+	suffix := "." + strings.TrimPrefix(domain, ".")
+	return func(r *http.Request) string {
+		host := r.Host
+		if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+			host = host[:i]
+		}
+		label, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+		if !ok || strings.Contains(label, ".") {
+			return ""
+		}
+		return label
+	}
+}
+
+// TenantFromHeader resolves the tenant from a request header such as X-Tenant-ID,
+// which must be set by a trusted proxy or checked against the authenticated principal
+func TenantFromHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
@@ -0,0 +1,253 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+	"github.com/go-bold/bold/driver"
+)
+
+// ThrottleState is the failed attempt bookkeeping for a single throttle key
+type ThrottleState struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// ThrottleStore persists throttle state, e.g. in a cache shared by all instances
+type ThrottleStore interface {
+	Get(key string) (ThrottleState, error)
+	// Incr atomically counts a failure at key and returns the failures so
+	// far, starting the ttl of key when it is created
+	Incr(key string, ttl time.Duration) (int, error)
+	// Lock locks key out until the given time
+	Lock(key string, until time.Time, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// LoginThrottle protects login endpoints against brute-force attacks by
+// counting failed attempts per client IP and per account identifier, locking
+// either out for an exponentially growing period once MaxAttempts is reached.
+type LoginThrottle struct {
+	Store ThrottleStore
+	// Identifier extracts the account identifier (e.g. the submitted email) from the request
+	Identifier func(r *http.Request) string
+	// MaxAttempts is the number of failures allowed before locking out, 5 when zero
+	MaxAttempts int
+	// BaseLockout is the first lockout period, doubled on every further failure, 1 minute when zero
+	BaseLockout time.Duration
+	// MaxLockout caps the lockout period, 1 hour when zero
+	MaxLockout time.Duration
+	// OnLockout is called when a key gets locked out
+	OnLockout func(r *http.Request, key string, until time.Time)
+	// Clock tells the time of lockouts, clock.Default() when nil
+	Clock clock.Clock
+}
+
+// Middleware returns the throttling middleware. A response of 401 or 422
+// counts as a failed login and a 2xx or 3xx response clears the counters.
+// Locked out requests are rejected with 429 and a Retry-After header.
+func (t *LoginThrottle) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			keys := t.keys(r)
+			now := clock.Or(t.Clock).Now()
+
+			for _, key := range keys {
+				state, err := t.Store.Get(key)
+				if err != nil {
+					continue
+				}
+				if state.LockedUntil.After(now) {
+					retry := int(state.LockedUntil.Sub(now).Seconds()) + 1
+					w.Header().Set("Retry-After", strconv.Itoa(retry))
+					http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			rw := newResponseWriter(w)
+			next(rw, r)
+
+			switch status := rw.Status(); {
+			case status == http.StatusUnauthorized || status == http.StatusUnprocessableEntity:
+				for _, key := range keys {
+					t.fail(r, key)
+				}
+			case status < 400:
+				for _, key := range keys {
+					t.Store.Delete(key)
+				}
+			}
+		}
+	}
+}
+
+// keys returns the throttle keys the request is counted against
+func (t *LoginThrottle) keys(r *http.Request) []string {
+	var keys []string
+	if ip := ClientIP(r); ip.IsValid() {
+		keys = append(keys, "login:ip:"+ip.String())
+	}
+	if t.Identifier != nil {
+		if id := t.Identifier(r); id != "" {
+			keys = append(keys, "login:id:"+id)
+		}
+	}
+	return keys
+}
+
+// fail records a failed attempt for key, locking it out once the limit is reached
+func (t *LoginThrottle) fail(r *http.Request, key string) {
+	maxAttempts := t.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = 5
+	}
+	base := t.BaseLockout
+	if base == 0 {
+		base = time.Minute
+	}
+	maxLockout := t.MaxLockout
+	if maxLockout == 0 {
+		maxLockout = time.Hour
+	}
+
+	failures, err := t.Store.Incr(key, 2*maxLockout)
+	if err != nil || failures < maxAttempts {
+		return
+	}
+
+	lockout := base << min(failures-maxAttempts, 30)
+	if lockout <= 0 || lockout > maxLockout {
+		lockout = maxLockout
+	}
+	until := clock.Or(t.Clock).Now().Add(lockout)
+	if t.Store.Lock(key, until, 2*maxLockout) != nil {
+		return
+	}
+	if t.OnLockout != nil {
+		t.OnLockout(r, key, until)
+	}
+}
+
+// MemoryThrottleStore is an in-process ThrottleStore for single instance deployments
+type MemoryThrottleStore struct {
+	// Clock tells when entries expire, clock.Default() when nil. Set it to
+	// the Clock of the LoginThrottle, so lockouts and their entries share a time.
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]memoryThrottleEntry
+}
+
+type memoryThrottleEntry struct {
+	state   ThrottleState
+	expires time.Time
+}
+
+func NewMemoryThrottleStore() *MemoryThrottleStore {
+	return &MemoryThrottleStore{}
+}
+
+// entry returns the live entry at key, or a new one expiring after ttl
+func (s *MemoryThrottleStore) entry(key string, ttl time.Duration) memoryThrottleEntry {
+	now := clock.Or(s.Clock).Now()
+	if entry, ok := s.entries[key]; ok && !now.After(entry.expires) {
+		return entry
+	}
+	if s.entries == nil {
+		s.entries = map[string]memoryThrottleEntry{}
+	}
+	return memoryThrottleEntry{expires: now.Add(ttl)}
+}
+
+func (s *MemoryThrottleStore) Get(key string) (ThrottleState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || clock.Or(s.Clock).Now().After(entry.expires) {
+		delete(s.entries, key)
+		return ThrottleState{}, nil
+	}
+	return entry.state, nil
+}
+
+func (s *MemoryThrottleStore) Incr(key string, ttl time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entry(key, ttl)
+	entry.state.Failures++
+	s.entries[key] = entry
+	return entry.state.Failures, nil
+}
+
+func (s *MemoryThrottleStore) Lock(key string, until time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entry(key, ttl)
+	if until.After(entry.state.LockedUntil) {
+		entry.state.LockedUntil = until
+	}
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryThrottleStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// KVThrottleStore is a ThrottleStore backed by a driver.KV, sharing lockouts
+// across instances. Failures are counted with KV.Incr at key:failures and the
+// end of a lockout is stored at key:locked.
+type KVThrottleStore struct {
+	KV driver.KV
+}
+
+func (s KVThrottleStore) Get(key string) (ThrottleState, error) {
+	var state ThrottleState
+	ctx := context.Background()
+
+	value, ok, err := s.KV.Get(ctx, key+":failures")
+	if err != nil {
+		return state, err
+	}
+	if ok {
+		if state.Failures, err = strconv.Atoi(string(value)); err != nil {
+			return state, err
+		}
+	}
+
+	value, ok, err = s.KV.Get(ctx, key+":locked")
+	if err != nil || !ok {
+		return state, err
+	}
+	until, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return state, err
+	}
+	state.LockedUntil = time.Unix(0, until)
+	return state, nil
+}
+
+func (s KVThrottleStore) Incr(key string, ttl time.Duration) (int, error) {
+	n, err := s.KV.Incr(context.Background(), key+":failures", ttl)
+	return int(n), err
+}
+
+func (s KVThrottleStore) Lock(key string, until time.Time, ttl time.Duration) error {
+	return s.KV.Set(context.Background(), key+":locked", []byte(strconv.FormatInt(until.UnixNano(), 10)), ttl)
+}
+
+func (s KVThrottleStore) Delete(key string) error {
+	return s.KV.Delete(context.Background(), key+":failures", key+":locked")
+}
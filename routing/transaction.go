@@ -0,0 +1,100 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/go-bold/bold/errors"
+)
+
+type txKey struct{}
+
+// Transaction returns middleware running each request in a transaction of
+// db, available to handlers through TxFrom, so a request either applies all
+// of its writes or none. The transaction commits when the handler responds
+// with a status below 400 and rolls back on any other status or a panic.
+//
+// The response is held back until the commit, so clients never see a success
+// whose writes were lost, and a failing commit is answered with 500 instead.
+// Streaming handlers should not use it, as nothing reaches the client before
+// the handler returns.
+func Transaction(db *sql.DB, opts *sql.TxOptions) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tx, err := db.BeginTx(r.Context(), opts)
+			if err != nil {
+				errors.Report(r.Context(), err, errors.WithRequest(r))
+				WriteProblem(w, Problem{Status: http.StatusServiceUnavailable})
+				return
+			}
+			// a panicking handler leaves the transaction to this rollback
+			defer tx.Rollback()
+
+			bw := &bufferedWriter{ResponseWriter: w, header: http.Header{}}
+			next(bw, r.WithContext(context.WithValue(r.Context(), txKey{}, tx)))
+
+			if bw.Status() >= 400 {
+				tx.Rollback()
+				bw.send()
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				errors.Report(r.Context(), err, errors.WithRequest(r))
+				WriteProblem(w, Problem{Status: http.StatusInternalServerError})
+				return
+			}
+			bw.send()
+		}
+	}
+}
+
+// TxFrom returns the transaction Transaction opened for the request of ctx
+func TxFrom(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// bufferedWriter holds a response back until send
+type bufferedWriter struct {
+	http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (bw *bufferedWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferedWriter) WriteHeader(status int) {
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.body.Write(b)
+}
+
+// Status returns the status code written, http.StatusOK if the handler wrote nothing explicit
+func (bw *bufferedWriter) Status() int {
+	if bw.status == 0 {
+		return http.StatusOK
+	}
+	return bw.status
+}
+
+// send writes the held back response to the client
+func (bw *bufferedWriter) send() {
+	header := bw.ResponseWriter.Header()
+	for key, values := range bw.header {
+		header[key] = values
+	}
+	bw.ResponseWriter.WriteHeader(bw.Status())
+	bw.ResponseWriter.Write(bw.body.Bytes())
+}
@@ -0,0 +1,22 @@
+package routing
+
+import "net/http"
+
+// RequireTwoFactor returns middleware only letting through requests whose
+// session completed the second authentication factor, as reported by verified.
+// Other GET requests are redirected to challengeURL; anything else gets 403.
+func RequireTwoFactor(verified func(r *http.Request) bool, challengeURL string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if verified(r) {
+				next(w, r)
+				return
+			}
+			if r.Method == http.MethodGet && challengeURL != "" {
+				http.Redirect(w, r, challengeURL, http.StatusSeeOther)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// ReusePort makes Listen bind with SO_REUSEPORT, so a new release started
+// next to the running one can bind the same address while the old one drains
+var ReusePort = false
+
+// UpgradeParentEnv names the environment variable holding the process ID of
+// the process that started an upgrade, which the new process stops once it serves
+const UpgradeParentEnv = "BOLD_UPGRADE_PARENT"
+
+// ErrUpgradeUnsupported is returned by Upgrade on platforms without socket inheritance
+var ErrUpgradeUnsupported = errors.New("routing: binary upgrades are not supported on this platform")
+
+// Upgrade starts the current executable again with the same arguments,
+// handing it the listening socket of a running Listen. Once the new process
+// serves, it stops this one with SIGTERM, which drains in-flight requests as
+// usual, so the address never stops accepting connections. When the new
+// process fails to boot, this one keeps serving. Listen also upgrades on SIGUSR2.
+func (app *NetHTTPApp) Upgrade() error {
+	app.mu.Lock()
+	ln := app.listener
+	app.mu.Unlock()
+	if ln == nil {
+		return errors.New("routing: Upgrade requires a running Listen")
+	}
+	return upgrade(ln)
+}
+
+// notifyUpgradeParent stops the process that started this one with Upgrade
+func notifyUpgradeParent() {
+	value := os.Getenv(UpgradeParentEnv)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(UpgradeParentEnv)
+	os.Unsetenv(ListenFDEnv)
+
+	if pid, err := strconv.Atoi(value); err == nil {
+		if p, err := os.FindProcess(pid); err == nil {
+			p.Signal(syscall.SIGTERM)
+		}
+	}
+}
+
+// listenTCP binds addr, with SO_REUSEPORT when ReusePort is set
+func listenTCP(addr string) (net.Listener, error) {
+	if !ReusePort {
+		return net.Listen("tcp", addr)
+	}
+	lc := net.ListenConfig{Control: reusePort}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
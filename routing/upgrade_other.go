@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package routing
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+func upgrade(ln net.Listener) error {
+	return ErrUpgradeUnsupported
+}
+
+func upgradeOnSignal(ctx context.Context, ln net.Listener, onError func(error)) {}
+
+func reusePort(network, address string, conn syscall.RawConn) error {
+	return ErrUpgradeUnsupported
+}
@@ -0,0 +1,68 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/go-bold/bold/errors"
+)
+
+// BuildInfo describes the running release for App.Version
+type BuildInfo struct {
+	// Version is the release name, such as a tag
+	Version string
+	// SHA and Time are the commit and build time, by default the vcs.revision
+	// and vcs.time the Go toolchain stamps into binaries built in a repository
+	SHA  string
+	Time string
+	// SchemaVersion returns the last migration applied to the database, such
+	// as migrations.Runner.Version, left out when nil
+	SchemaVersion func(ctx context.Context) (string, error)
+}
+
+// VersionInfo is the body of the /version route
+type VersionInfo struct {
+	Version   string `json:"version,omitempty"`
+	SHA       string `json:"sha"`
+	BuildTime string `json:"build_time"`
+	// Modified reports a binary built from a repository with uncommitted changes
+	Modified      bool   `json:"modified,omitempty"`
+	GoVersion     string `json:"go_version"`
+	SchemaVersion string `json:"schema_version,omitempty"`
+}
+
+// Version registers GET /version, responding with the release, commit, build
+// time and Go version of the binary and the schema version of the database,
+// for auditing what each instance of a fleet runs. The middlewares guard the
+// route. A failing SchemaVersion is reported and its field left out.
+func (app *NetHTTPApp) Version(info BuildInfo, middlewares ...MiddlewareFunc) {
+	static := VersionInfo{Version: info.Version, SHA: info.SHA, BuildTime: info.Time, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range build.Settings {
+			switch {
+			case s.Key == "vcs.revision" && static.SHA == "":
+				static.SHA = s.Value
+			case s.Key == "vcs.time" && static.BuildTime == "":
+				static.BuildTime = s.Value
+			case s.Key == "vcs.modified" && info.SHA == "":
+				static.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	r := NewRoute()
+	app.Routes(r.Group("", middlewares, r.GET("/version", func(w http.ResponseWriter, r *http.Request) {
+		version := static
+		if info.SchemaVersion != nil {
+			schema, err := info.SchemaVersion(r.Context())
+			if err != nil {
+				errors.Report(r.Context(), err, errors.WithRequest(r))
+			}
+			version.SchemaVersion = schema
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, version)
+	})))
+}
@@ -0,0 +1,45 @@
+package routing
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+	"github.com/go-bold/bold/errors"
+)
+
+// ErrSlowResponse is reported by Watchdog for the requests running longer than its threshold
+var ErrSlowResponse = errors.New("routing: slow response")
+
+// Watchdog returns middleware calling onSlow for each request still running
+// after threshold, as soon as it crosses it, so endpoints creeping slower show
+// up before they time out and the ones that hang are caught while they hang.
+// onSlow runs on its own goroutine while the handler goes on. When nil, the
+// request is reported as ErrSlowResponse, tagged with its route.
+func Watchdog(threshold time.Duration, onSlow func(r *http.Request)) MiddlewareFunc {
+	if onSlow == nil {
+		onSlow = func(r *http.Request) {
+			err := fmt.Errorf("%w: %s %s still running after %s", ErrSlowResponse, r.Method, r.URL.Path, threshold)
+			errors.Report(r.Context(), err, errors.WithRequest(r), errors.WithTag("route", r.Pattern))
+		}
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			timer := clock.Default().NewTimer(threshold)
+			done := make(chan struct{})
+			go func() {
+				select {
+				case <-timer.C():
+					onSlow(r)
+				case <-done:
+				}
+			}()
+			defer func() {
+				timer.Stop()
+				close(done)
+			}()
+			next(w, r)
+		}
+	}
+}
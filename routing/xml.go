@@ -0,0 +1,210 @@
+package routing
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// BindXML decodes the XML request body into v. The body is read in the
+// charset of its Content-Type, or else of its XML declaration, UTF-8 when
+// neither names one. US-ASCII, ISO-8859-1 and Windows-1252 are converted,
+// other charsets are rejected.
+func BindXML(r *http.Request, v any) error {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !isXML(mediaType) {
+		return fmt.Errorf("routing: expected an XML body, got %q", r.Header.Get("Content-Type"))
+	}
+
+	dec := xml.NewDecoder(r.Body)
+	dec.CharsetReader = charsetReader
+	if charset := params["charset"]; charset != "" {
+		body, err := charsetReader(charset, r.Body)
+		if err != nil {
+			return err
+		}
+		dec = xml.NewDecoder(body)
+		// the body is UTF-8 by now, whatever its declaration says
+		dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("routing: decoding the XML body: %w", err)
+	}
+	return nil
+}
+
+// WriteXML encodes v as the UTF-8 XML response body with the given status code
+func WriteXML(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
+
+// Negotiate writes v as XML when the Accept header of r prefers XML to
+// JSON, and as JSON otherwise
+func Negotiate(w http.ResponseWriter, r *http.Request, status int, v any) {
+	if prefersXML(r.Header.Get("Accept")) {
+		WriteXML(w, status, v)
+		return
+	}
+	writeJSON(w, status, v)
+}
+
+// writeProblemXML writes p as an application/problem+xml response, with
+// arrays of extension members as i elements as RFC 9457 describes
+func writeProblemXML(w http.ResponseWriter, p Problem) {
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	w.Header().Set("Content-Type", "application/problem+xml; charset=utf-8")
+	w.WriteHeader(p.Status)
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+	element := func(name, value string) {
+		fmt.Fprintf(out, "<%s>", name)
+		xml.EscapeText(out, []byte(value))
+		fmt.Fprintf(out, "</%s>", name)
+	}
+
+	io.WriteString(out, xml.Header)
+	io.WriteString(out, `<problem xmlns="urn:ietf:rfc:7807">`)
+	if p.Type != "" {
+		element("type", p.Type)
+	}
+	element("title", p.Title)
+	element("status", strconv.Itoa(p.Status))
+	if p.Detail != "" {
+		element("detail", p.Detail)
+	}
+
+	keys := make([]string, 0, len(p.Extra))
+	for key := range p.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !xmlName(key) {
+			continue
+		}
+		items, ok := p.Extra[key].([]string)
+		if !ok {
+			element(key, fmt.Sprint(p.Extra[key]))
+			continue
+		}
+		fmt.Fprintf(out, "<%s>", key)
+		for _, item := range items {
+			element("i", item)
+		}
+		fmt.Fprintf(out, "</%s>", key)
+	}
+	io.WriteString(out, "</problem>\n")
+}
+
+// isXML reports whether mediaType is an XML one, such as text/xml or application/atom+xml
+func isXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// prefersXML reports whether accept ranks an XML type above JSON, wildcards
+// counting for JSON
+func prefersXML(accept string) bool {
+	var xmlQ, jsonQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case isXML(mediaType):
+			xmlQ = max(xmlQ, q)
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+			mediaType == "*/*" || mediaType == "application/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return xmlQ > jsonQ
+}
+
+// xmlName reports whether name can be used as an element name as is
+func xmlName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || !(r == '-' || r == '.' || (r >= '0' && r <= '9'))) {
+			return false
+		}
+	}
+	return true
+}
+
+// charsetReader converts input from charset to UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "l1":
+		return &singleByteReader{in: bufio.NewReader(input)}, nil
+	case "windows-1252", "cp1252":
+		return &singleByteReader{in: bufio.NewReader(input), table: &windows1252}, nil
+	}
+	return nil, fmt.Errorf("routing: unsupported charset %q", charset)
+}
+
+// singleByteReader decodes a single byte charset, ISO-8859-1 unless table
+// maps the bytes from 0x80 to 0x9F
+type singleByteReader struct {
+	in      *bufio.Reader
+	table   *[32]rune
+	pending []byte
+}
+
+func (r *singleByteReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) > 0 {
+			c := copy(p[n:], r.pending)
+			r.pending = r.pending[c:]
+			n += c
+			continue
+		}
+		b, err := r.in.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		char := rune(b)
+		if r.table != nil && b >= 0x80 && b < 0xA0 {
+			char = r.table[b-0x80]
+		}
+		r.pending = utf8.AppendRune(r.pending[:0], char)
+	}
+	return n, nil
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252, where it differs
+// from ISO-8859-1
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
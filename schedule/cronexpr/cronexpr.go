@@ -0,0 +1,297 @@
+// Package cronexpr parses cron expressions and computes their next run time.
+//
+// Expressions have five fields (minute hour day-of-month month day-of-week) or
+// six with a leading seconds field. Fields accept *, ?, lists (1,15), ranges
+// (1-5), steps (*/10, 10-40/5) and month and weekday names (JAN, MON). The
+// aliases @yearly, @annually, @monthly, @weekly, @daily, @midnight and
+// @hourly are supported, and a "CRON_TZ=Europe/Paris " or "TZ=..." prefix
+// pins the expression to a time zone.
+package cronexpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a parsed cron expression
+type Expression struct {
+	seconds  uint64
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	// daysAny and weekdaysAny record unrestricted day fields, since cron
+	// matches either day field when both are restricted
+	daysAny     bool
+	weekdaysAny bool
+
+	location *time.Location
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondBounds  = bounds{0, 59, nil}
+	minuteBounds  = bounds{0, 59, nil}
+	hourBounds    = bounds{0, 23, nil}
+	dayBounds     = bounds{1, 31, nil}
+	monthBounds   = bounds{1, 12, map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}}
+	weekdayBounds = bounds{0, 7, map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}}
+)
+
+var aliases = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse parses a cron expression
+func Parse(spec string) (*Expression, error) {
+	e := &Expression{}
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		tz, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("cronexpr: %w", err)
+		}
+		e.location = loc
+		spec = strings.TrimSpace(rest)
+	}
+
+	if alias, ok := aliases[strings.ToLower(spec)]; ok {
+		spec = alias
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cronexpr: expected 5 or 6 fields, got %d in %q", len(fields), spec)
+	}
+
+	var err error
+	if e.seconds, _, err = parseField(fields[0], secondBounds); err != nil {
+		return nil, err
+	}
+	if e.minutes, _, err = parseField(fields[1], minuteBounds); err != nil {
+		return nil, err
+	}
+	if e.hours, _, err = parseField(fields[2], hourBounds); err != nil {
+		return nil, err
+	}
+	if e.days, e.daysAny, err = parseField(fields[3], dayBounds); err != nil {
+		return nil, err
+	}
+	if e.months, _, err = parseField(fields[4], monthBounds); err != nil {
+		return nil, err
+	}
+	if e.weekdays, e.weekdaysAny, err = parseField(fields[5], weekdayBounds); err != nil {
+		return nil, err
+	}
+	// 7 is an alias for Sunday
+	if e.weekdays&(1<<7) != 0 {
+		e.weekdays |= 1
+	}
+	return e, nil
+}
+
+// MustParse is like Parse but panics if the expression is invalid
+func MustParse(spec string) *Expression {
+	e, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Location returns the time zone the expression was pinned to with a CRON_TZ prefix, or nil
+func (e *Expression) Location() *time.Location {
+	return e.location
+}
+
+// Next returns the first time strictly after from matching the expression,
+// evaluated in the expression's time zone or else in from's location. The
+// zero time is returned if nothing matches within five years.
+//
+// Times are matched on the wall clock, so a run during a backward DST
+// transition happens once. A run falling in a forward transition gap is moved
+// to the end of the gap, unless the expression runs every hour anyway.
+func (e *Expression) Next(from time.Time) time.Time {
+	loc := from.Location()
+	if e.location != nil {
+		loc = e.location
+	}
+	from = from.In(loc)
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < 5*366; i++ {
+		y, m, d := day.Date()
+		day = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+
+		if !e.matchesDay(y, m, d, loc) {
+			continue
+		}
+		if t, ok := e.nextOnDay(y, m, d, from, loc); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (e *Expression) matchesDay(y int, m time.Month, d int, loc *time.Location) bool {
+	if e.months&(1<<uint(m)) == 0 {
+		return false
+	}
+
+	weekday := time.Date(y, m, d, 12, 0, 0, 0, loc).Weekday()
+	dayMatch := e.days&(1<<uint(d)) != 0
+	weekdayMatch := e.weekdays&(1<<uint(weekday)) != 0
+
+	switch {
+	case e.daysAny && e.weekdaysAny:
+		return true
+	case e.daysAny:
+		return weekdayMatch
+	case e.weekdaysAny:
+		return dayMatch
+	default:
+		return dayMatch || weekdayMatch
+	}
+}
+
+// nextOnDay returns the first matching time of the given day after from
+func (e *Expression) nextOnDay(y int, m time.Month, d int, from time.Time, loc *time.Location) (time.Time, bool) {
+	everyHour := e.hours == fullMask(hourBounds)
+
+	for h := 0; h <= 23; h++ {
+		if e.hours&(1<<uint(h)) == 0 {
+			continue
+		}
+		for min := 0; min <= 59; min++ {
+			if e.minutes&(1<<uint(min)) == 0 {
+				continue
+			}
+			for s := 0; s <= 59; s++ {
+				if e.seconds&(1<<uint(s)) == 0 {
+					continue
+				}
+
+				t := time.Date(y, m, d, h, min, s, 0, loc)
+				// a wall clock time that does not exist is normalized by
+				// time.Date to before the gap, so move it to the gap's end
+				if t.Hour() != h || t.Minute() != min {
+					if everyHour {
+						continue
+					}
+					_, t = t.ZoneBounds()
+				}
+				if !t.After(from) {
+					continue
+				}
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseField parses a comma separated field into a bit set, reporting whether it is unrestricted
+func parseField(field string, b bounds) (uint64, bool, error) {
+	if field == "*" || field == "?" {
+		return fullMask(b), true, nil
+	}
+
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		bits, err := parsePart(part, b)
+		if err != nil {
+			return 0, false, fmt.Errorf("cronexpr: invalid field %q: %w", field, err)
+		}
+		mask |= bits
+	}
+	return mask, false, nil
+}
+
+func parsePart(part string, b bounds) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q", stepPart)
+		}
+	}
+
+	var lo, hi int
+	switch {
+	case rangePart == "*" || rangePart == "?":
+		lo, hi = b.min, b.max
+	case strings.Contains(rangePart, "-"):
+		start, end, _ := strings.Cut(rangePart, "-")
+		var err error
+		if lo, err = parseValue(start, b); err != nil {
+			return 0, err
+		}
+		if hi, err = parseValue(end, b); err != nil {
+			return 0, err
+		}
+	default:
+		var err error
+		if lo, err = parseValue(rangePart, b); err != nil {
+			return 0, err
+		}
+		hi = lo
+		if hasStep {
+			hi = b.max
+		}
+	}
+
+	if lo > hi {
+		return 0, fmt.Errorf("range %d-%d is reversed", lo, hi)
+	}
+
+	var mask uint64
+	for v := lo; v <= hi; v += step {
+		mask |= 1 << uint(v)
+	}
+	return mask, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+func fullMask(b bounds) uint64 {
+	var mask uint64
+	for v := b.min; v <= b.max; v++ {
+		mask |= 1 << uint(v)
+	}
+	return mask
+}
@@ -0,0 +1,274 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+	"github.com/go-bold/bold/errors"
+	"github.com/go-bold/bold/lock"
+	"github.com/go-bold/bold/metrics"
+	"github.com/go-bold/bold/schedule/cronexpr"
+)
+
+var (
+	taskRuns     = metrics.NewCounter("bold_schedule_runs_total", "Scheduled task runs by outcome", "task", "status")
+	taskDuration = metrics.NewHistogram("bold_schedule_run_duration_seconds", "Time spent running scheduled tasks", nil, "task")
+)
+
+// TaskFunc is the work performed by a scheduled task
+type TaskFunc func(ctx context.Context) error
+
+// Task is a unit of work run on a schedule
+type Task struct {
+	name               string
+	fn                 TaskFunc
+	next               func(time.Time) time.Time
+	withoutOverlapping bool
+	onOneServer        bool
+	lockTTL            time.Duration
+	location           *time.Location
+
+	running sync.Mutex
+}
+
+// WithoutOverlapping skips a run while the previous one is still in progress,
+// on this instance and, when a Locker is configured, on every other one
+func (t *Task) WithoutOverlapping() *Task {
+	t.withoutOverlapping = true
+	return t
+}
+
+// OnOneServer makes each run happen on a single instance of a multi-replica
+// deployment by taking a distributed lock keyed on the task and its scheduled
+// time. The lock is released once the run finishes, but no sooner than
+// slotGrace after the scheduled time, so instances firing late still skip the
+// run without a database lock pinning a connection until the next one.
+func (t *Task) OnOneServer() *Task {
+	t.onOneServer = true
+	return t
+}
+
+// LockTTL sets how long the WithoutOverlapping lock may be held, 24 hours by default
+func (t *Task) LockTTL(ttl time.Duration) *Task {
+	t.lockTTL = ttl
+	return t
+}
+
+// Timezone sets the time zone a Cron task's expression is evaluated in,
+// time.Local by default. A CRON_TZ prefix in the expression takes precedence.
+func (t *Task) Timezone(loc *time.Location) *Task {
+	t.location = loc
+	return t
+}
+
+// Scheduler runs registered tasks when they are due
+type Scheduler struct {
+	tasks  []*Task
+	locker lock.Locker
+	clock  clock.Clock
+	// OnError is called with errors returned by tasks or lock backends
+	OnError func(task string, err error)
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// UseLocker sets the Locker used by OnOneServer and WithoutOverlapping, lock.Default() when unset
+func (s *Scheduler) UseLocker(l lock.Locker) *Scheduler {
+	s.locker = l
+	return s
+}
+
+// UseClock sets the Clock deciding when tasks are due, clock.Default() when unset
+func (s *Scheduler) UseClock(c clock.Clock) *Scheduler {
+	s.clock = c
+	return s
+}
+
+// Every registers a task run at every multiple of interval since the Unix
+// epoch, so every instance agrees on when a run is due. It panics when
+// interval is not positive.
+func (s *Scheduler) Every(interval time.Duration, name string, fn TaskFunc) *Task {
+	if interval <= 0 {
+		panic("schedule: Every requires a positive interval")
+	}
+	return s.add(name, fn, func(now time.Time) time.Time {
+		since := now.UnixNano() % int64(interval)
+		if since < 0 {
+			since += int64(interval)
+		}
+		return now.Add(interval - time.Duration(since))
+	})
+}
+
+// Cron registers a task run whenever the cron expression spec matches, see
+// the cronexpr package for the supported syntax
+func (s *Scheduler) Cron(spec, name string, fn TaskFunc) (*Task, error) {
+	expr, err := cronexpr.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("schedule: task %s: %w", name, err)
+	}
+
+	var t *Task
+	t = s.add(name, fn, func(now time.Time) time.Time {
+		loc := t.location
+		if loc == nil {
+			loc = time.Local
+		}
+		return expr.Next(now.In(loc))
+	})
+	return t, nil
+}
+
+func (s *Scheduler) add(name string, fn TaskFunc, next func(time.Time) time.Time) *Task {
+	t := &Task{name: name, fn: fn, next: next, lockTTL: 24 * time.Hour}
+	s.tasks = append(s.tasks, t)
+	return t
+}
+
+// Run runs the tasks until ctx is done, then waits for in-flight runs to finish
+func (s *Scheduler) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, t := range s.tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, t, &wg)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// loop waits for each due time of t and starts a run
+func (s *Scheduler) loop(ctx context.Context, t *Task, wg *sync.WaitGroup) {
+	c := clock.Or(s.clock)
+	for {
+		now := c.Now()
+		due := t.next(now)
+		if due.IsZero() {
+			return
+		}
+
+		timer := c.NewTimer(due.Sub(now))
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.run(ctx, t, due)
+		}()
+	}
+}
+
+// run performs a single due run of t, honouring its overlap and single server constraints
+func (s *Scheduler) run(ctx context.Context, t *Task, due time.Time) {
+	locker := s.locker
+	if locker == nil {
+		locker = lock.Default()
+	}
+
+	if t.onOneServer {
+		if locker == nil {
+			s.fail(ctx, t, errors.New("schedule: OnOneServer requires a lock.Locker"))
+			return
+		}
+		// the ttl bounds the lock of an instance dying mid-run to the slot
+		interval := max(t.next(due).Sub(due), time.Second)
+		l, err := locker.Acquire(ctx, fmt.Sprintf("schedule:%s:%d", t.name, due.Unix()), interval)
+		if err != nil {
+			if !errors.Is(err, lock.ErrNotAcquired) {
+				s.fail(ctx, t, err)
+			}
+			return
+		}
+		defer s.releaseSlot(ctx, t, l, due.Add(min(slotGrace, interval)))
+	}
+
+	if t.withoutOverlapping {
+		if !t.running.TryLock() {
+			return
+		}
+		defer t.running.Unlock()
+
+		if locker != nil {
+			l, err := locker.Acquire(ctx, "schedule:"+t.name+":overlap", t.lockTTL)
+			if err != nil {
+				if !errors.Is(err, lock.ErrNotAcquired) {
+					s.fail(ctx, t, err)
+				}
+				return
+			}
+			defer l.Release(context.WithoutCancel(ctx))
+		}
+	}
+
+	started := time.Now()
+	stack, err := call(ctx, t.fn)
+	taskDuration.Observe(time.Since(started).Seconds(), t.name)
+	if err != nil {
+		taskRuns.Inc(t.name, "failed")
+		var opts []errors.Option
+		if p, ok := err.(*errors.PanicError); ok {
+			opts = append(opts, errors.WithPanic(p, stack))
+		}
+		s.fail(ctx, t, err, opts...)
+		return
+	}
+	taskRuns.Inc(t.name, "succeeded")
+}
+
+// slotGrace is how long after the scheduled time an OnOneServer lock is
+// held at least, covering instances whose clocks or timers run late
+const slotGrace = time.Minute
+
+// releaseSlot releases the OnOneServer lock of a finished run at until, or
+// right away when that has passed
+func (s *Scheduler) releaseSlot(ctx context.Context, t *Task, l *lock.Lock, until time.Time) {
+	ctx = context.WithoutCancel(ctx)
+	release := func() {
+		if err := l.Release(ctx); err != nil {
+			s.fail(ctx, t, err)
+		}
+	}
+
+	c := clock.Or(s.clock)
+	wait := until.Sub(c.Now())
+	if wait <= 0 {
+		release()
+		return
+	}
+	timer := c.NewTimer(wait)
+	go func() {
+		<-timer.C()
+		release()
+	}()
+}
+
+// call runs fn, turning a panic into an errors.PanicError returned with the
+// stack it was raised on, so one task cannot take the scheduler down
+func call(ctx context.Context, fn TaskFunc) (stack []byte, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			stack, err = debug.Stack(), &errors.PanicError{Value: v}
+		}
+	}()
+	return nil, fn(ctx)
+}
+
+func (s *Scheduler) fail(ctx context.Context, t *Task, err error, opts ...errors.Option) {
+	errors.Report(ctx, err, append([]errors.Option{errors.WithTag("task", t.name)}, opts...)...)
+	if s.OnError != nil {
+		s.OnError(t.name, err)
+	}
+}
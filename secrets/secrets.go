@@ -0,0 +1,201 @@
+// Package secrets resolves sensitive values such as database passwords and
+// application keys from the environment, mounted files or an external
+// secret manager, caching them and notifying subscribers when they rotate.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-bold/bold/clock"
+)
+
+// ErrNotFound is returned when a provider has no secret with the requested name
+var ErrNotFound = errors.New("secrets: not found")
+
+// Provider fetches secrets from a backing store. Adapters for Vault, AWS SSM
+// and similar managers implement it, or use ProviderFunc.
+type Provider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// ProviderFunc adapts a function to a Provider
+type ProviderFunc func(ctx context.Context, name string) (string, error)
+
+func (f ProviderFunc) Secret(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// Env returns a Provider reading the environment variable prefix+name
+func Env(prefix string) Provider {
+	return ProviderFunc(func(ctx context.Context, name string) (string, error) {
+		value, ok := os.LookupEnv(prefix + name)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return value, nil
+	})
+}
+
+// File returns a Provider reading the file dir/name, as mounted by Docker and
+// Kubernetes secrets, without its trailing newline
+func File(dir string) Provider {
+	return ProviderFunc(func(ctx context.Context, name string) (string, error) {
+		if name != filepath.Base(name) {
+			return "", fmt.Errorf("secrets: invalid name %q", name)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	})
+}
+
+// Chain returns a Provider trying each provider in turn until one has the secret
+func Chain(providers ...Provider) Provider {
+	return ProviderFunc(func(ctx context.Context, name string) (string, error) {
+		for _, p := range providers {
+			value, err := p.Secret(ctx, name)
+			if !errors.Is(err, ErrNotFound) {
+				return value, err
+			}
+		}
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	})
+}
+
+type entry struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// Store caches the secrets of a Provider and calls rotation callbacks when
+// a refreshed secret has a new value
+type Store struct {
+	provider Provider
+	ttl      time.Duration
+
+	mu       sync.Mutex
+	cache    map[string]entry
+	onRotate map[string][]func(value string)
+}
+
+// New returns a Store caching secrets of p for ttl, or until Refresh when ttl is 0
+func New(p Provider, ttl time.Duration) *Store {
+	return &Store{
+		provider: p,
+		ttl:      ttl,
+		cache:    map[string]entry{},
+		onRotate: map[string][]func(string){},
+	}
+}
+
+// Get returns the named secret, fetching it when it is not cached or expired
+func (s *Store) Get(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	e, ok := s.cache[name]
+	s.mu.Unlock()
+
+	if ok && (s.ttl == 0 || clock.Now().Sub(e.fetchedAt) < s.ttl) {
+		return e.value, nil
+	}
+	return s.fetch(ctx, name)
+}
+
+// OnRotate registers fn to be called with the new value whenever the named secret changes
+func (s *Store) OnRotate(name string, fn func(value string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRotate[name] = append(s.onRotate[name], fn)
+}
+
+// Refresh fetches every cached secret again, calling the rotation callbacks of those that changed
+func (s *Store) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.cache))
+	for name := range s.cache {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, name := range names {
+		if _, err := s.fetch(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Watch calls Refresh every interval until ctx is done, passing failures to onError when not nil
+func (s *Store) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// fetch reads name from the provider, updating the cache and notifying rotation callbacks
+func (s *Store) fetch(ctx context.Context, name string) (string, error) {
+	value, err := s.provider.Secret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	old, cached := s.cache[name]
+	s.cache[name] = entry{value: value, fetchedAt: clock.Now()}
+	var callbacks []func(string)
+	if cached && old.value != value {
+		callbacks = append(callbacks, s.onRotate[name]...)
+	}
+	s.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(value)
+	}
+	return value, nil
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore = New(Env(""), 0)
+)
+
+// Use sets the Store used by the package level Get, the environment by default
+func Use(s *Store) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStore = s
+}
+
+// Default returns the Store used by the package level Get
+func Default() *Store {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
+
+// Get returns the named secret from the default Store
+func Get(ctx context.Context, name string) (string, error) {
+	return Default().Get(ctx, name)
+}